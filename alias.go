@@ -0,0 +1,140 @@
+package downcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedirectFormat is the format WriteRedirects writes redirects in.
+type RedirectFormat string
+
+const (
+	RedirectFormatNetlify RedirectFormat = "netlify" // A Netlify _redirects file, with a "from to 301" line per redirect
+	RedirectFormatNginx   RedirectFormat = "nginx"   // The entries of an nginx map block, with a "from" "to"; line per redirect
+	RedirectFormatJSON    RedirectFormat = "json"    // A JSON array of Redirect objects
+)
+
+// Redirect is a permanent redirect from an alias of a post to its permalink.
+type Redirect struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status"`
+}
+
+// normalizeAliases returns the aliases as URL paths starting with a slash, without duplicates, blanks or the
+// permalink, which would redirect to itself.
+func normalizeAliases(aliases []string, permalink string) []string {
+	var normalized []string
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			continue
+		}
+		alias = "/" + strings.TrimPrefix(alias, "/")
+		if slices.Contains(pathCandidates(alias), permalink) || slices.Contains(normalized, alias) {
+			continue
+		}
+		normalized = append(normalized, alias)
+	}
+	return normalized
+}
+
+// ResolveAlias returns the post with the alias, such as a request's URL path, so routers can redirect to its
+// permalink. The aliases of a post are set in its frontmatter, and its previous URLs are added when Update moves it
+// to another type or slug. The path matches with or without a trailing slash. As with Get, ErrNotFound is returned
+// if the viewer in the context can't see the post.
+func (cm *DownCache) ResolveAlias(ctx context.Context, alias string) (*Post, error) {
+	alias = "/" + strings.TrimPrefix(alias, "/")
+	for _, candidate := range pathCandidates(alias) {
+		posts, _, err := cm.Search(ctx, FilterOptions{
+			PageSize:         1,
+			FilterAlias:      candidate,
+			FilterPostType:   PostTypeKeyAny,
+			Viewer:           Viewer{Role: ViewerAdmin},
+			IncludeScheduled: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(posts) > 0 {
+			return cm.Get(ctx, posts[0].PostType, posts[0].Slug)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no post has the alias %s", ErrNotFound, alias)
+}
+
+// Redirects returns a redirect from each alias of the posts the viewer in the context can see to the post's
+// permalink, sorted by alias. Aliases that are the permalink of another post are left out, so its page isn't
+// hidden.
+func (cm *DownCache) Redirects(ctx context.Context) ([]Redirect, error) {
+	posts, err := cm.searchAll(ctx, FilterOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	permalinks := make(map[string]bool, len(posts))
+	for _, post := range posts {
+		permalinks[post.Permalink] = true
+	}
+
+	viewer := ViewerFromContext(ctx)
+	now := time.Now().UTC()
+	var redirects []Redirect
+	for _, post := range posts {
+		if post.Permalink == "" || !viewer.CanView(post, now) {
+			continue
+		}
+		for _, alias := range post.Aliases {
+			if slices.ContainsFunc(pathCandidates(alias), func(p string) bool { return permalinks[p] }) {
+				continue
+			}
+			redirects = append(redirects, Redirect{From: alias, To: post.Permalink, Status: 301})
+		}
+	}
+
+	sort.SliceStable(redirects, func(i, j int) bool {
+		return redirects[i].From < redirects[j].From
+	})
+	return redirects, nil
+}
+
+// WriteRedirects writes the redirects in the format, such as a Netlify _redirects file. The nginx format is the
+// entries of a map block, which can be included in a server's configuration:
+//
+//	map $uri $redirect_uri {
+//	    include redirects.map;
+//	}
+func WriteRedirects(w io.Writer, redirects []Redirect, format RedirectFormat) error {
+	switch format {
+	case RedirectFormatNetlify:
+		for _, redirect := range redirects {
+			if _, err := fmt.Fprintf(w, "%s %s %d\n", redirect.From, redirect.To, redirect.Status); err != nil {
+				return err
+			}
+		}
+	case RedirectFormatNginx:
+		for _, redirect := range redirects {
+			if _, err := fmt.Fprintf(w, "%s %s;\n", strconv.Quote(redirect.From), strconv.Quote(redirect.To)); err != nil {
+				return err
+			}
+		}
+	case RedirectFormatJSON:
+		if redirects == nil {
+			redirects = []Redirect{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(redirects)
+	default:
+		return fmt.Errorf("unknown redirect format '%s'", format)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package downcache_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestDownCache_ResolveAlias(t *testing.T) {
+	dir := t.TempDir()
+	fs := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store, downcache.WithPermalink("articles", "/:year/:slug/"))
+
+	ctx := context.Background()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pages"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "about.md"),
+		[]byte("---\nname: About\nstatus: published\nvisibility: public\naliases:\n  - about-us\n  - /team/\n---\n\nAbout"), 0o644))
+	require.NoError(t, cm.SyncAll(ctx))
+
+	// Aliases from the frontmatter
+	post, err := cm.ResolveAlias(ctx, "/about-us")
+	require.NoError(t, err)
+	assert.Equal(t, "about", post.Slug)
+	assert.Equal(t, []string{"/about-us", "/team/"}, post.Aliases)
+
+	post, err = cm.ResolveAlias(ctx, "/team")
+	require.NoError(t, err)
+	assert.Equal(t, "about", post.Slug)
+
+	// Moving a post keeps its old path and permalink as aliases
+	published := sql.NullString{String: "2024-03-10", Valid: true}
+	_, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "hello", Name: "Hello", Published: published, Status: "published", Visibility: "public"})
+	require.NoError(t, err)
+	require.NoError(t, cm.Update(ctx, "articles", "hello", &downcache.Post{PostType: "articles", Slug: "hello-world", Name: "Hello", Published: published, Status: "published", Visibility: "public"}))
+
+	post, err = cm.ResolveAlias(ctx, "/2024/hello/")
+	require.NoError(t, err)
+	assert.Equal(t, "hello-world", post.Slug)
+	assert.Equal(t, "/2024/hello-world/", post.Permalink)
+	assert.Equal(t, []string{"/articles/hello", "/2024/hello/"}, post.Aliases)
+
+	// The aliases are written to the frontmatter, so they survive a sync
+	content, err := os.ReadFile(filepath.Join(dir, "articles", "hello-world.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "aliases:")
+	require.NoError(t, cm.SyncAll(ctx))
+
+	// Moving again keeps the earlier aliases, and moving back drops the alias of the permalink
+	require.NoError(t, cm.Update(ctx, "articles", "hello-world", &downcache.Post{PostType: "articles", Slug: "hello", Name: "Hello", Published: published, Status: "published", Visibility: "public"}))
+	post, err = cm.ResolveAlias(ctx, "/articles/hello-world")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", post.Slug)
+	assert.Equal(t, []string{"/articles/hello", "/articles/hello-world", "/2024/hello-world/"}, post.Aliases)
+
+	_, err = cm.ResolveAlias(ctx, "/missing")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+}
+
+func TestDownCache_Redirects(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: "new", Status: "published", Visibility: "public", Aliases: []string{"/old", "/articles/older/"}})
+	_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: "draft", Status: "draft", Visibility: "public", Aliases: []string{"/draft"}})
+	// The alias is another post's permalink, so it isn't redirected
+	_ = fs.Write(ctx, &downcache.Post{PostType: "pages", Slug: "about", Status: "published", Visibility: "public", Aliases: []string{"/articles/new"}})
+	require.NoError(t, cm.SyncAll(ctx))
+
+	redirects, err := cm.Redirects(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []downcache.Redirect{
+		{From: "/articles/older/", To: "/articles/new", Status: 301},
+		{From: "/old", To: "/articles/new", Status: 301},
+	}, redirects)
+
+	var buf bytes.Buffer
+	require.NoError(t, downcache.WriteRedirects(&buf, redirects, downcache.RedirectFormatNetlify))
+	assert.Equal(t, "/articles/older/ /articles/new 301\n/old /articles/new 301\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, downcache.WriteRedirects(&buf, redirects, downcache.RedirectFormatNginx))
+	assert.Equal(t, "\"/articles/older/\" \"/articles/new\";\n\"/old\" \"/articles/new\";\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, downcache.WriteRedirects(&buf, redirects[1:], downcache.RedirectFormatJSON))
+	assert.JSONEq(t, `[{"from": "/old", "to": "/articles/new", "status": 301}]`, buf.String())
+
+	assert.Error(t, downcache.WriteRedirects(&buf, redirects, "apache"))
+}
@@ -1,6 +1,8 @@
 package bboltstore
 
 import (
+	"cmp"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -10,9 +12,12 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/numeric"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
 	"github.com/blevesearch/bleve/v2/search/query"
 	"go.etcd.io/bbolt"
 
@@ -24,6 +29,8 @@ const (
 	bleveFile        = "downcache.bleve"
 	bucketPosts      = "posts"
 	bucketTaxonomies = "taxonomies"
+	bucketPermalinks = "permalinks"
+	bucketAliases    = "aliases"
 )
 
 type matchOptions struct {
@@ -31,6 +38,60 @@ type matchOptions struct {
 	value string
 }
 
+// document is the form of a post that is indexed in Bleve. Dates are parsed, so they can be compared as ranges and
+// sorted, rather than indexed as the strings of the post's sql.NullString fields. Posts without a status or
+// visibility are indexed as published and public, as the other stores treat them.
+type document struct {
+	Slug       string              `json:"slug"`
+	PostType   string              `json:"postType"`
+	Author     string              `json:"author"`
+	Name       string              `json:"name"`
+	Subtitle   string              `json:"subtitle"`
+	Summary    string              `json:"summary"`
+	Content    string              `json:"content"`
+	Pinned     bool                `json:"pinned"`
+	Status     string              `json:"status"`
+	Visibility string              `json:"visibility"`
+	Published  *time.Time          `json:"published,omitempty"`
+	Expires    *time.Time          `json:"expires,omitempty"`
+	Updated    *time.Time          `json:"updated,omitempty"`
+	Taxonomies map[string][]string `json:"taxonomies"`
+	Properties map[string]any      `json:"properties"`
+}
+
+// newDocument returns the document to index for the post. Dates that aren't set are left out.
+func newDocument(post *downcache.Post) document {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+
+	return document{
+		Slug:       post.Slug,
+		PostType:   post.PostType,
+		Author:     post.Author,
+		Name:       post.Name,
+		Subtitle:   post.Subtitle,
+		Summary:    post.Summary,
+		Content:    post.Content,
+		Pinned:     post.Pinned,
+		Status:     cmp.Or(post.Status, "published"),
+		Visibility: cmp.Or(post.Visibility, "public"),
+		Published:  optionalTime(post.PublishedTime()),
+		Expires:    optionalTime(post.ExpiresTime()),
+		Updated:    optionalTime(post.UpdatedTime()),
+		Taxonomies: post.Taxonomies,
+		Properties: downcache.NormalizeProperties(post.Properties),
+	}
+}
+
+// BleveType returns the type of the document's mapping.
+func (d document) BleveType() string {
+	return "post"
+}
+
 type BBoltStore struct {
 	bleveIndex bleve.Index
 	boltIndex  *bbolt.DB
@@ -124,7 +185,7 @@ func (bbs *BBoltStore) Create(post *downcache.Post) (*downcache.Post, error) {
 		}
 	}()
 
-	currentPage, _ := bbs.GetBySlug(downcache.PostPathID())
+	currentPage, _ := bbs.GetBySlug(downcache.PostPathID(post.PostType, post.Slug))
 
 	err := bbs.boltIndex.Update(func(tx *bbolt.Tx) error {
 		if currentPage != nil {
@@ -157,6 +218,18 @@ func (bbs *BBoltStore) Create(post *downcache.Post) (*downcache.Post, error) {
 			return fmt.Errorf("failed to put post in bucket: %w", err)
 		}
 
+		var oldPermalinks, oldAliases []string
+		if currentPage != nil {
+			oldPermalinks = []string{currentPage.Permalink}
+			oldAliases = currentPage.Aliases
+		}
+		if err := bbs.updatePaths(tx, bucketPermalinks, post.PostID, oldPermalinks, []string{post.Permalink}); err != nil {
+			return fmt.Errorf("failed to update permalink: %w", err)
+		}
+		if err := bbs.updatePaths(tx, bucketAliases, post.PostID, oldAliases, post.Aliases); err != nil {
+			return fmt.Errorf("failed to update aliases: %w", err)
+		}
+
 		// Update the taxonomies
 		for taxonomy, terms := range post.Taxonomies {
 			for _, term := range terms {
@@ -176,7 +249,7 @@ func (bbs *BBoltStore) Create(post *downcache.Post) (*downcache.Post, error) {
 	}
 
 	// Index in Bleve
-	if err := bbs.bleveIndex.Index(post.PostID, post); err != nil {
+	if err := bbs.bleveIndex.Index(post.PostID, newDocument(post)); err != nil {
 		return nil, fmt.Errorf("failed to index post in bleve: %w", err)
 	}
 
@@ -203,6 +276,13 @@ func (bbs *BBoltStore) Delete(slug string) error {
 			return fmt.Errorf("failed to delete post: %w", err)
 		}
 
+		if err := bbs.updatePaths(tx, bucketPermalinks, post.PostID, []string{post.Permalink}, nil); err != nil {
+			return fmt.Errorf("failed to remove permalink: %w", err)
+		}
+		if err := bbs.updatePaths(tx, bucketAliases, post.PostID, post.Aliases, nil); err != nil {
+			return fmt.Errorf("failed to remove aliases: %w", err)
+		}
+
 		// Remove the taxonomies
 		for taxonomy, terms := range post.Taxonomies {
 			for _, term := range terms {
@@ -291,6 +371,11 @@ func (bbs *BBoltStore) Search(filter downcache.FilterOptions) (downcache.Paginat
 	var options []matchOptions
 	var extraFields []string
 
+	cursor, hasCursor, before, err := filter.Cursor()
+	if err != nil {
+		return downcache.Paginator{}, err
+	}
+
 	if filter.PageNum < 1 {
 		filter.PageNum = 1
 	}
@@ -299,49 +384,275 @@ func (bbs *BBoltStore) Search(filter downcache.FilterOptions) (downcache.Paginat
 		filter.PageSize = 10
 	}
 
-	switch filter.FilterType {
-	case downcache.FilterTypeAuthor:
-		checkField = "authors"
-		checkValue = filter.FilterTerm
-		extraFields = append(extraFields, "authors")
-		options = append(options, matchOptions{"authors", checkValue})
-	case downcache.FilterTypeTaxonomy:
-		checkField = fmt.Sprintf("taxonomies.%s", filter.FilterKey)
-		checkValue = filter.FilterTerm
-		extraFields = append(extraFields, checkField)
-		options = append(options, matchOptions{checkField, checkValue})
+	if filter.FilterAuthor != "" {
+		checkField = "author"
+		checkValue = filter.FilterAuthor
+		options = append(options, matchOptions{"author", checkValue})
+	}
+
+	if filter.FilterStatus != "" && filter.FilterStatus != downcache.FilterTypeAny.String() {
+		options = append(options, matchOptions{"status", filter.FilterStatus})
+	}
+
+	if filter.FilterVisibility != "" && filter.FilterVisibility != downcache.FilterTypeAny.String() {
+		options = append(options, matchOptions{"visibility", filter.FilterVisibility})
+	}
+
+	postsQuery := bbs.searchQuery(
+		filter.FilterPostType,
+		options...,
+	)
+
+	if filter.FilterPermalink != "" {
+		postsQuery.AddQuery(bbs.pathQuery(bucketPermalinks, filter.FilterPermalink))
+	}
+
+	if filter.FilterAlias != "" {
+		postsQuery.AddQuery(bbs.pathQuery(bucketAliases, filter.FilterAlias))
+	}
+
+	if accessQuery := bbs.accessQuery(filter.CurrentViewer(context.Background()).Access(true)); accessQuery != nil {
+		postsQuery.AddQuery(accessQuery)
+	}
+
+	var excludes []query.Query
+	for _, group := range filter.TaxonomyGroups() {
+		terms := make([]query.Query, 0, len(group.Terms))
+		for _, tax := range group.Terms {
+			field := fmt.Sprintf("taxonomies.%s", tax.Key)
+			if !slices.Contains(extraFields, field) {
+				extraFields = append(extraFields, field)
+			}
+
+			termQuery := bleve.NewMatchQuery(tax.Value)
+			termQuery.SetField(field)
+			terms = append(terms, termQuery)
+		}
+
+		if group.Negate {
+			excludes = append(excludes, terms...)
+		} else {
+			postsQuery.AddQuery(bleve.NewDisjunctionQuery(terms...))
+		}
+	}
+
+	if text := filter.TextQuery(); !text.IsEmpty() {
+		postsQuery.AddQuery(bbs.textQuery(text))
+	}
+
+	if !filter.FilterPublishedAfter.IsZero() || !filter.FilterPublishedBefore.IsZero() {
+		inclusive := true
+		dateQuery := bleve.NewDateRangeInclusiveQuery(filter.FilterPublishedAfter, filter.FilterPublishedBefore, &inclusive, nil)
+		dateQuery.SetField("published")
+		postsQuery.AddQuery(dateQuery)
+	}
+
+	if !filter.FilterExpiresAfter.IsZero() || !filter.FilterExpiresBefore.IsZero() {
+		inclusive := true
+		dateQuery := bleve.NewDateRangeInclusiveQuery(filter.FilterExpiresAfter, filter.FilterExpiresBefore, &inclusive, nil)
+		dateQuery.SetField("expires")
+		postsQuery.AddQuery(dateQuery)
 	}
 
-	options = append(options, matchOptions{"search", filter.FilterSearch})
+	// Exclude posts that are scheduled for later or have expired
+	if asOf, ok := filter.LiveAsOf(); ok {
+		exclusive := false
+		scheduledQuery := bleve.NewDateRangeInclusiveQuery(asOf, time.Time{}, &exclusive, nil)
+		scheduledQuery.SetField("published")
 
-	if filter.FilterStatus != downcache.FilterTypeAny.String() {
-		if filter.FilterStatus != "" {
-			options = append(options, matchOptions{"status", filter.FilterStatus})
+		inclusive := true
+		expiredQuery := bleve.NewDateRangeInclusiveQuery(time.Time{}, asOf, nil, &inclusive)
+		expiredQuery.SetField("expires")
+
+		excludes = append(excludes, scheduledQuery, expiredQuery)
+	}
+
+	for _, prop := range filter.FilterProperties {
+		propQuery, negate := bbs.propertyQuery(prop)
+		if negate {
+			excludes = append(excludes, propQuery)
 		} else {
-			options = append(options, matchOptions{"status", "published"})
+			postsQuery.AddQuery(propQuery)
 		}
 	}
 
-	if filter.FilterVisibility != downcache.FilterTypeAny.String() {
-		if filter.FilterVisibility != "" {
-			options = append(options, matchOptions{"visibility", filter.FilterVisibility})
+	// A conjunction without any queries matches nothing, rather than everything
+	if len(postsQuery.Conjuncts) == 0 {
+		postsQuery.AddQuery(bleve.NewMatchAllQuery())
+	}
+
+	var searchQuery query.Query = postsQuery
+	if len(excludes) > 0 {
+		boolQuery := bleve.NewBooleanQuery()
+		boolQuery.AddMust(postsQuery)
+		boolQuery.AddMustNot(excludes...)
+		searchQuery = boolQuery
+	}
+
+	request := bbs.searchRequest(searchQuery, filter.PageNum, filter.PageSize, filter.Sort(), extraFields...)
+	if hasCursor {
+		request.From = 0
+		if before {
+			request.SetSearchBefore(bbs.searchAfterValues(cursor))
 		} else {
-			options = append(options, matchOptions{"visibility", "public"})
+			request.SetSearchAfter(bbs.searchAfterValues(cursor))
 		}
 	}
 
-	postsQuery := bbs.searchQuery(
-		filter.FilterPostType,
-		options...,
-	)
+	if filter.Highlight && !filter.TextQuery().IsEmpty() {
+		request.Highlight = bleve.NewHighlightWithStyle(html.Name)
+		request.Highlight.Fields = textFields
+	}
 
-	request := bbs.searchRequest(postsQuery, filter.PageNum, filter.PageSize, filter.SortBy, extraFields...)
 	result, posts, err := bbs.postsFromSearchRequest(request, checkField, checkValue)
 	if err != nil {
 		return downcache.Paginator{}, fmt.Errorf("error searching for posts: %w", err)
 	}
 
-	return downcache.NewPaginator(posts, int(result.Total), filter.PageNum, filter.PageSize, filter.SplitPinned), err
+	cursorPaginator := downcache.NewCursorPaginator(posts, int(result.Total), filter)
+	if hasCursor {
+		return cursorPaginator, nil
+	}
+
+	// Pages selected by number also have the cursor of the next page, so callers can continue with cursors
+	paginator := downcache.NewPaginator(posts, int(result.Total), filter.PageNum, filter.PageSize, filter.SplitPinned)
+	if paginator.HasNext {
+		paginator.NextCursor = cursorPaginator.NextCursor
+	}
+	return paginator, nil
+}
+
+// ResolvePermalink returns the post with the permalink, such as a request's URL path, for routers. The path matches
+// with or without a trailing slash.
+func (bbs *BBoltStore) ResolvePermalink(permalink string) (*downcache.Post, error) {
+	return bbs.resolvePath(bucketPermalinks, permalink)
+}
+
+// ResolveAlias returns the post with the alias, such as a request's URL path, so routers can redirect to its
+// permalink. The path matches with or without a trailing slash.
+func (bbs *BBoltStore) ResolveAlias(alias string) (*downcache.Post, error) {
+	return bbs.resolvePath(bucketAliases, alias)
+}
+
+// resolvePath returns the post with the URL path in the bucket, as it is or with its trailing slash removed or added.
+func (bbs *BBoltStore) resolvePath(bucket, urlPath string) (*downcache.Post, error) {
+	urlPath = "/" + strings.TrimPrefix(urlPath, "/")
+	candidates := []string{urlPath}
+	if trimmed := strings.TrimSuffix(urlPath, "/"); trimmed != urlPath && trimmed != "" {
+		candidates = append(candidates, trimmed)
+	} else if trimmed == urlPath {
+		candidates = append(candidates, urlPath+"/")
+	}
+
+	for _, candidate := range candidates {
+		postID, err := bbs.pathPostID(bucket, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if postID != "" {
+			return bbs.GetBySlug(postID)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no post has the path %s", downcache.ErrNotFound, urlPath)
+}
+
+// pathQuery returns a query that matches the post with the URL path in the bucket, or nothing if there isn't one.
+func (bbs *BBoltStore) pathQuery(bucket, urlPath string) query.Query {
+	var ids []string
+	if postID, err := bbs.pathPostID(bucket, urlPath); err == nil && postID != "" {
+		ids = append(ids, postID)
+	}
+	return bleve.NewDocIDQuery(ids)
+}
+
+// pathPostID returns the ID of the post with the URL path in the bucket, or "" if there isn't one.
+func (bbs *BBoltStore) pathPostID(bucket, urlPath string) (string, error) {
+	var postID string
+	err := bbs.boltIndex.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return fmt.Errorf("bucket not found")
+		}
+		postID = string(b.Get([]byte(urlPath)))
+		return nil
+	})
+	return postID, err
+}
+
+// accessQuery returns a query that only matches the posts allowed by the access, or nil if every post is allowed.
+// Posts without a status or visibility are indexed as published and public, so empty values in the access are
+// ignored.
+func (bbs *BBoltStore) accessQuery(access downcache.Access) query.Query {
+	if access.Unrestricted {
+		return nil
+	}
+
+	anyOf := func(field string, values []string) query.Query {
+		queries := make([]query.Query, 0, len(values))
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			termQuery := bleve.NewTermQuery(value)
+			termQuery.SetField(field)
+			queries = append(queries, termQuery)
+		}
+		return bleve.NewDisjunctionQuery(queries...)
+	}
+
+	accessQuery := bleve.NewConjunctionQuery(anyOf("status", access.Statuses), anyOf("visibility", access.Visibilities))
+	if access.Author == "" {
+		return accessQuery
+	}
+
+	authorQuery := bleve.NewMatchQuery(access.Author)
+	authorQuery.SetField("author")
+	authorQuery.SetOperator(query.MatchQueryOperatorAnd)
+	return bleve.NewDisjunctionQuery(accessQuery, authorQuery)
+}
+
+// textFields are the fields searched by full-text queries and highlighted, as in the other stores.
+var textFields = []string{"name", "subtitle", "summary", "content"}
+
+// bleveSortFields maps the downcache sort fields to the index fields they sort by
+var bleveSortFields = map[string]string{
+	"pinned":    "pinned",
+	"published": "published",
+	"name":      "slug",
+	"score":     "_score",
+}
+
+// searchAfterValues converts a cursor into bleve search after values, in the format of the index's sort values.
+// The last value is the document ID, which breaks ties.
+func (bbs *BBoltStore) searchAfterValues(cursor downcache.Cursor) []string {
+	values := make([]string, 0, len(cursor.Values)+1)
+	for i, field := range cursor.SortBy {
+		name, _ := downcache.ParseSortField(field)
+		if _, ok := bleveSortFields[name]; !ok {
+			continue
+		}
+
+		value, _ := downcache.ParseSortValue(name, cursor.Values[i])
+		switch v := value.(type) {
+		case bool:
+			if v {
+				values = append(values, "T")
+			} else {
+				values = append(values, "F")
+			}
+		case time.Time:
+			if v.IsZero() {
+				values = append(values, "")
+			} else {
+				values = append(values, string(numeric.MustNewPrefixCodedInt64(v.UnixNano(), 0)))
+			}
+		default:
+			values = append(values, cursor.Values[i])
+		}
+	}
+
+	return append(values, cursor.PostID)
 }
 
 func (bbs *BBoltStore) initBolt() (*bbolt.DB, error) {
@@ -363,6 +674,16 @@ func (bbs *BBoltStore) initBolt() (*bbolt.DB, error) {
 			return fmt.Errorf("failed to create taxonomies bucket: %w", err)
 		}
 
+		_, err = tx.CreateBucketIfNotExists([]byte(bucketPermalinks))
+		if err != nil {
+			return fmt.Errorf("failed to create permalinks bucket: %w", err)
+		}
+
+		_, err = tx.CreateBucketIfNotExists([]byte(bucketAliases))
+		if err != nil {
+			return fmt.Errorf("failed to create aliases bucket: %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -393,7 +714,7 @@ func (bbs *BBoltStore) defineBleveMapping() *mapping.IndexMappingImpl {
 	docMapping := bleve.NewDocumentMapping()
 
 	// To use queries, I found it was necessary to use both a TextField and a KeywordField
-	docMapping.AddFieldMappingsAt("slug", bleve.NewTextFieldMapping())
+	docMapping.AddFieldMappingsAt("slug", bleve.NewKeywordFieldMapping())
 	docMapping.AddFieldMappingsAt("postType", bleve.NewTextFieldMapping())
 	docMapping.AddFieldMappingsAt("content", bleve.NewTextFieldMapping())
 	docMapping.AddFieldMappingsAt("name", bleve.NewTextFieldMapping())
@@ -402,8 +723,9 @@ func (bbs *BBoltStore) defineBleveMapping() *mapping.IndexMappingImpl {
 	docMapping.AddFieldMappingsAt("featured", bleve.NewBooleanFieldMapping())
 	docMapping.AddFieldMappingsAt("status", bleve.NewTextFieldMapping())
 	docMapping.AddFieldMappingsAt("published", bleve.NewDateTimeFieldMapping())
+	docMapping.AddFieldMappingsAt("expires", bleve.NewDateTimeFieldMapping())
 	docMapping.AddFieldMappingsAt("updated", bleve.NewDateTimeFieldMapping())
-	docMapping.AddFieldMappingsAt("authors", bleve.NewTextFieldMapping())
+	docMapping.AddFieldMappingsAt("author", bleve.NewKeywordFieldMapping())
 
 	// Create a sub-mapping for taxonomies
 	taxonomyMapping := bleve.NewDocumentMapping()
@@ -452,6 +774,34 @@ func (bbs *BBoltStore) updateTaxonomyCount(tx *bbolt.Tx, taxonomy, term string,
 	return b.Put(key, newCount)
 }
 
+// updatePaths removes the old URL paths of the post from the bucket, unless another post has them now, and adds the
+// new ones. Empty paths are ignored.
+func (bbs *BBoltStore) updatePaths(tx *bbolt.Tx, bucket, postID string, oldPaths, newPaths []string) error {
+	b := tx.Bucket([]byte(bucket))
+	if b == nil {
+		return fmt.Errorf("bucket not found")
+	}
+
+	for _, urlPath := range oldPaths {
+		if urlPath != "" && string(b.Get([]byte(urlPath))) == postID {
+			if err := b.Delete([]byte(urlPath)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, urlPath := range newPaths {
+		if urlPath == "" {
+			continue
+		}
+		if err := b.Put([]byte(urlPath), []byte(postID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (bbs *BBoltStore) postsFromSearchRequest(request *bleve.SearchRequest, checkField, checkValue string) (*bleve.SearchResult, []*downcache.Post, error) {
 	result, err := bbs.bleveIndex.Search(request)
 	if err != nil {
@@ -465,6 +815,18 @@ func (bbs *BBoltStore) postsFromSearchRequest(request *bleve.SearchRequest, chec
 			if err != nil {
 				return nil, nil, fmt.Errorf("error getting post %s: %w", hit.ID, err)
 			}
+
+			if request.Highlight != nil {
+				doc.Score = hit.Score
+				doc.Highlights = make(map[string]string, len(hit.Fragments))
+				for field, fragments := range hit.Fragments {
+					// Empty fields have an empty fragment rather than none
+					if highlight := strings.Join(fragments, downcache.DefaultHighlightEllipsis); highlight != "" {
+						doc.Highlights[field] = highlight
+					}
+				}
+			}
+
 			docs = append(docs, doc)
 		}
 	}
@@ -476,15 +838,21 @@ func (bbs *BBoltStore) searchRequest(query query.Query, pageNum, pageSize int, s
 	offset := (pageNum - 1) * pageSize
 	request := bleve.NewSearchRequestOptions(query, pageSize, offset, true)
 
-	if len(sortBy) > 0 {
-		request.SortBy(sortBy)
-	} else {
-		request.SortBy([]string{
-			"-featured",
-			"-published",
-			"name",
-		})
+	// Sort by the index fields, with the document ID as a tie-breaker so cursors are stable
+	sortFields := make([]string, 0, len(sortBy)+1)
+	for _, field := range sortBy {
+		name, descending := downcache.ParseSortField(field)
+		indexField, ok := bleveSortFields[name]
+		if !ok {
+			continue
+		}
+
+		if descending {
+			indexField = "-" + indexField
+		}
+		sortFields = append(sortFields, indexField)
 	}
+	request.SortBy(append(sortFields, "_id"))
 
 	requestFields := []string{
 		"slug",
@@ -492,7 +860,7 @@ func (bbs *BBoltStore) searchRequest(query query.Query, pageNum, pageSize int, s
 		"postType",
 		"published",
 		"updated",
-		"authors",
+		"author",
 	}
 
 	request.Fields = append(requestFields, fields...)
@@ -516,12 +884,6 @@ func (bbs *BBoltStore) searchQuery(postType downcache.PostType, matches ...match
 			continue
 		}
 
-		if strings.ToLower(field) == "search" {
-			search := bleve.NewQueryStringQuery(value)
-			queries = append(queries, search)
-			continue
-		}
-
 		termQuery := bleve.NewMatchQuery(match.value)
 		termQuery.SetField(match.field)
 		queries = append(queries, termQuery)
@@ -530,6 +892,129 @@ func (bbs *BBoltStore) searchQuery(postType downcache.PostType, matches ...match
 	return bleve.NewConjunctionQuery(queries...)
 }
 
+// propertyQuery translates a property filter into a Bleve query on the properties sub-document. The filter value is
+// typed using downcache.InferPropertyType, so numbers and dates use range queries. It returns true if the query
+// must not match, which is the case for downcache.FilterOpNe.
+func (bbs *BBoltStore) propertyQuery(prop downcache.KeyValueFilter) (query.Query, bool) {
+	field := fmt.Sprintf("properties.%s", prop.Key)
+	inclusive := true
+	exclusive := false
+
+	switch prop.Operator() {
+	case downcache.FilterOpExists:
+		existsQuery := bleve.NewWildcardQuery("*")
+		existsQuery.SetField(field)
+		return existsQuery, false
+	case downcache.FilterOpNe:
+		eq := prop
+		eq.Op = downcache.FilterOpEq
+		eqQuery, _ := bbs.propertyQuery(eq)
+		return eqQuery, true
+	case downcache.FilterOpPrefix:
+		prefixQuery := bleve.NewPrefixQuery(prop.Value)
+		prefixQuery.SetField(field)
+		return prefixQuery, false
+	case downcache.FilterOpGt, downcache.FilterOpGte, downcache.FilterOpLt, downcache.FilterOpLte:
+		op := prop.Operator()
+		isMin := op == downcache.FilterOpGt || op == downcache.FilterOpGte
+		incl := &exclusive
+		if op == downcache.FilterOpGte || op == downcache.FilterOpLte {
+			incl = &inclusive
+		}
+
+		propType := downcache.InferPropertyType(prop.Value)
+		parsed, _ := downcache.ParsePropertyValue(prop.Value, propType)
+		switch v := parsed.(type) {
+		case float64:
+			var rangeQuery *query.NumericRangeQuery
+			if isMin {
+				rangeQuery = bleve.NewNumericRangeInclusiveQuery(&v, nil, incl, nil)
+			} else {
+				rangeQuery = bleve.NewNumericRangeInclusiveQuery(nil, &v, nil, incl)
+			}
+			rangeQuery.SetField(field)
+			return rangeQuery, false
+		case time.Time:
+			var rangeQuery *query.DateRangeQuery
+			if isMin {
+				rangeQuery = bleve.NewDateRangeInclusiveQuery(v, time.Time{}, incl, nil)
+			} else {
+				rangeQuery = bleve.NewDateRangeInclusiveQuery(time.Time{}, v, nil, incl)
+			}
+			rangeQuery.SetField(field)
+			return rangeQuery, false
+		default:
+			var rangeQuery *query.TermRangeQuery
+			if isMin {
+				rangeQuery = bleve.NewTermRangeInclusiveQuery(prop.Value, "", incl, nil)
+			} else {
+				rangeQuery = bleve.NewTermRangeInclusiveQuery("", prop.Value, nil, incl)
+			}
+			rangeQuery.SetField(field)
+			return rangeQuery, false
+		}
+	}
+
+	// FilterOpEq and FilterOpIn match any of the filter values
+	values := make([]query.Query, 0, len(prop.FilterValues()))
+	for _, value := range prop.FilterValues() {
+		if b, err := downcache.ParsePropertyValue(value, downcache.PropertyTypeBool); err == nil {
+			boolQuery := bleve.NewBoolFieldQuery(b.(bool))
+			boolQuery.SetField(field)
+			values = append(values, boolQuery)
+			continue
+		}
+
+		if num, err := downcache.ParsePropertyValue(value, downcache.PropertyTypeNumber); err == nil {
+			n := num.(float64)
+			numQuery := bleve.NewNumericRangeInclusiveQuery(&n, &n, &inclusive, &inclusive)
+			numQuery.SetField(field)
+			values = append(values, numQuery)
+			continue
+		}
+
+		matchQuery := bleve.NewMatchQuery(value)
+		matchQuery.SetField(field)
+		values = append(values, matchQuery)
+	}
+
+	return bleve.NewDisjunctionQuery(values...), false
+}
+
+// textQuery translates a full-text query into a Bleve query. Terms are added as match and phrase queries rather
+// than a query string, so user input is never interpreted as Bleve query syntax. Terms only match the textFields,
+// not the post's status, type or other fields.
+func (bbs *BBoltStore) textQuery(text downcache.TextQuery) query.Query {
+	clauses := make([]query.Query, 0, len(text))
+	for _, clause := range text {
+		boolQuery := bleve.NewBooleanQuery()
+		for _, term := range clause {
+			fieldQueries := make([]query.Query, 0, len(textFields))
+			for _, field := range textFields {
+				if term.Phrase {
+					phraseQuery := bleve.NewMatchPhraseQuery(term.Value)
+					phraseQuery.SetField(field)
+					fieldQueries = append(fieldQueries, phraseQuery)
+				} else {
+					matchQuery := bleve.NewMatchQuery(term.Value)
+					matchQuery.SetField(field)
+					fieldQueries = append(fieldQueries, matchQuery)
+				}
+			}
+			termQuery := bleve.NewDisjunctionQuery(fieldQueries...)
+
+			if term.Negate {
+				boolQuery.AddMustNot(termQuery)
+			} else {
+				boolQuery.AddMust(termQuery)
+			}
+		}
+		clauses = append(clauses, boolQuery)
+	}
+
+	return bleve.NewDisjunctionQuery(clauses...)
+}
+
 // convertToStringSlice converts a []byte to a []string
 func anyToStringSlice(value any) []string {
 	if val, ok := value.(string); ok {
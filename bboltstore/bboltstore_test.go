@@ -0,0 +1,418 @@
+package bboltstore_test
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+	"github.com/hypergopher/downcache/bboltstore"
+)
+
+func setupTestStore(t *testing.T) *bboltstore.BBoltStore {
+	store := bboltstore.New(t.TempDir(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, store.Init())
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	return store
+}
+
+func createTestPost(t *testing.T, store *bboltstore.BBoltStore, post *downcache.Post) {
+	post.PostID = downcache.PostPathID(post.PostType, post.Slug)
+	_, err := store.Create(post)
+	require.NoError(t, err)
+}
+
+func searchSlugs(t *testing.T, store *bboltstore.BBoltStore, filter downcache.FilterOptions) []string {
+	page, err := store.Search(filter)
+	require.NoError(t, err)
+
+	var slugs []string
+	for _, post := range page.AllPosts {
+		slugs = append(slugs, post.Slug)
+	}
+	return slugs
+}
+
+func TestBBoltStore_SearchSchedule(t *testing.T) {
+	store := setupTestStore(t)
+
+	now := time.Now().UTC()
+	date := func(d time.Duration) sql.NullString {
+		return sql.NullString{String: now.Add(d).Format(time.RFC3339), Valid: true}
+	}
+
+	for _, post := range []*downcache.Post{
+		{Name: "Undated", Slug: "undated", PostType: "article"},
+		{Name: "Live", Slug: "live", PostType: "article", Published: date(-time.Hour), Expires: date(time.Hour)},
+		{Name: "Scheduled", Slug: "scheduled", PostType: "article", Published: date(24 * time.Hour)},
+		{Name: "Expired", Slug: "expired", PostType: "article", Published: date(-48 * time.Hour), Expires: date(-24 * time.Hour)},
+	} {
+		post.Status = "published"
+		post.Visibility = "public"
+		createTestPost(t, store, post)
+	}
+
+	cases := []struct {
+		name     string
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"Now", downcache.FilterOptions{}, []string{"undated", "live"}},
+		{"As of tomorrow", downcache.FilterOptions{AsOf: now.Add(36 * time.Hour)}, []string{"undated", "scheduled"}},
+		{"As of two days ago", downcache.FilterOptions{AsOf: now.Add(-36 * time.Hour)}, []string{"undated", "expired"}},
+		{"Include scheduled", downcache.FilterOptions{IncludeScheduled: true}, []string{"undated", "live", "scheduled", "expired"}},
+		{"Expiring", downcache.FilterOptions{IncludeScheduled: true, FilterExpiresAfter: now}, []string{"live"}},
+		{"Expired before now", downcache.FilterOptions{IncludeScheduled: true, FilterExpiresBefore: now}, []string{"expired"}},
+		{"Published after", downcache.FilterOptions{IncludeScheduled: true, FilterPublishedAfter: now.Add(-2 * time.Hour)}, []string{"live", "scheduled"}},
+		{"Published between", downcache.FilterOptions{IncludeScheduled: true, FilterPublishedAfter: now.Add(-72 * time.Hour), FilterPublishedBefore: now}, []string{"live", "expired"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.expected, searchSlugs(t, store, tc.filter))
+		})
+	}
+
+	// Posts are sorted by their published date, newest first, and undated posts last
+	assert.Equal(t, []string{"scheduled", "live", "expired", "undated"},
+		searchSlugs(t, store, downcache.FilterOptions{IncludeScheduled: true}))
+}
+
+func TestBBoltStore_SearchViewer(t *testing.T) {
+	store := setupTestStore(t)
+
+	createTestPost(t, store, &downcache.Post{Name: "Public", Slug: "public", PostType: "article", Author: "jane", Status: "published", Visibility: "public"})
+	createTestPost(t, store, &downcache.Post{Name: "Unlisted", Slug: "unlisted", PostType: "article", Author: "jane", Status: "published", Visibility: "unlisted"})
+	createTestPost(t, store, &downcache.Post{Name: "Jane's draft", Slug: "jane-draft", PostType: "article", Author: "jane", Status: "draft", Visibility: "public"})
+	createTestPost(t, store, &downcache.Post{Name: "Jane Doe's draft", Slug: "jane-doe-draft", PostType: "article", Author: "jane doe", Status: "draft", Visibility: "public"})
+	createTestPost(t, store, &downcache.Post{Name: "Bob's private", Slug: "bob-private", PostType: "article", Author: "bob", Status: "published", Visibility: "private"})
+	createTestPost(t, store, &downcache.Post{Name: "Defaults", Slug: "defaults", PostType: "article"})
+
+	admin := downcache.Viewer{Role: downcache.ViewerAdmin}
+	cases := []struct {
+		name     string
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"Anonymous by default", downcache.FilterOptions{}, []string{"public", "defaults"}},
+		{"Anonymous draft filter", downcache.FilterOptions{FilterStatus: "draft"}, nil},
+		{"Author", downcache.FilterOptions{Viewer: downcache.Viewer{Role: downcache.ViewerAuthor, Author: "jane"}}, []string{"public", "unlisted", "jane-draft", "defaults"}},
+		{"Admin", downcache.FilterOptions{Viewer: admin}, []string{"public", "unlisted", "jane-draft", "jane-doe-draft", "bob-private", "defaults"}},
+		{"Admin drafts", downcache.FilterOptions{Viewer: admin, FilterStatus: "draft"}, []string{"jane-draft", "jane-doe-draft"}},
+		{"Admin author filter", downcache.FilterOptions{Viewer: admin, FilterAuthor: "jane"}, []string{"public", "unlisted", "jane-draft"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.expected, searchSlugs(t, store, tc.filter))
+		})
+	}
+}
+
+func TestBBoltStore_Search(t *testing.T) {
+	store := setupTestStore(t)
+
+	createTestPost(t, store, &downcache.Post{
+		Name:       "Test Post",
+		Slug:       "test-post",
+		PostType:   "article",
+		Content:    "This is a test post",
+		Status:     "draft",
+		Visibility: "public",
+		Properties: map[string]any{"test1": "test 1", "test2": "test 2"},
+		Taxonomies: map[string][]string{"tags": {"tag1", "tag2"}, "categories": {"cat1", "cat2"}},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:       "Test Post 2",
+		Slug:       "test-post-2",
+		PostType:   "article",
+		Content:    "This is a test post 2",
+		Author:     "Jane Smith",
+		Status:     "published",
+		Visibility: "public",
+		Properties: map[string]any{"test1": "test 1", "test2": "test 2"},
+		Taxonomies: map[string][]string{"tags": {"tag1", "tag2"}, "categories": {"cat1", "cat2"}},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:       "Test Post 3",
+		Slug:       "test-post-3",
+		PostType:   "page",
+		Content:    "This is a test post 3",
+		Author:     "Jane Doe",
+		Status:     "published",
+		Visibility: "private",
+		Properties: map[string]any{"test3": "test3"},
+		Taxonomies: map[string][]string{"tags": {"tag3"}, "categories": {"cat3"}},
+	})
+
+	cases := []struct {
+		name     string
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"All posts", downcache.FilterOptions{}, []string{"test-post", "test-post-2", "test-post-3"}},
+		{"Filter by post type", downcache.FilterOptions{FilterPostType: "page"}, []string{"test-post-3"}},
+		{"Filter by text", downcache.FilterOptions{FilterSearch: `"post 2"`}, []string{"test-post-2"}},
+		{"Filter by excluded text", downcache.FilterOptions{FilterSearch: "test -draft -private", FilterVisibility: "public"}, []string{"test-post", "test-post-2"}},
+		{"Filter by status", downcache.FilterOptions{FilterStatus: "draft"}, []string{"test-post"}},
+		{"Filter by author", downcache.FilterOptions{FilterAuthor: "Jane Smith"}, []string{"test-post-2"}},
+		{"Filter by tag", downcache.FilterOptions{FilterTaxonomies: []downcache.KeyValueFilter{{Key: "tags", Value: "tag3"}}}, []string{"test-post-3"}},
+		{"Filter by visibility and category", downcache.FilterOptions{
+			FilterVisibility: "private",
+			FilterTaxonomies: []downcache.KeyValueFilter{{Key: "categories", Value: "cat3"}},
+		}, []string{"test-post-3"}},
+		{"Filter by any of tags, excluding a category", downcache.FilterOptions{
+			FilterTaxonomyGroups: []downcache.TaxonomyFilterGroup{
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "tag1"}, {Key: "tags", Value: "tag3"}}},
+				{Terms: []downcache.KeyValueFilter{{Key: "categories", Value: "cat3"}}, Negate: true},
+			},
+		}, []string{"test-post", "test-post-2"}},
+		{"Filter by all of tags", downcache.FilterOptions{
+			FilterTaxonomies: []downcache.KeyValueFilter{{Key: "tags", Value: "tag1"}, {Key: "tags", Value: "tag2"}},
+		}, []string{"test-post", "test-post-2"}},
+		{"Filter by property", downcache.FilterOptions{
+			FilterProperties: []downcache.KeyValueFilter{{Key: "test1", Value: "test 1"}},
+		}, []string{"test-post", "test-post-2"}},
+		{"Anonymous viewer", downcache.FilterOptions{Viewer: downcache.AnonymousViewer}, []string{"test-post-2"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Search as an admin, who can see drafts and private posts, unless the case has a viewer
+			if tc.filter.Viewer.Role == "" {
+				tc.filter.Viewer = downcache.Viewer{Role: downcache.ViewerAdmin}
+			}
+			assert.ElementsMatch(t, tc.expected, searchSlugs(t, store, tc.filter))
+		})
+	}
+}
+
+func TestBBoltStore_SearchHighlight(t *testing.T) {
+	store := setupTestStore(t)
+
+	createTestPost(t, store, &downcache.Post{Name: "Test Post", Slug: "test-post", PostType: "article", Content: "This is a test post"})
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Gophers",
+		Slug:     "gophers",
+		PostType: "article",
+		Content:  "Gophers are small burrowing rodents. Some gophers are also mascots.",
+	})
+
+	page, err := store.Search(downcache.FilterOptions{FilterSearch: "gophers", Highlight: true})
+	require.NoError(t, err)
+	if assert.Len(t, page.AllPosts, 1) {
+		post := page.AllPosts[0]
+		assert.Equal(t, "<mark>Gophers</mark>", post.Highlights["name"])
+		assert.Contains(t, post.Highlights["content"], "<mark>Gophers</mark> are small")
+		assert.NotContains(t, post.Highlights, "summary")
+		assert.Greater(t, post.Score, 0.0)
+	}
+
+	// Without highlighting, no highlights or score are returned
+	page, err = store.Search(downcache.FilterOptions{FilterSearch: "gophers"})
+	require.NoError(t, err)
+	if assert.Len(t, page.AllPosts, 1) {
+		assert.Empty(t, page.AllPosts[0].Highlights)
+		assert.Zero(t, page.AllPosts[0].Score)
+	}
+}
+
+func TestBBoltStore_SearchProperties(t *testing.T) {
+	store := setupTestStore(t)
+
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Pancakes",
+		Slug:     "pancakes",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  20,
+			"difficulty": "easy",
+			"vegan":      false,
+			"added":      time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+			"meals":      []any{"breakfast", "brunch"},
+		},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Risotto",
+		Slug:     "risotto",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  45,
+			"difficulty": "medium",
+			"vegan":      true,
+			"added":      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			"meals":      []any{"dinner"},
+		},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Souffle",
+		Slug:     "souffle",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  25,
+			"difficulty": "hard",
+		},
+	})
+
+	cases := []struct {
+		name     string
+		filters  []downcache.KeyValueFilter
+		expected []string
+	}{
+		{"Number lt", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"}}, []string{"pancakes", "souffle"}},
+		{"Number gte", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpGte, Value: "25"}}, []string{"risotto", "souffle"}},
+		{"In", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}}}, []string{"pancakes", "risotto"}},
+		{"Bool eq", []downcache.KeyValueFilter{{Key: "vegan", Value: "true"}}, []string{"risotto"}},
+		{"Ne includes missing", []downcache.KeyValueFilter{{Key: "vegan", Op: downcache.FilterOpNe, Value: "true"}}, []string{"pancakes", "souffle"}},
+		{"Exists", []downcache.KeyValueFilter{{Key: "meals", Op: downcache.FilterOpExists}}, []string{"pancakes", "risotto"}},
+		{"Date gt", []downcache.KeyValueFilter{{Key: "added", Op: downcache.FilterOpGt, Value: "2024-02-01"}}, []string{"risotto"}},
+		{"List eq", []downcache.KeyValueFilter{{Key: "meals", Value: "brunch"}}, []string{"pancakes"}},
+		{"Prefix", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpPrefix, Value: "me"}}, []string{"risotto"}},
+		{"Combined", []downcache.KeyValueFilter{
+			{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"},
+			{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}},
+		}, []string{"pancakes"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.expected, searchSlugs(t, store, downcache.FilterOptions{FilterProperties: tc.filters}))
+		})
+	}
+}
+
+func TestBBoltStore_SearchCursor(t *testing.T) {
+	store := setupTestStore(t)
+
+	for i := 1; i <= 5; i++ {
+		createTestPost(t, store, &downcache.Post{
+			Name:      fmt.Sprintf("Note %d", i),
+			Slug:      fmt.Sprintf("note-%d", i),
+			PostType:  "notes",
+			Content:   "A short note",
+			Published: sql.NullString{String: fmt.Sprintf("2024-01-0%d", i), Valid: true},
+		})
+	}
+
+	search := func(filter downcache.FilterOptions) ([]string, downcache.Paginator) {
+		t.Helper()
+		filter.FilterPostType = "notes"
+		filter.PageSize = 2
+		page, err := store.Search(filter)
+		require.NoError(t, err)
+
+		var slugs []string
+		for _, post := range page.AllPosts {
+			slugs = append(slugs, post.Slug)
+		}
+		return slugs, page
+	}
+
+	slugs, page := search(downcache.FilterOptions{})
+	assert.Equal(t, []string{"note-5", "note-4"}, slugs)
+	assert.True(t, page.HasNext)
+
+	// A new post doesn't shift the following pages
+	createTestPost(t, store, &downcache.Post{
+		Name:      "Note 6",
+		Slug:      "note-6",
+		PostType:  "notes",
+		Published: sql.NullString{String: "2024-01-06", Valid: true},
+	})
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+	assert.True(t, page.HasPrev)
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-1"}, slugs)
+	assert.False(t, page.HasNext)
+
+	slugs, page = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+
+	slugs, _ = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-5", "note-4"}, slugs)
+
+	// Cursors are tied to the sort order
+	_, err := store.Search(downcache.FilterOptions{After: page.PrevCursor, SortBy: []string{"name"}})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+
+	_, err = store.Search(downcache.FilterOptions{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+}
+
+func TestBBoltStore_SearchCursorScore(t *testing.T) {
+	store := setupTestStore(t)
+
+	for i := 1; i <= 4; i++ {
+		createTestPost(t, store, &downcache.Post{
+			Name:     fmt.Sprintf("Gopher %d", i),
+			Slug:     fmt.Sprintf("gopher-%d", i),
+			PostType: "notes",
+			Content:  strings.Repeat("gopher ", i) + strings.Repeat("burrow ", 10),
+		})
+	}
+
+	filter := downcache.FilterOptions{FilterSearch: "gopher", Highlight: true, PageSize: 3}
+
+	var slugs []string
+	for {
+		page, err := store.Search(filter)
+		require.NoError(t, err)
+		for _, post := range page.AllPosts {
+			slugs = append(slugs, post.Slug)
+		}
+
+		if !page.HasNext {
+			break
+		}
+		filter.After = page.NextCursor
+	}
+
+	assert.Equal(t, []string{"gopher-4", "gopher-3", "gopher-2", "gopher-1"}, slugs)
+}
+
+func TestBBoltStore_Paths(t *testing.T) {
+	store := setupTestStore(t)
+
+	createTestPost(t, store, &downcache.Post{Name: "First", Slug: "first", PostType: "article", Permalink: "/2024/03/first/", Aliases: []string{"/old/first", "/article/1"}})
+	second := &downcache.Post{Name: "Second", Slug: "second", PostType: "article", Permalink: "/2024/03/second/"}
+	createTestPost(t, store, second)
+
+	post, err := store.ResolvePermalink("/2024/03/second")
+	require.NoError(t, err)
+	assert.Equal(t, "second", post.Slug)
+
+	post, err = store.ResolveAlias("/old/first/")
+	require.NoError(t, err)
+	assert.Equal(t, "first", post.Slug)
+
+	assert.Equal(t, []string{"second"}, searchSlugs(t, store, downcache.FilterOptions{FilterPermalink: "/2024/03/second/"}))
+	assert.Equal(t, []string{"first"}, searchSlugs(t, store, downcache.FilterOptions{FilterAlias: "/article/1"}))
+
+	// Updating a post replaces its paths
+	second.Permalink = "/2024/04/second/"
+	second.Aliases = []string{"/2024/03/second/"}
+	createTestPost(t, store, second)
+
+	post, err = store.ResolveAlias("/2024/03/second/")
+	require.NoError(t, err)
+	assert.Equal(t, "second", post.Slug)
+	assert.Empty(t, searchSlugs(t, store, downcache.FilterOptions{FilterPermalink: "/2024/03/second/"}))
+
+	// Deleting a post removes its paths
+	require.NoError(t, store.Delete(downcache.PostPathID("article", "first")))
+	_, err = store.ResolveAlias("/old/first")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+	assert.Empty(t, searchSlugs(t, store, downcache.FilterOptions{FilterAlias: "/article/1"}))
+}
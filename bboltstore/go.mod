@@ -7,6 +7,7 @@ replace github.com/hypergopher/downcache => ../
 require (
 	github.com/blevesearch/bleve/v2 v2.4.2
 	github.com/hypergopher/downcache v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.9.0
 	go.etcd.io/bbolt v1.3.11
 )
 
@@ -31,6 +32,7 @@ require (
 	github.com/blevesearch/zapx/v14 v14.3.10 // indirect
 	github.com/blevesearch/zapx/v15 v15.3.13 // indirect
 	github.com/blevesearch/zapx/v16 v16.1.5 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/golang/geo v0.0.0-20210211234256-740aa86cb551 // indirect
 	github.com/golang/protobuf v1.3.2 // indirect
@@ -43,6 +45,7 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mschoch/smat v0.2.0 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	github.com/yuin/goldmark v1.7.4 // indirect
 	go.abhg.dev/goldmark/frontmatter v0.2.0 // indirect
@@ -0,0 +1,255 @@
+package downcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BundleIndexName is the name, without an extension, of the markdown file of a page bundle: a directory below a post
+// type holding a post and its assets, such as images and attachments. The post's slug is the directory's.
+const BundleIndexName = "index"
+
+// Asset is a file in a page bundle, other than its index file.
+type Asset struct {
+	Path     string `json:"path"`     // The path relative to the bundle directory, with forward slashes
+	MIMEType string `json:"mimeType"` // From the file extension, or sniffed from the contents if it is unknown
+	Size     int64  `json:"size"`     // In bytes
+	Hash     string `json:"hash"`     // The hex encoded SHA-256 hash of the contents
+}
+
+// Asset returns the post's asset with the path, if it has one.
+func (p *Post) Asset(path string) (Asset, bool) {
+	for _, asset := range p.Assets {
+		if asset.Path == path {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetFS is a MarkdownFS that supports page bundles. Walk and Read set the Assets of posts in page bundles, and
+// Move and Delete move and delete their assets with them.
+type AssetFS interface {
+	MarkdownFS
+	// OpenAsset opens an asset of a post in a page bundle. It returns an error matching fs.ErrNotExist if the post
+	// isn't in a bundle or doesn't have the asset.
+	OpenAsset(ctx context.Context, postType, slug, path string) (fs.File, error)
+}
+
+// AssetHandler returns an http.Handler that serves the assets of page bundles at /{postType}/{slug}/{asset path}.
+// Use http.StripPrefix to serve them under another path. Assets are only served if the viewer in the request
+// context can see the post, as with Get, so wrap the handler with PreviewMiddleware to serve the assets of posts
+// being previewed. Unknown assets, and every asset if the MarkdownFS isn't an AssetFS, are 404 Not Found.
+func (cm *DownCache) AssetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assets, ok := cm.fs.(AssetFS)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Slugs and asset paths can both contain slashes, so try the longest slug first
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		for i := len(parts) - 1; i >= 2; i-- {
+			postType, slug, path := parts[0], strings.Join(parts[1:i], "/"), strings.Join(parts[i:], "/")
+
+			post, err := cm.Get(r.Context(), postType, slug)
+			if err != nil {
+				continue
+			}
+			asset, ok := post.Asset(path)
+			if !ok {
+				continue
+			}
+
+			cm.serveAsset(w, r, assets, post, asset)
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+}
+
+// serveAsset writes an asset to the response, handling conditional and range requests.
+func (cm *DownCache) serveAsset(w http.ResponseWriter, r *http.Request, assets AssetFS, post *Post, asset Asset) {
+	f, err := assets.OpenAsset(r.Context(), post.PostType, post.Slug, asset.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "error opening asset", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "error opening asset", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.MIMEType)
+	w.Header().Set("ETag", `"`+asset.Hash+`"`)
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, asset.Path, info.ModTime(), rs)
+		return
+	}
+
+	_, _ = io.Copy(w, f)
+}
+
+var _ AssetFS = (*LocalMarkdownFS)(nil)
+
+func (fs *LocalMarkdownFS) OpenAsset(_ context.Context, postType, slug, path string) (fs.File, error) {
+	index, bundle := fs.postPath(postType, slug)
+	if !bundle {
+		return nil, fmt.Errorf("%s isn't a page bundle: %w", PostPathID(postType, slug), os.ErrNotExist)
+	}
+
+	if !validAssetPath(path, filepath.Base(index)) {
+		return nil, fmt.Errorf("invalid asset path %q: %w", path, os.ErrNotExist)
+	}
+
+	return os.Open(filepath.Join(filepath.Dir(index), filepath.FromSlash(path)))
+}
+
+// validAssetPath returns true if the path is one bundleAssets could return: a relative path inside the bundle,
+// other than its index file, without hidden files or directories.
+func validAssetPath(path, index string) bool {
+	if !fs.ValidPath(path) || path == "." || path == index {
+		return false
+	}
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// bundleAssets returns the files in the directory of a page bundle's index file and its subdirectories, other than
+// the index file, in lexical order. Hidden files and directories, such as the temporary files of atomic writes, are
+// skipped. The assets of a section are only the files directly in its directory that aren't posts.
+func bundleAssets(index string, cfg walkConfig) ([]Asset, error) {
+	dir := filepath.Dir(index)
+	section := cfg.isSection(index)
+
+	var assets []Asset
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if section && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() || path == index || (section && cfg.isPost(path)) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		asset, err := readAsset(path)
+		if err != nil {
+			return err
+		}
+		asset.Path = filepath.ToSlash(relPath)
+
+		assets = append(assets, asset)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading page bundle %s: %w", dir, err)
+	}
+
+	return assets, nil
+}
+
+// readAsset returns the MIME type, size and hash of a file.
+func readAsset(path string) (Asset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Asset{}, err
+	}
+	defer f.Close()
+
+	// Read the start of the file to sniff its type from, if the extension is unknown
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return Asset{}, err
+	}
+	head = head[:n]
+
+	hash := sha256.New()
+	hash.Write(head)
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return Asset{}, err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(head)
+	}
+
+	return Asset{
+		MIMEType: mimeType,
+		Size:     int64(n) + size,
+		Hash:     fmt.Sprintf("%x", hash.Sum(nil)),
+	}, nil
+}
+
+// checkChildren returns ErrHasChildren if the post is a section, since its page bundle's directory also holds its
+// child posts, which would be deleted, moved or trashed with it.
+func (fs *LocalMarkdownFS) checkChildren(postType, slug, path string, bundle bool) error {
+	if bundle && fs.cfg.isSection(path) {
+		return fmt.Errorf("%w: %s", ErrHasChildren, PostPathID(postType, slug))
+	}
+	return nil
+}
+
+// isSection returns true if the page bundle of the index file has posts of its own, in its directory or below it.
+// The bundle is then a section, such as a chapter of the documentation: its posts are its children in a Tree.
+func (cfg walkConfig) isSection(index string) bool {
+	dir := filepath.Dir(index)
+	errFound := errors.New("found")
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		if hiddenOrTemp(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && path != index && cfg.isPost(path) {
+			return errFound
+		}
+		return nil
+	})
+	return errors.Is(err, errFound)
+}
@@ -0,0 +1,129 @@
+package downcache_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+// pngHeader is the start of a PNG file, enough to sniff its type.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func TestLocalMarkdownFS_Bundles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "index.md"), []byte("---\nname: Trip\n---\n\nSee the photo"))
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "photo.png"), pngHeader)
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "files", "notes"), []byte("Some notes"))
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", ".index.md.swp"), []byte("hidden"))
+	writeTestFile(t, filepath.Join(dir, "articles", "plain.md"), []byte("---\nname: Plain\n---\n\nNo assets"))
+
+	fs := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	expected := []downcache.Asset{
+		{Path: "files/notes", MIMEType: "text/plain; charset=utf-8", Size: 10, Hash: fmt.Sprintf("%x", sha256.Sum256([]byte("Some notes")))},
+		{Path: "photo.png", MIMEType: "image/png", Size: int64(len(pngHeader)), Hash: fmt.Sprintf("%x", sha256.Sum256(pngHeader))},
+	}
+
+	// A bundle is one post, with the other files in its directory as assets
+	ctx := context.Background()
+	posts, errs := fs.Walk(ctx)
+	walked := make(map[string]*downcache.Post)
+	for post := range posts {
+		walked[post.Slug] = post
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, walked, 2)
+	assert.Equal(t, "Trip", walked["trip"].Name)
+	assert.Equal(t, expected, walked["trip"].Assets)
+	assert.Empty(t, walked["plain"].Assets)
+
+	post, err := fs.Read(ctx, "articles", "trip")
+	require.NoError(t, err)
+	assert.Equal(t, expected, post.Assets)
+
+	// Writing the post updates its index.md
+	post.Name = "Road Trip"
+	require.NoError(t, fs.Write(ctx, post))
+	assert.NoFileExists(t, filepath.Join(dir, "articles", "trip.md"))
+	assert.Equal(t, expected, post.Assets)
+
+	// Moving and deleting the post carries its assets along
+	require.NoError(t, fs.Move(ctx, "articles", "trip", "notes", "road-trip"))
+	assert.NoDirExists(t, filepath.Join(dir, "articles", "trip"))
+	assert.FileExists(t, filepath.Join(dir, "notes", "road-trip", "photo.png"))
+
+	post, err = fs.Read(ctx, "notes", "road-trip")
+	require.NoError(t, err)
+	assert.Equal(t, "Road Trip", post.Name)
+	assert.Equal(t, expected, post.Assets)
+
+	require.NoError(t, fs.Trash(ctx, "notes", "road-trip", time.Now()))
+	assert.NoDirExists(t, filepath.Join(dir, "notes", "road-trip"))
+
+	trashed, err := fs.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, expected, trashed[0].Post.Assets)
+
+	require.NoError(t, fs.Untrash(ctx, "notes", "road-trip"))
+	assert.FileExists(t, filepath.Join(dir, "notes", "road-trip", "files", "notes"))
+
+	require.NoError(t, fs.Delete(ctx, "notes", "road-trip"))
+	assert.NoDirExists(t, filepath.Join(dir, "notes", "road-trip"))
+}
+
+func TestDownCache_AssetHandler(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "index.md"), []byte("---\nname: Trip\n---\n\nSee the photo"))
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "images", "photo.png"), pngHeader)
+	writeTestFile(t, filepath.Join(dir, "articles", "secret", "index.md"), []byte("---\nname: Secret\nstatus: draft\n---\n\nDraft"))
+	writeTestFile(t, filepath.Join(dir, "articles", "secret", "photo.png"), pngHeader)
+
+	cm := downcache.NewDownCache(downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML),
+		downcache.NewMemoryCacheStore())
+	require.NoError(t, cm.SyncAll(context.Background()))
+
+	server := httptest.NewServer(http.StripPrefix("/assets", cm.AssetHandler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/assets/articles/trip/images/photo.png")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, pngHeader, body)
+
+	// Unknown assets, the markdown itself, and the assets of posts the viewer can't see aren't served
+	for _, path := range []string{
+		"/assets/articles/trip/missing.png",
+		"/assets/articles/trip/index.md",
+		"/assets/articles/trip/../secret/photo.png",
+		"/assets/articles/secret/photo.png",
+	} {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
@@ -3,6 +3,7 @@ package downcache
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -129,16 +130,29 @@ func (m *MemoryCacheStore) Search(ctx context.Context, options FilterOptions) ([
 
 	var filtered []*Post
 
+	// Fix the live time, so every post is checked against the same time
+	if asOf, ok := options.LiveAsOf(); ok {
+		options.AsOf = asOf
+	}
+
+	access := options.CurrentViewer(ctx).Access(true)
+	text := options.TextQuery()
 	for _, post := range m.posts {
-		if m.postMatchesFilters(post, options) {
+		if access.Allows(post) && m.postMatchesFilters(post, text, options) {
 			filtered = append(filtered, post)
 		}
 	}
 
 	totalCount := len(filtered)
 
+	// Highlight search matches if requested
+	if options.Highlight && !text.IsEmpty() {
+		filtered = m.highlightPosts(filtered, text.Words(), options.HighlightOptions)
+	}
+
 	// Sort the filtered posts
-	m.sortPosts(filtered, options.SortBy)
+	sortBy := options.Sort()
+	m.sortPosts(filtered, sortBy)
 
 	// Split pinned items if required
 	var pinned []*Post
@@ -154,12 +168,22 @@ func (m *MemoryCacheStore) Search(ctx context.Context, options FilterOptions) ([
 		options.PageSize = 10
 	}
 
-	// Paginate the results
-	start, end := m.getPaginationBounds(options.PageNum, options.PageSize, len(filtered))
-	paginatedResults := filtered[start:end]
+	cursor, hasCursor, before, err := options.Cursor()
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Prepend pinned items if split
-	if options.SplitPinned {
+	// Paginate the results, seeking past the cursor if there is one
+	var paginatedResults []*Post
+	if hasCursor {
+		paginatedResults = m.seek(filtered, cursor, before, options.PageSize)
+	} else {
+		start, end := m.getPaginationBounds(options.PageNum, options.PageSize, len(filtered))
+		paginatedResults = filtered[start:end]
+	}
+
+	// Prepend pinned items if split. Cursor pages follow the first page, which already has them.
+	if options.SplitPinned && !hasCursor {
 		paginatedResults = append(pinned, paginatedResults...)
 	}
 
@@ -198,29 +222,54 @@ func (m *MemoryCacheStore) GetTaxonomyTerms(ctx context.Context, taxonomy string
 	return unique(terms), nil
 }
 
-// postMatchesFilters checks if a post matches the provided filters
-func (m *MemoryCacheStore) postMatchesFilters(post *Post, options FilterOptions) bool {
+// postMatchesFilters checks if a post matches the provided filters and full-text query
+func (m *MemoryCacheStore) postMatchesFilters(post *Post, text TextQuery, options FilterOptions) bool {
 	if options.FilterPostType != PostTypeKeyAny && string(options.FilterPostType) != post.PostType {
 		return false
 	}
 
-	if options.FilterStatus != "" && options.FilterStatus != post.Status {
+	if !isAnyFilter(options.FilterStatus) && options.FilterStatus != post.Status {
+		return false
+	}
+
+	if !isAnyFilter(options.FilterVisibility) && options.FilterVisibility != post.Visibility {
 		return false
 	}
 
-	if options.FilterVisibility != "" && options.FilterVisibility != post.Visibility {
+	if options.FilterPermalink != "" && post.Permalink != options.FilterPermalink {
 		return false
 	}
 
-	//if !options.IncludeUnpublished && post.Status != "published" {
-	//	return false
-	//}
+	if options.FilterAlias != "" && !slices.Contains(post.Aliases, options.FilterAlias) {
+		return false
+	}
 
 	if options.FilterAuthor != "" && !strings.Contains(post.Author, options.FilterAuthor) {
 		return false
 	}
 
-	if options.FilterSearch != "" && !strings.Contains(strings.ToLower(post.Content), strings.ToLower(options.FilterSearch)) {
+	if !text.IsEmpty() && !text.Match(strings.Join([]string{post.Name, post.Subtitle, post.Summary, post.Content}, "\n")) {
+		return false
+	}
+
+	published := parsePostDate(post.Published)
+	if !options.FilterPublishedAfter.IsZero() && (published.IsZero() || published.Before(options.FilterPublishedAfter)) {
+		return false
+	}
+
+	if !options.FilterPublishedBefore.IsZero() && (published.IsZero() || !published.Before(options.FilterPublishedBefore)) {
+		return false
+	}
+
+	if !options.FilterExpiresAfter.IsZero() && (!post.HasExpires() || post.ExpiresTime().Before(options.FilterExpiresAfter)) {
+		return false
+	}
+
+	if !options.FilterExpiresBefore.IsZero() && (!post.HasExpires() || !post.ExpiresTime().Before(options.FilterExpiresBefore)) {
+		return false
+	}
+
+	if asOf, ok := options.LiveAsOf(); ok && !post.IsLive(asOf) {
 		return false
 	}
 
@@ -230,8 +279,8 @@ func (m *MemoryCacheStore) postMatchesFilters(post *Post, options FilterOptions)
 		}
 	}
 
-	for _, tax := range options.FilterTaxonomies {
-		if !m.matchesKeyValueFilterSlice(post.Taxonomies, tax) {
+	for _, group := range options.TaxonomyGroups() {
+		if m.matchesTaxonomyGroup(post.Taxonomies, group) == group.Negate {
 			return false
 		}
 	}
@@ -239,16 +288,10 @@ func (m *MemoryCacheStore) postMatchesFilters(post *Post, options FilterOptions)
 	return true
 }
 
-// matchesKeyValueFilter checks if a map contains a key-value pair
-func (m *MemoryCacheStore) matchesKeyValueFilter(data map[string]string, filter KeyValueFilter) bool {
+// matchesKeyValueFilter checks if a map contains a key with a value that matches the filter
+func (m *MemoryCacheStore) matchesKeyValueFilter(data map[string]any, filter KeyValueFilter) bool {
 	value, exists := data[filter.Key]
-	if !exists {
-		return false
-	}
-
-	// Implement type-specific comparisons here
-	// This is a simple string comparison; extend as needed for other types
-	return fmt.Sprintf("%v", value) == fmt.Sprintf("%v", filter.Value)
+	return filter.MatchProperty(value, exists)
 }
 
 // matchesKeyValueFilterSlice checks if a map contains a key-value pair in a slice
@@ -267,24 +310,32 @@ func (m *MemoryCacheStore) matchesKeyValueFilterSlice(data map[string][]string,
 	return false
 }
 
+// matchesTaxonomyGroup checks if a map contains any of the key-value pairs in a taxonomy group
+func (m *MemoryCacheStore) matchesTaxonomyGroup(data map[string][]string, group TaxonomyFilterGroup) bool {
+	for _, term := range group.Terms {
+		if m.matchesKeyValueFilterSlice(data, term) {
+			return true
+		}
+	}
+	return false
+}
+
 // sortPosts sorts the posts based on the provided sort fields
 func (m *MemoryCacheStore) sortPosts(posts []*Post, sortBy []string) {
 	sort.Slice(posts, func(i, j int) bool {
 		for _, field := range sortBy {
-			descending := false
-			if strings.HasPrefix(field, "-") {
-				descending = true
-				field = field[1:]
-			}
+			field, descending := ParseSortField(field)
 
 			var comparison int
 			switch field {
 			case "pinned":
 				comparison = compareBool(posts[i].Pinned, posts[j].Pinned)
 			case "published":
-				comparison = compareTime(posts[i].PublishedTime(), posts[j].PublishedTime())
+				comparison = compareTime(parsePostDate(posts[i].Published), parsePostDate(posts[j].Published))
 			case "name":
 				comparison = strings.Compare(posts[i].Slug, posts[j].Slug)
+			case "score":
+				comparison = compareFloat(posts[i].Score, posts[j].Score)
 			// Add more cases for other fields as needed
 			default:
 				continue
@@ -297,7 +348,9 @@ func (m *MemoryCacheStore) sortPosts(posts []*Post, sortBy []string) {
 				return comparison < 0
 			}
 		}
-		return false
+
+		// Fall back to the post path ID so the order is stable between searches, and matches the cursor tie-breaker
+		return PostPathID(posts[i].PostType, posts[i].Slug) < PostPathID(posts[j].PostType, posts[j].Slug)
 	})
 }
 
@@ -324,6 +377,70 @@ func compareTime(a, b time.Time) int {
 	}
 }
 
+// compareFloat compares two float64 values
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// highlightFieldWeights are the fields that are highlighted in search results, along with their weight in the score
+var highlightFieldWeights = []struct {
+	field  string
+	weight float64
+}{
+	{"name", 4},
+	{"subtitle", 2},
+	{"summary", 2},
+	{"content", 1},
+}
+
+// highlightPosts returns copies of the posts with highlighted snippets and a relevance score for the search words.
+// Copies are returned so the stored posts are never modified by a search.
+func (m *MemoryCacheStore) highlightPosts(posts []*Post, words []string, opts HighlightOptions) []*Post {
+	highlighted := make([]*Post, 0, len(posts))
+	for _, post := range posts {
+		hit := *post
+		hit.Score = 0
+		hit.Highlights = make(map[string]string)
+
+		for _, fw := range highlightFieldWeights {
+			var value string
+			switch fw.field {
+			case "name":
+				value = post.Name
+			case "subtitle":
+				value = post.Subtitle
+			case "summary":
+				value = post.Summary
+			case "content":
+				value = post.Content
+			}
+
+			// Use the snippet for the first word that matches, but score all the words
+			for _, word := range words {
+				snippet, count := Snippet(value, word, opts)
+				if count == 0 {
+					continue
+				}
+
+				if _, ok := hit.Highlights[fw.field]; !ok {
+					hit.Highlights[fw.field] = snippet
+				}
+				hit.Score += float64(count) * fw.weight
+			}
+		}
+
+		highlighted = append(highlighted, &hit)
+	}
+	return highlighted
+}
+
 // splitPinned separates pinned posts from non-pinned posts
 func (m *MemoryCacheStore) splitPinned(posts []*Post) (pinned, nonPinned []*Post) {
 	for _, post := range posts {
@@ -336,6 +453,21 @@ func (m *MemoryCacheStore) splitPinned(posts []*Post) (pinned, nonPinned []*Post
 	return pinned, nonPinned
 }
 
+// seek returns up to pageSize of the sorted posts after the cursor, or before it if before is true
+func (m *MemoryCacheStore) seek(posts []*Post, cursor Cursor, before bool, pageSize int) []*Post {
+	if before {
+		end := sort.Search(len(posts), func(i int) bool {
+			return cursor.ComparePost(posts[i]) >= 0
+		})
+		return posts[max(0, end-pageSize):end]
+	}
+
+	start := sort.Search(len(posts), func(i int) bool {
+		return cursor.ComparePost(posts[i]) > 0
+	})
+	return posts[start:min(len(posts), start+pageSize)]
+}
+
 // getPaginationBounds calculates the start and end indices for pagination
 func (m *MemoryCacheStore) getPaginationBounds(pageNum, pageSize, totalItems int) (start, end int) {
 	start = (pageNum - 1) * pageSize
@@ -351,6 +483,11 @@ func (m *MemoryCacheStore) makeKey(postType, slug string) string {
 	return fmt.Sprintf("%s:%s", postType, slug)
 }
 
+// isAnyFilter returns true if a status or visibility filter matches any value
+func isAnyFilter(value string) bool {
+	return value == "" || value == FilterTypeAny.String()
+}
+
 func unique(slice []string) []string {
 	result := make([]string, 0, len(slice))
 	inResult := map[string]bool{}
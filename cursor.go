@@ -0,0 +1,152 @@
+package downcache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSortBy is the sort order used when FilterOptions.SortBy is empty and results aren't ranked by relevance.
+var DefaultSortBy = []string{"-pinned", "-published", "name"}
+
+// Cursor is the decoded form of an opaque pagination cursor. It records the sort key of the post it was created
+// from, so the next page can seek past it instead of counting an offset.
+type Cursor struct {
+	SortBy []string `json:"s"`  // The sort fields the cursor was created with
+	Values []string `json:"v"`  // The post's sort value for each field in SortBy (see SortValue)
+	PostID string   `json:"id"` // The post's PostPathID, which breaks ties between posts with the same sort values
+}
+
+// NewCursor returns a cursor positioned at the post for the given sort fields.
+func NewCursor(post *Post, sortBy []string) Cursor {
+	values := make([]string, 0, len(sortBy))
+	for _, field := range sortBy {
+		name, _ := ParseSortField(field)
+		values = append(values, SortValue(post, name))
+	}
+
+	return Cursor{
+		SortBy: slices.Clone(sortBy),
+		Values: values,
+		PostID: PostPathID(post.PostType, post.Slug),
+	}
+}
+
+// Encode returns the cursor as an opaque, URL-safe token.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a token returned by Cursor.Encode.
+func DecodeCursor(token string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if len(c.Values) != len(c.SortBy) || c.PostID == "" {
+		return Cursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+
+	for i, field := range c.SortBy {
+		name, _ := ParseSortField(field)
+		if _, err := ParseSortValue(name, c.Values[i]); err != nil {
+			return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+	}
+
+	return c, nil
+}
+
+// ComparePost compares the post to the cursor in the cursor's sort order. It returns a negative number if the post
+// sorts before the cursor, a positive number if it sorts after, and zero if it is the cursor's post.
+func (c Cursor) ComparePost(post *Post) int {
+	for i, field := range c.SortBy {
+		name, descending := ParseSortField(field)
+		cmp := CompareSortValues(name, SortValue(post, name), c.Values[i])
+		if descending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	return strings.Compare(PostPathID(post.PostType, post.Slug), c.PostID)
+}
+
+// ParseSortField splits a sort field such as -published into its name and whether it is descending.
+func ParseSortField(field string) (string, bool) {
+	if strings.HasPrefix(field, "-") {
+		return field[1:], true
+	}
+	return field, false
+}
+
+// SortValue returns the string form of a post's value for a sort field. Unknown fields return an empty string.
+func SortValue(post *Post, field string) string {
+	switch field {
+	case "pinned":
+		return strconv.FormatBool(post.Pinned)
+	case "published":
+		if published := parsePostDate(post.Published); !published.IsZero() {
+			return published.Format(time.RFC3339Nano)
+		}
+		return ""
+	case "name":
+		return post.Slug
+	case "score":
+		return strconv.FormatFloat(post.Score, 'g', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// ParseSortValue parses a value returned by SortValue. Pinned values are returned as a bool, published values as a
+// time.Time (the zero time if empty), scores as a float64 and anything else as a string.
+func ParseSortValue(field, value string) (any, error) {
+	switch field {
+	case "pinned":
+		return strconv.ParseBool(value)
+	case "published":
+		if value == "" {
+			return time.Time{}, nil
+		}
+		return time.Parse(time.RFC3339Nano, value)
+	case "score":
+		return strconv.ParseFloat(value, 64)
+	default:
+		return value, nil
+	}
+}
+
+// CompareSortValues compares two values returned by SortValue for the same field in ascending order.
+// Values that can't be parsed are compared as strings.
+func CompareSortValues(field, a, b string) int {
+	av, aErr := ParseSortValue(field, a)
+	bv, bErr := ParseSortValue(field, b)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+
+	switch av := av.(type) {
+	case bool:
+		return compareBool(av, bv.(bool))
+	case time.Time:
+		return compareTime(av, bv.(time.Time))
+	case float64:
+		return compareFloat(av, bv.(float64))
+	default:
+		return strings.Compare(a, b)
+	}
+}
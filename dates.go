@@ -0,0 +1,121 @@
+package downcache
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the layouts frontmatter dates are parsed with, most specific first. Fractional seconds are
+// accepted after the seconds of any of them.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700 MST", // time.Time's String, which file modification times used to be stored as
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"2006/01/02",
+	"2006-01-02",
+}
+
+// ParseDate parses a date in one of the common frontmatter formats, such as 2006-01-02, 2006-01-02 15:04,
+// 2006-01-02T15:04:05Z07:00 or Jan 2, 2006, and returns it in UTC. Dates without a time zone are in the location,
+// or in UTC if it is nil.
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if dt, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return dt.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
+
+// FormatDate formats a date as RFC 3339 in UTC, which is how the stores keep dates. Dates in this format sort in
+// time order as strings.
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatDate formats a date in the location, or UTC if it is nil, returning "" for the zero time.
+func formatDate(t time.Time, layout string, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(layout)
+}
+
+// normalizeDate returns the date in the format FormatDate returns. Dates that can't be parsed are returned as they
+// are, so they aren't lost.
+func normalizeDate(value string, loc *time.Location) string {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+	dt, err := ParseDate(value, loc)
+	if err != nil {
+		return value
+	}
+	return FormatDate(dt)
+}
+
+// normalizeNullDate is normalizeDate for an optional date.
+func normalizeNullDate(value sql.NullString, loc *time.Location) sql.NullString {
+	if !value.Valid {
+		return value
+	}
+	return sql.NullString{String: normalizeDate(value.String, loc), Valid: true}
+}
+
+// normalizeDates converts the post's dates to UTC in the format FormatDate returns, before it is added to the
+// store, so every store compares and sorts them the same way.
+func (cm *DownCache) normalizeDates(post *Post) {
+	post.Published = normalizeNullDate(post.Published, cm.location)
+	post.Expires = normalizeNullDate(post.Expires, cm.location)
+	post.Created = normalizeDate(post.Created, cm.location)
+	post.Updated = normalizeDate(post.Updated, cm.location)
+}
+
+// FrontmatterDate is a date in frontmatter. It can be written as a string in any of the formats ParseDate accepts,
+// or as a native TOML date or datetime.
+type FrontmatterDate string
+
+// UnmarshalTOML decodes a TOML string, offset datetime, local datetime or local date.
+func (d *FrontmatterDate) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case string:
+		*d = FrontmatterDate(v)
+	case time.Time:
+		// The TOML package gives local dates and datetimes these zones, with the offset of the system's time zone.
+		// They are in the default location instead.
+		switch v.Location().String() {
+		case "date-local":
+			*d = FrontmatterDate(v.Format("2006-01-02"))
+		case "datetime-local":
+			*d = FrontmatterDate(v.Format("2006-01-02T15:04:05.999999999"))
+		default:
+			*d = FrontmatterDate(v.Format(time.RFC3339Nano))
+		}
+	default:
+		return fmt.Errorf("invalid date %v", value)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package downcache_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestParseDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data is not installed")
+	}
+
+	cases := []struct {
+		value    string
+		loc      *time.Location
+		expected string
+	}{
+		{"2024-03-10", nil, "2024-03-10T00:00:00Z"},
+		{"2024-03-10", newYork, "2024-03-10T05:00:00Z"},
+		{"2024-03-10 01:30", newYork, "2024-03-10T06:30:00Z"},
+		{"2024-03-10 03:15:00", newYork, "2024-03-10T07:15:00Z"},
+		{"2024-03-10T03:15:00.5", newYork, "2024-03-10T07:15:00Z"},
+		{"2024-03-10T03:15:00+01:00", newYork, "2024-03-10T02:15:00Z"},
+		{"2024-03-10T03:15:00Z", newYork, "2024-03-10T03:15:00Z"},
+		{"2024-03-10 03:15:00.123 +0100 CET", nil, "2024-03-10T02:15:00Z"},
+		{"Sun, 10 Mar 2024 03:15:00 +0100", nil, "2024-03-10T02:15:00Z"},
+		{"March 10, 2024", nil, "2024-03-10T00:00:00Z"},
+		{"Mar 10, 2024", nil, "2024-03-10T00:00:00Z"},
+		{"10 Mar 2024", nil, "2024-03-10T00:00:00Z"},
+		{"2024/03/10", nil, "2024-03-10T00:00:00Z"},
+		{" 2024-03-10 ", nil, "2024-03-10T00:00:00Z"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.value, func(t *testing.T) {
+			dt, err := downcache.ParseDate(tc.value, tc.loc)
+			require.NoError(t, err)
+			assert.Equal(t, time.UTC, dt.Location())
+			assert.Equal(t, tc.expected, downcache.FormatDate(dt))
+		})
+	}
+
+	_, err = downcache.ParseDate("yesterday", nil)
+	assert.Error(t, err)
+}
+
+func TestFrontmatterDate(t *testing.T) {
+	post, err := realProcessor.Process([]byte("+++\nname = \"Native\"\npublished = 2024-03-10\nexpires = 2024-04-01T10:00:00\nupdated = 2024-03-11T12:00:00+02:00\n+++\n"))
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullString{String: "2024-03-10", Valid: true}, post.Published)
+	assert.Equal(t, sql.NullString{String: "2024-04-01T10:00:00", Valid: true}, post.Expires)
+	assert.Equal(t, "2024-03-11T12:00:00+02:00", post.Updated)
+
+	post, err = realProcessor.Process([]byte("---\nname: YAML\npublished: 2024-03-10 01:30\nupdated: 2024-03-11\n---\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-10 01:30", post.Published.String)
+	assert.Equal(t, "2024-03-11", post.Updated)
+	assert.True(t, post.HasPublished())
+	assert.Equal(t, "Mar 10, 2024", post.PublishedDate())
+	assert.Equal(t, "2024-03-11", post.FormatUpdated("2006-01-02", nil))
+}
+
+func TestDownCache_WithLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data is not installed")
+	}
+
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store, downcache.WithLocation(newYork))
+	assert.Equal(t, newYork, cm.Location())
+
+	// Clocks in New York went forward an hour at 2am on 2024-03-10
+	ctx := context.Background()
+	published := map[string]string{
+		"before-dst": "2024-03-10 01:30",
+		"utc":        "2024-03-10T06:45:00Z",
+		"after-dst":  "2024-03-10 03:15",
+	}
+	for slug, date := range published {
+		_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: slug, Published: sql.NullString{String: date, Valid: true}})
+	}
+	require.NoError(t, cm.SyncAll(ctx))
+
+	post, err := store.Get(ctx, "articles", "after-dst")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-10T07:15:00Z", post.Published.String)
+	assert.Equal(t, "Mar 10, 2024 3:15am", post.FormatPublished("Jan 2, 2006 3:04pm", cm.Location()))
+
+	posts, _, err := cm.Search(ctx, downcache.FilterOptions{FilterPostType: "articles", SortBy: []string{"published"}})
+	require.NoError(t, err)
+	var slugs []string
+	for _, post := range posts {
+		slugs = append(slugs, post.Slug)
+	}
+	assert.Equal(t, []string{"before-dst", "utc", "after-dst"}, slugs)
+
+	// Created posts are stored with normalized dates too
+	created, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "new", Published: sql.NullString{String: "2024-07-01", Valid: true}})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T04:00:00Z", created.Published.String)
+}
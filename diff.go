@@ -0,0 +1,220 @@
+package downcache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RevisionCurrent can be passed to Diff in place of a revision ID to compare with the current version of a post.
+const RevisionCurrent = "current"
+
+// DiffOp is how a line changed between two versions of a post.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = " " // The line is in both versions
+	DiffInsert DiffOp = "+" // The line was added
+	DiffDelete DiffOp = "-" // The line was removed
+)
+
+// DiffLine is a line of a post's body in a diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// FieldChange is a frontmatter field that changed between two versions of a post.
+type FieldChange struct {
+	Field string // The frontmatter name of the field, e.g. published
+	From  any
+	To    any
+}
+
+// RevisionDiff is the difference between two versions of a post: the frontmatter fields that changed, and a line
+// diff of the body.
+type RevisionDiff struct {
+	From   string // The revision ID the diff is from, or RevisionCurrent
+	To     string // The revision ID the diff is to, or RevisionCurrent
+	Fields []FieldChange
+	Lines  []DiffLine
+}
+
+// HasChanges returns true if any field or line changed.
+func (d *RevisionDiff) HasChanges() bool {
+	if len(d.Fields) > 0 {
+		return true
+	}
+
+	for _, line := range d.Lines {
+		if line.Op != DiffEqual {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the changed fields followed by the body diff, with each line prefixed by its DiffOp.
+func (d *RevisionDiff) String() string {
+	var sb strings.Builder
+	for _, field := range d.Fields {
+		fmt.Fprintf(&sb, "%s: %v -> %v\n", field.Field, field.From, field.To)
+	}
+
+	for _, line := range d.Lines {
+		sb.WriteString(string(line.Op))
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// Diff compares two versions of a post, each given as a revision ID or RevisionCurrent.
+func (cm *DownCache) Diff(ctx context.Context, postType, slug, from, to string) (*RevisionDiff, error) {
+	fromPost, err := cm.revisionPost(ctx, postType, slug, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toPost, err := cm.revisionPost(ctx, postType, slug, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffPosts(fromPost, toPost)
+	diff.From = from
+	diff.To = to
+
+	return diff, nil
+}
+
+// revisionPost returns the version of a post saved in a revision, or the current version for RevisionCurrent.
+func (cm *DownCache) revisionPost(ctx context.Context, postType, slug, id string) (*Post, error) {
+	if id == RevisionCurrent {
+		post, err := cm.fs.Read(ctx, postType, slug)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrNotFound, PostPathID(postType, slug), err)
+		}
+		return post, nil
+	}
+
+	rev, err := cm.GetRevision(ctx, postType, slug, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return rev.Post, nil
+}
+
+// DiffPosts compares the frontmatter fields and bodies of two versions of a post.
+func DiffPosts(from, to *Post) *RevisionDiff {
+	return &RevisionDiff{
+		Fields: diffMeta(from.Meta(), to.Meta()),
+		Lines:  diffLines(splitLines(stripFrontmatter(from.Content)), splitLines(stripFrontmatter(to.Content))),
+	}
+}
+
+// diffMeta returns the frontmatter fields that differ, in the order they are declared in PostMeta.
+// Empty and missing values are treated as equal.
+func diffMeta(from, to *PostMeta) []FieldChange {
+	var changes []FieldChange
+
+	fromValue := reflect.ValueOf(from).Elem()
+	toValue := reflect.ValueOf(to).Elem()
+	for i := range fromValue.NumField() {
+		a, b := fromValue.Field(i), toValue.Field(i)
+		if isEmptyValue(a) && isEmptyValue(b) || reflect.DeepEqual(a.Interface(), b.Interface()) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(fromValue.Type().Field(i).Tag.Get("yaml"), ",")
+		changes = append(changes, FieldChange{Field: name, From: a.Interface(), To: b.Interface()})
+	}
+
+	return changes
+}
+
+// isEmptyValue returns true for zero values and empty maps and slices.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// splitLines splits text into lines, without a trailing empty line for a final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns a line diff that turns a into b, using the longest common subsequence of their lines.
+func diffLines(a, b []string) []DiffLine {
+	// Lines in common at the start and end, which are most of the lines for a typical edit, don't need comparing
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, text := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+
+	i, j := 0, 0
+	for i < len(midA) && j < len(midB) {
+		switch {
+		case midA[i] == midB[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: midA[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: midA[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: midB[j]})
+			j++
+		}
+	}
+	for ; i < len(midA); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: midA[i]})
+	}
+	for ; j < len(midB); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: midB[j]})
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+
+	return lines
+}
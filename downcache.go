@@ -2,23 +2,108 @@ package downcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
 )
 
 // DownCache is the main entry point for the markdown cache system
 type DownCache struct {
-	fs    MarkdownFS
-	store CacheStore
+	fs            MarkdownFS
+	store         CacheStore
+	previewSecret []byte            // The secret used to sign preview tokens
+	journal       *journal          // The write-ahead journal, if a data directory is set
+	revisions     RevisionStore     // Where previous versions of posts are saved, if set
+	writeMu       sync.Mutex        // Held while a post is written, so writes and IfMatch checks can't interleave
+	readOnly      bool              // Whether writes to posts are rejected
+	location      *time.Location    // The location of dates without a time zone
+	permalinks    map[string]string // The permalink patterns of post types, if set
 }
 
-func NewDownCache(fs MarkdownFS, store CacheStore) *DownCache {
-	return &DownCache{fs: fs, store: store}
+// searchAllPageSize is the number of posts searchAll and searchPages fetch per search.
+const searchAllPageSize = 100
+
+// Option configures a DownCache.
+type Option func(*DownCache)
+
+func NewDownCache(fs MarkdownFS, store CacheStore, opts ...Option) *DownCache {
+	cm := &DownCache{fs: fs, store: store}
+	for _, opt := range opts {
+		opt(cm)
+	}
+	return cm
+}
+
+// WithReadOnly rejects every change to posts with ErrReadOnly. The store can still be synced from the filesystem.
+// A DownCache is always read-only if its MarkdownFS is, such as an IOFSMarkdownFS.
+func WithReadOnly() Option {
+	return func(cm *DownCache) {
+		cm.readOnly = true
+	}
 }
 
+// WithLocation sets the location of frontmatter dates without a time zone, such as 2006-01-02 or
+// 2006-01-02 15:04, which are in UTC by default. Dates are stored in UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(cm *DownCache) {
+		cm.location = loc
+	}
+}
+
+// Location returns the location of dates without a time zone, for formatting dates in templates.
+func (cm *DownCache) Location() *time.Location {
+	if cm.location == nil {
+		return time.UTC
+	}
+	return cm.location
+}
+
+// ReadOnly returns true if changes to posts are rejected, because of WithReadOnly or a read-only MarkdownFS.
+func (cm *DownCache) ReadOnly() bool {
+	if ro, ok := cm.fs.(interface{ ReadOnly() bool }); ok && ro.ReadOnly() {
+		return true
+	}
+	return cm.readOnly
+}
+
+// slugifier returns the Slugifier of the post type, from the filesystem if it has one.
+func (cm *DownCache) slugifier(postType string) Slugifier {
+	return fsSlugifier(cm.fs, postType)
+}
+
+// checkWritable returns ErrReadOnly if changes to posts are rejected.
+func (cm *DownCache) checkWritable(postType, slug string) error {
+	if cm.ReadOnly() {
+		return fmt.Errorf("%w: can't change %s", ErrReadOnly, PostPathID(postType, slug))
+	}
+	return nil
+}
+
+// SyncAll adds every post in the filesystem to the store. If more than one file has the same post type and slug,
+// such as articles/Hello World.md and articles/hello-world.md, the first found is synced and a *SlugCollisionError
+// with the paths of the files is returned for each slug, after the other posts are synced.
 func (cm *DownCache) SyncAll(ctx context.Context) error {
 	posts, errs := cm.fs.Walk(ctx)
 
+	// The source paths of the posts synced so far, to detect files with the same slug
+	synced := make(map[string]*SlugCollisionError)
+	var collisions []error
+
 	for post := range posts {
+		id := PostPathID(post.PostType, post.Slug)
+		if collision, ok := synced[id]; ok {
+			collision.Paths = append(collision.Paths, post.SourcePath())
+			if len(collision.Paths) == 2 {
+				collisions = append(collisions, collision)
+			}
+			continue
+		}
+		synced[id] = &SlugCollisionError{PostID: id, Paths: []string{post.SourcePath()}}
+
+		cm.index(post)
 		_, err := cm.store.Create(ctx, post)
 		if err != nil {
 			// If the post already exists, update it
@@ -33,71 +118,333 @@ func (cm *DownCache) SyncAll(ctx context.Context) error {
 		return fmt.Errorf("error walking filesystem: %w", err)
 	}
 
-	return nil
+	return errors.Join(collisions...)
+}
+
+// index prepares a post to be added to the store: its dates are normalized and its permalink is set.
+func (cm *DownCache) index(post *Post) {
+	cm.normalizeDates(post)
+	post.Permalink = cm.permalink(post)
+	post.Aliases = normalizeAliases(post.Aliases, post.Permalink)
 }
 
+// Create writes the post to the filesystem and adds it to the store. If the store can't be updated, the file is
+// removed again.
+//
+// If the post doesn't have a slug, one is made from its name that isn't used by another post of its type, adding
+// -2, -3 and so on if needed, such as hello-world-2.
 func (cm *DownCache) Create(ctx context.Context, post *Post) (*Post, error) {
+	if err := cm.checkWritable(post.PostType, post.Slug); err != nil {
+		return nil, err
+	}
+
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	if post.Slug == "" {
+		generated, err := cm.uniqueSlug(ctx, post.PostType, post.Name)
+		if err != nil {
+			return nil, err
+		}
+		post.Slug = generated
+	}
+
+	id, err := cm.journal.begin(JournalOpCreate, PostPathID(post.PostType, post.Slug))
+	if err != nil {
+		return nil, err
+	}
+
 	// Write to filesystem
 	if err := cm.fs.Write(ctx, post); err != nil {
-		return nil, fmt.Errorf("error writing to filesystem: %w", err)
+		return nil, cm.commit(id, fmt.Errorf("error writing to filesystem: %w", err))
 	}
 
 	// Add to store
+	cm.index(post)
 	newPost, err := cm.store.Create(ctx, post)
 	if err != nil {
 		// Rollback: delete from filesystem if store add fails
 		if delErr := cm.fs.Delete(ctx, post.PostType, post.Slug); delErr != nil {
 			return nil, fmt.Errorf("failed to add to store and rollback failed: %v, %w", delErr, err)
 		}
-		return nil, fmt.Errorf("error adding to store: %w", err)
+		return nil, cm.commit(id, fmt.Errorf("error adding to store: %w", err))
 	}
 
-	return newPost, nil
+	return newPost, cm.commit(id, nil)
+}
+
+// uniqueSlug returns the slugified name, with a -2, -3, etc. suffix if a post of the type already has the slug in
+// the store, the filesystem or the trash, so the post can't take the place of one that is restored later.
+func (cm *DownCache) uniqueSlug(ctx context.Context, postType, name string) (string, error) {
+	base := cm.slugifier(postType).Slugify(name)
+	if base == "" {
+		return "", fmt.Errorf("%w: a post needs a slug or a name to make one from", ErrInvalidPostMeta)
+	}
+
+	trashed := make(map[string]bool)
+	if trash, ok := cm.fs.(TrashFS); ok {
+		posts, err := trash.ListTrash(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, post := range posts {
+			trashed[PostPathID(post.PostType, post.Slug)] = true
+		}
+	}
+
+	candidate := base
+	for n := 2; ; n++ {
+		if !cm.slugTaken(ctx, postType, candidate) && !trashed[PostPathID(postType, candidate)] {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
 }
 
+// slugTaken returns true if a post of the type has the slug in the store or the filesystem. Unlike get, a post that
+// is only in the filesystem isn't added to the store. A file that can't be read still takes the slug.
+func (cm *DownCache) slugTaken(ctx context.Context, postType, slug string) bool {
+	if _, err := cm.store.Get(ctx, postType, slug); err == nil {
+		return true
+	}
+	_, err := cm.fs.Read(ctx, postType, slug)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// Update writes the post to the filesystem and store, moving it if its type or slug changed, whatever the current
+// version of the post is. Use UpdateIfMatch to avoid overwriting changes made since the post was read.
 func (cm *DownCache) Update(ctx context.Context, oldType, oldSlug string, post *Post) error {
-	// If the type or slug has changed, move the file
-	if oldType != post.PostType || oldSlug != post.Slug {
+	return cm.UpdateIfMatch(ctx, oldType, oldSlug, "", post)
+}
+
+// UpdateIfMatch is like Update, but only updates the post if the current version of the file has the ifMatch ETag,
+// i.e. it hasn't changed since the caller read it. Otherwise, a *ConflictError (matching ErrConflict) is returned
+// with the current version. ifMatch may be an ETag, an HTTP If-Match header value or "*". If it is empty, the post
+// is always updated. On success, post.ETag is set to the new version's ETag.
+//
+// If the store can't be updated, the file is moved back and its previous contents are restored.
+func (cm *DownCache) UpdateIfMatch(ctx context.Context, oldType, oldSlug, ifMatch string, post *Post) error {
+	return cm.update(ctx, oldType, oldSlug, ifMatch, post, RevisionOpUpdate)
+}
+
+// update updates the post, recording op as the reason the previous version was replaced.
+func (cm *DownCache) update(ctx context.Context, oldType, oldSlug, ifMatch string, post *Post, op RevisionOp) error {
+	if err := cm.checkWritable(oldType, oldSlug); err != nil {
+		return err
+	}
+
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	if ifMatch != "" {
+		if err := cm.checkIfMatch(ctx, oldType, oldSlug, ifMatch); err != nil {
+			return err
+		}
+	}
+
+	id, err := cm.journal.begin(JournalOpUpdate, PostPathID(oldType, oldSlug), PostPathID(post.PostType, post.Slug))
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous version to restore if the update fails
+	previous, err := cm.fs.Read(ctx, oldType, oldSlug)
+	if err != nil {
+		previous = nil
+	}
+
+	// If the type or slug has changed, move the file, keeping the old URLs as aliases
+	moved := oldType != post.PostType || oldSlug != post.Slug
+	if moved {
+		if previous != nil {
+			post.Aliases = append(post.Aliases, previous.Aliases...)
+		}
+		post.Aliases = append(post.Aliases, "/"+PostPathID(oldType, oldSlug))
+		if stored, err := cm.store.Get(ctx, oldType, oldSlug); err == nil && stored.Permalink != "" {
+			post.Aliases = append(post.Aliases, stored.Permalink)
+		}
+		indexed := *post
+		cm.normalizeDates(&indexed)
+		post.Aliases = normalizeAliases(post.Aliases, cm.permalink(&indexed))
+
 		if err := cm.fs.Move(ctx, oldType, oldSlug, post.PostType, post.Slug); err != nil {
-			return fmt.Errorf("error moving file: %w", err)
+			return cm.commit(id, fmt.Errorf("error moving file: %w", err))
 		}
 	}
 
 	// Write to filesystem
 	if err := cm.fs.Write(ctx, post); err != nil {
-		return fmt.Errorf("error writing to filesystem: %w", err)
+		err = fmt.Errorf("error writing to filesystem: %w", err)
+		if moved {
+			if mvErr := cm.fs.Move(ctx, post.PostType, post.Slug, oldType, oldSlug); mvErr != nil {
+				return fmt.Errorf("failed to write file and rollback failed: %v, %w", mvErr, err)
+			}
+		}
+		return cm.commit(id, err)
 	}
 
 	// Update in store
+	cm.index(post)
 	if err := cm.store.Update(ctx, oldType, oldSlug, post); err != nil {
-		// Rollback: move file back or revert content if update fails
-		if oldType != post.PostType || oldSlug != post.Slug {
-			if mvErr := cm.fs.Move(ctx, post.PostType, post.Slug, oldType, oldSlug); mvErr != nil {
-				return fmt.Errorf("failed to update store and rollback failed: %v, %w", mvErr, err)
-			}
+		// Rollback: move the file back and restore its previous contents
+		if rbErr := cm.rollbackUpdate(ctx, oldType, oldSlug, previous, post); rbErr != nil {
+			return fmt.Errorf("failed to update store and rollback failed: %v, %w", rbErr, err)
 		}
-		return fmt.Errorf("error updating in store: %w", err)
+		return cm.commit(id, fmt.Errorf("error updating in store: %w", err))
 	}
 
-	return nil
+	// Only keep a revision of the previous version once it has been replaced
+	return cm.commit(id, cm.saveRevision(ctx, previous, op))
 }
 
+// rollbackUpdate moves an updated file back to its old type and slug, and restores its previous contents.
+func (cm *DownCache) rollbackUpdate(ctx context.Context, oldType, oldSlug string, previous, post *Post) error {
+	if oldType != post.PostType || oldSlug != post.Slug {
+		if err := cm.fs.Move(ctx, post.PostType, post.Slug, oldType, oldSlug); err != nil {
+			return err
+		}
+	}
+
+	if previous == nil {
+		return fmt.Errorf("previous version of %s is unknown", PostPathID(oldType, oldSlug))
+	}
+
+	return cm.fs.Write(ctx, previous)
+}
+
+// checkIfMatch returns a *ConflictError if the post's current file doesn't have the ifMatch ETag. The file is
+// checked rather than the store, as it may have been edited outside DownCache. If the store is behind the file,
+// it is brought up to date so the caller can fetch the current version.
+func (cm *DownCache) checkIfMatch(ctx context.Context, postType, slug, ifMatch string) error {
+	current, err := cm.fs.Read(ctx, postType, slug)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrNotFound, PostPathID(postType, slug), err)
+	}
+
+	if MatchETag(ifMatch, current.ETag) {
+		return nil
+	}
+
+	if stored, err := cm.store.Get(ctx, postType, slug); err == nil && stored.ETag != current.ETag {
+		cm.index(current)
+		if err := cm.store.Update(ctx, postType, slug, current); err != nil {
+			return fmt.Errorf("error refreshing post in store: %w", err)
+		}
+	}
+
+	return &ConflictError{IfMatch: ifMatch, Current: current}
+}
+
+// MatchETag returns true if the etag satisfies ifMatch, which may be a single ETag or an HTTP If-Match header value:
+// a comma separated list of quoted ETags, optionally weak (W/"..."), or "*" to match any ETag.
+func MatchETag(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		candidate = strings.Trim(strings.TrimPrefix(candidate, "W/"), `"`)
+		if candidate != "" && candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delete removes the post from the filesystem and store. If the filesystem supports a trash (see TrashFS), the post
+// is moved to the trash, from where it can be restored. If the store can't be updated, the file is restored.
 func (cm *DownCache) Delete(ctx context.Context, postType, slug string) error {
-	// Delete from filesystem
-	if err := cm.fs.Delete(ctx, postType, slug); err != nil {
-		return fmt.Errorf("error deleting from filesystem: %w", err)
+	if err := cm.checkWritable(postType, slug); err != nil {
+		return err
+	}
+
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	id, err := cm.journal.begin(JournalOpDelete, PostPathID(postType, slug))
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous version to restore if the delete fails
+	previous, err := cm.fs.Read(ctx, postType, slug)
+	if err != nil {
+		previous = nil
+	}
+
+	// Delete from filesystem, or move to the trash
+	trash, hasTrash := cm.fs.(TrashFS)
+	if hasTrash {
+		err = trash.Trash(ctx, postType, slug, time.Now().UTC())
+	} else {
+		err = cm.fs.Delete(ctx, postType, slug)
+	}
+	if err != nil {
+		return cm.commit(id, fmt.Errorf("error deleting from filesystem: %w", err))
 	}
 
 	// Delete from store
 	if err := cm.store.Delete(ctx, postType, slug); err != nil {
-		// Note: We don't rollback the filesystem delete here, as the file is considered the source of truth
-		return fmt.Errorf("error deleting from store: %w", err)
+		// Rollback: restore the file, so it isn't left in the store without one
+		if hasTrash {
+			if unErr := trash.Untrash(ctx, postType, slug); unErr != nil {
+				return fmt.Errorf("failed to delete from store and rollback failed: %v, %w", unErr, err)
+			}
+			return cm.commit(id, fmt.Errorf("error deleting from store: %w", err))
+		}
+		if previous == nil {
+			return fmt.Errorf("failed to delete from store and the file can't be restored: %w", err)
+		}
+		if wrErr := cm.fs.Write(ctx, previous); wrErr != nil {
+			return fmt.Errorf("failed to delete from store and rollback failed: %v, %w", wrErr, err)
+		}
+		return cm.commit(id, fmt.Errorf("error deleting from store: %w", err))
 	}
 
-	return nil
+	// Only keep a revision of the deleted version once it has been deleted
+	revErr := cm.saveRevision(ctx, previous, RevisionOpDelete)
+
+	// A layered filesystem, like OverlayMarkdownFS, can reveal a lower version of the post. If it can't be added,
+	// the journal entry is left for Recover.
+	if revealed, err := cm.fs.Read(ctx, postType, slug); err == nil {
+		cm.index(revealed)
+		if _, err := cm.store.Create(ctx, revealed); err != nil {
+			return errors.Join(revErr, fmt.Errorf("error adding revealed post to store: %w", err))
+		}
+	}
+
+	return cm.commit(id, revErr)
 }
 
+// commit marks a journal entry as finished, as the filesystem and store agree again, and returns err.
+// Entries left unfinished because a rollback failed are repaired by Recover.
+func (cm *DownCache) commit(id int64, err error) error {
+	if jErr := cm.journal.commit(id); jErr != nil {
+		return errors.Join(err, fmt.Errorf("error updating journal: %w", jErr))
+	}
+	return err
+}
+
+// Get returns a post if the viewer in the context can see it (see WithViewer), or the context has a valid preview
+// token for it (see WithPreviewToken). Anonymous viewers can only fetch published, public or unlisted posts that
+// are live. ErrNotFound is returned for posts the viewer can't see, so their existence isn't revealed.
 func (cm *DownCache) Get(ctx context.Context, postType, slug string) (*Post, error) {
+	post, err := cm.get(ctx, postType, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ViewerFromContext(ctx).CanView(post, time.Now().UTC()) && !cm.previewAllowed(ctx, postType, slug) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, PostPathID(postType, slug))
+	}
+
+	return post, nil
+}
+
+// get returns a post from the store, falling back to the filesystem, regardless of the viewer.
+func (cm *DownCache) get(ctx context.Context, postType, slug string) (*Post, error) {
 	// Try to get from store first (it's faster)
 	post, err := cm.store.Get(ctx, postType, slug)
 	if err == nil {
@@ -107,14 +454,16 @@ func (cm *DownCache) Get(ctx context.Context, postType, slug string) (*Post, err
 	// If not in store, try to get from filesystem
 	post, err = cm.fs.Read(ctx, postType, slug)
 	if err != nil {
-		return nil, fmt.Errorf("post not found in store or filesystem: %w", err)
+		return nil, fmt.Errorf("%w: post not found in store or filesystem: %w", ErrNotFound, err)
 	}
 
 	// Add to store for future fast retrieval
+	cm.index(post)
 	newPost, err := cm.store.Create(ctx, post)
 	if err != nil {
 		// Log the error but don't fail the operation
 		fmt.Printf("Failed to add post to store after filesystem retrieval: %v\n", err)
+		return post, nil
 	}
 
 	return newPost, nil
@@ -127,3 +476,34 @@ func (cm *DownCache) Get(ctx context.Context, postType, slug string) (*Post, err
 func (cm *DownCache) Search(ctx context.Context, filter FilterOptions) ([]*Post, int, error) {
 	return cm.store.Search(ctx, filter)
 }
+
+// searchAll returns every post of any type, status or visibility matching the filter options, paging through the
+// results with cursors.
+func (cm *DownCache) searchAll(ctx context.Context, filter FilterOptions) ([]*Post, error) {
+	filter.Viewer = Viewer{Role: ViewerAdmin}
+	filter.IncludeScheduled = true
+	return cm.searchPages(ctx, filter)
+}
+
+// searchPages returns the posts matching the filter from every page of results.
+func (cm *DownCache) searchPages(ctx context.Context, filter FilterOptions) ([]*Post, error) {
+	if filter.FilterPostType == "" {
+		filter.FilterPostType = PostTypeKeyAny
+	}
+	filter.PageSize = searchAllPageSize
+
+	var posts []*Post
+	for {
+		page, total, err := cm.Search(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, page...)
+
+		paginator := NewCursorPaginator(page, total, filter)
+		if !paginator.HasNext {
+			return posts, nil
+		}
+		filter.After = paginator.NextCursor
+	}
+}
@@ -2,8 +2,12 @@ package downcache_test
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,19 +43,25 @@ func (fs *InMemoryFileSystem) Read(_ context.Context, postType, slug string) (*d
 	key := fmt.Sprintf("%s:%s", postType, slug)
 	post, ok := fs.files[key]
 	if !ok {
-		return nil, fmt.Errorf("post not found")
+		return nil, fmt.Errorf("post not found: %w", os.ErrNotExist)
 	}
-	return post, nil
+	// Return a copy, like reading a file, so callers can't change the stored post
+	postCopy := *post
+	return &postCopy, nil
 }
 
 func (fs *InMemoryFileSystem) Write(_ context.Context, post *downcache.Post) error {
 	key := fmt.Sprintf("%s:%s", post.PostType, post.Slug)
-	fs.files[key] = post
+	postCopy := *post
+	fs.files[key] = &postCopy
 	return nil
 }
 
 func (fs *InMemoryFileSystem) Delete(_ context.Context, postType, slug string) error {
 	key := fmt.Sprintf("%s:%s", postType, slug)
+	if _, ok := fs.files[key]; !ok {
+		return fmt.Errorf("post not found: %w", os.ErrNotExist)
+	}
 	delete(fs.files, key)
 	return nil
 }
@@ -61,7 +71,7 @@ func (fs *InMemoryFileSystem) Move(_ context.Context, oldType, oldSlug, newType,
 	newKey := fmt.Sprintf("%s:%s", newType, newSlug)
 	post, ok := fs.files[oldKey]
 	if !ok {
-		return fmt.Errorf("post not found")
+		return fmt.Errorf("post not found: %w", os.ErrNotExist)
 	}
 	post.PostType = newType
 	post.Slug = newSlug
@@ -92,6 +102,85 @@ func TestCacheManager_SyncAll(t *testing.T) {
 	assert.Equal(t, "About Us", post.Name)
 }
 
+func TestCacheManager_SyncAllSlugCollisions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"articles/Hello World.md":     "---\nname: First\n---\n",
+		"articles/hello-world.md":     "---\nname: Second\n---\n",
+		"articles/guide/index.md":     "---\nname: Bundle\n---\n",
+		"articles/guide.md":           "---\nname: File\n---\n",
+		"articles/unique.md":          "---\nname: Unique\n---\n",
+		"pages/hello-world.md":        "---\nname: Other type\n---\n",
+		"articles/nested/Post One.md": "---\nname: Nested\n---\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML), store)
+
+	ctx := context.Background()
+	err := cm.SyncAll(ctx)
+	require.ErrorIs(t, err, downcache.ErrSlugCollision)
+	assert.ErrorContains(t, err, "articles/hello-world is the slug of articles/Hello World.md and articles/hello-world.md")
+	assert.ErrorContains(t, err, "articles/guide is the slug of articles/guide/index.md and articles/guide.md")
+
+	var collision *downcache.SlugCollisionError
+	require.ErrorAs(t, err, &collision)
+
+	// The first file found is synced, and so is everything else
+	post, err := store.Get(ctx, "articles", "hello-world")
+	require.NoError(t, err)
+	assert.Equal(t, "First", post.Name)
+	for _, slug := range []string{"unique", "nested/post-one"} {
+		_, err := store.Get(ctx, "articles", slug)
+		assert.NoError(t, err, slug)
+	}
+	_, err = store.Get(ctx, "pages", "hello-world")
+	assert.NoError(t, err)
+
+	// Without collisions, SyncAll succeeds
+	require.NoError(t, os.Remove(filepath.Join(dir, "articles", "hello-world.md")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "articles", "guide.md")))
+	require.NoError(t, cm.SyncAll(ctx))
+}
+
+func TestCacheManager_CreateUniqueSlug(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: "hello-world"})
+
+	var slugs []string
+	for range 3 {
+		post, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Name: "Hello, World!"})
+		require.NoError(t, err)
+		slugs = append(slugs, post.Slug)
+	}
+	assert.Equal(t, []string{"hello-world-2", "hello-world-3", "hello-world-4"}, slugs)
+
+	// Checking the slugs doesn't add the posts only in the filesystem to the store
+	_, err := store.Get(ctx, "articles", "hello-world")
+	assert.Error(t, err)
+
+	// Slugs are unique within a post type
+	post, err := cm.Create(ctx, &downcache.Post{PostType: "pages", Name: "Hello World"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello-world", post.Slug)
+
+	// Slugs that are given are kept
+	post, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "custom", Name: "Hello World"})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", post.Slug)
+
+	_, err = cm.Create(ctx, &downcache.Post{PostType: "articles"})
+	assert.ErrorIs(t, err, downcache.ErrInvalidPostMeta)
+}
+
 func TestCacheManager_CreateUpdateDelete(t *testing.T) {
 	fs := NewInMemoryFileSystem()
 	store := downcache.NewMemoryCacheStore()
@@ -134,6 +223,76 @@ func TestCacheManager_CreateUpdateDelete(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestCacheManager_UpdateIfMatch(t *testing.T) {
+	dir := t.TempDir()
+	fs := downcache.NewLocalMarkdownFS(dir, &downcache.DefaultMarkdownProcessor{}, downcache.FrontmatterYAML)
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	created, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "shared", Name: "Shared", Content: "First"})
+	require.NoError(t, err)
+	original := created.ETag
+	require.NotEmpty(t, original)
+
+	// The first editor saves their changes
+	first := &downcache.Post{PostType: "articles", Slug: "shared", Name: "Shared", Content: "Edited by the first editor"}
+	require.NoError(t, cm.UpdateIfMatch(ctx, "articles", "shared", original, first))
+	assert.NotEqual(t, original, first.ETag)
+
+	// The second editor read the same version, so their changes are rejected
+	second := &downcache.Post{PostType: "articles", Slug: "shared", Name: "Shared", Content: "Edited by the second editor"}
+	err = cm.UpdateIfMatch(ctx, "articles", "shared", original, second)
+	require.ErrorIs(t, err, downcache.ErrConflict)
+	var conflict *downcache.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, first.ETag, conflict.Current.ETag)
+	assert.Contains(t, conflict.Current.Content, "Edited by the first editor")
+
+	// Retrying with the current version as a quoted If-Match header succeeds
+	require.NoError(t, cm.UpdateIfMatch(ctx, "articles", "shared", `"`+conflict.Current.ETag+`"`, second))
+
+	// Changes made outside DownCache are detected, and the store is brought up to date
+	path := filepath.Join(dir, "articles", "shared.md")
+	require.NoError(t, os.WriteFile(path, []byte("---\nname: Shared\n---\n\nEdited on disk"), 0o644))
+	third := &downcache.Post{PostType: "articles", Slug: "shared", Name: "Shared", Content: "Edited by the third editor"}
+	err = cm.UpdateIfMatch(ctx, "articles", "shared", second.ETag, third)
+	require.ErrorAs(t, err, &conflict)
+	stored, err := store.Get(ctx, "articles", "shared")
+	require.NoError(t, err)
+	assert.Equal(t, conflict.Current.ETag, stored.ETag)
+
+	// "*" matches any version, and an empty ifMatch skips the check
+	require.NoError(t, cm.UpdateIfMatch(ctx, "articles", "shared", "*", third))
+	require.NoError(t, cm.Update(ctx, "articles", "shared", &downcache.Post{PostType: "articles", Slug: "shared", Name: "Shared"}))
+
+	// Missing posts can't match
+	err = cm.UpdateIfMatch(ctx, "articles", "missing", "*", &downcache.Post{PostType: "articles", Slug: "missing"})
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+}
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		etag    string
+		want    bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "def", false},
+		{`"abc"`, "abc", true},
+		{`W/"abc"`, "abc", true},
+		{`"def", "abc"`, "abc", true},
+		{"*", "abc", true},
+		{`""`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifMatch, func(t *testing.T) {
+			assert.Equal(t, tt.want, downcache.MatchETag(tt.ifMatch, tt.etag))
+		})
+	}
+}
+
 func TestCacheManager_Get(t *testing.T) {
 	fs := NewInMemoryFileSystem()
 	store := downcache.NewMemoryCacheStore()
@@ -170,7 +329,7 @@ func TestCacheManager_Search(t *testing.T) {
 		Slug:     "post1",
 		Name:     "Post 1",
 		Author:   "John",
-		Properties: map[string]string{
+		Properties: map[string]any{
 			"series": "foo",
 		},
 		Taxonomies: map[string][]string{
@@ -183,7 +342,7 @@ func TestCacheManager_Search(t *testing.T) {
 		Slug:     "post2",
 		Name:     "Post 2",
 		Author:   "Jane",
-		Properties: map[string]string{
+		Properties: map[string]any{
 			"series": "foo",
 		},
 		Taxonomies: map[string][]string{
@@ -233,3 +392,422 @@ func TestCacheManager_Search(t *testing.T) {
 		t.Error("expected posts to be non-empty")
 	}
 }
+
+func TestCacheManager_SearchHighlight(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+
+	_, _ = store.Create(ctx, &downcache.Post{
+		PostType: "articles",
+		Slug:     "gophers",
+		Name:     "Gophers",
+		Content:  "Gophers are small burrowing rodents. Some gophers are also mascots for programming languages.",
+	})
+	_, _ = store.Create(ctx, &downcache.Post{
+		PostType: "articles",
+		Slug:     "rodents",
+		Name:     "Rodents",
+		Content:  "There are many rodents, and the gopher is one of them.",
+	})
+
+	posts, total, err := cm.Search(ctx, downcache.FilterOptions{
+		FilterPostType: downcache.PostTypeKeyArticle,
+		FilterSearch:   "gopher",
+		Highlight:      true,
+		HighlightOptions: downcache.HighlightOptions{
+			Words: 4,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, posts, 2)
+
+	// The post with the match in the name has the higher score and is sorted first
+	assert.Equal(t, "gophers", posts[0].Slug)
+	assert.Greater(t, posts[0].Score, posts[1].Score)
+	assert.Equal(t, "<mark>Gopher</mark>s", posts[0].Highlights["name"])
+	assert.Equal(t, "<mark>Gopher</mark>s are small burrowing…", posts[0].Highlights["content"])
+	assert.Equal(t, "…and the <mark>gopher</mark> is…", posts[1].Highlights["content"])
+	assert.NotContains(t, posts[1].Highlights, "name")
+
+	// The stored posts are not modified by a highlighted search
+	stored, err := store.Get(ctx, "articles", "gophers")
+	require.NoError(t, err)
+	assert.Empty(t, stored.Highlights)
+	assert.Zero(t, stored.Score)
+}
+
+func TestCacheManager_SearchProperties(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	proc := downcache.DefaultMarkdownProcessor{}
+
+	recipes := map[string]string{
+		"pancakes": "---\nname: Pancakes\nproperties:\n  cook_time: 20\n  difficulty: easy\n  vegan: false\n  added: 2024-01-10\n  meals: [breakfast, brunch]\n---\nPancakes",
+		"risotto":  "---\nname: Risotto\nproperties:\n  cook_time: 45\n  difficulty: medium\n  vegan: true\n  added: 2024-03-01\n  meals: [dinner]\n---\nRisotto",
+		"souffle":  "---\nname: Souffle\nproperties:\n  cook_time: 25\n  difficulty: hard\n---\nSouffle",
+	}
+
+	for slug, content := range recipes {
+		post, err := proc.Process([]byte(content))
+		require.NoError(t, err)
+		post.PostType = "recipes"
+		post.Slug = slug
+		_, err = store.Create(ctx, post)
+		require.NoError(t, err)
+	}
+
+	// Frontmatter types are preserved
+	pancakes, err := store.Get(ctx, "recipes", "pancakes")
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, pancakes.Properties["cook_time"])
+	assert.Equal(t, false, pancakes.Properties["vegan"])
+	assert.Equal(t, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), pancakes.Properties["added"])
+	assert.Equal(t, []any{"breakfast", "brunch"}, pancakes.Properties["meals"])
+
+	cases := []struct {
+		name     string
+		filters  []downcache.KeyValueFilter
+		expected []string
+	}{
+		{"Number lt", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"}}, []string{"pancakes", "souffle"}},
+		{"Number gte", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpGte, Value: "25"}}, []string{"risotto", "souffle"}},
+		{"In", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}}}, []string{"pancakes", "risotto"}},
+		{"Bool eq", []downcache.KeyValueFilter{{Key: "vegan", Value: "true"}}, []string{"risotto"}},
+		{"Ne includes missing", []downcache.KeyValueFilter{{Key: "vegan", Op: downcache.FilterOpNe, Value: "true"}}, []string{"pancakes", "souffle"}},
+		{"Exists", []downcache.KeyValueFilter{{Key: "meals", Op: downcache.FilterOpExists}}, []string{"pancakes", "risotto"}},
+		{"Date gt", []downcache.KeyValueFilter{{Key: "added", Op: downcache.FilterOpGt, Value: "2024-02-01"}}, []string{"risotto"}},
+		{"List eq", []downcache.KeyValueFilter{{Key: "meals", Value: "brunch"}}, []string{"pancakes"}},
+		{"Prefix", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpPrefix, Value: "me"}}, []string{"risotto"}},
+		{"Combined", []downcache.KeyValueFilter{
+			{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"},
+			{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}},
+		}, []string{"pancakes"}},
+		{"Type mismatch", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpGt, Value: "abc"}}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, _, err := cm.Search(ctx, downcache.FilterOptions{
+				FilterPostType:   "recipes",
+				FilterProperties: tc.filters,
+			})
+			require.NoError(t, err)
+
+			var slugs []string
+			for _, post := range posts {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.Equal(t, tc.expected, slugs)
+		})
+	}
+}
+
+func TestCacheManager_SearchTaxonomyGroups(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+
+	posts := map[string][]string{
+		"go-intro":       {"go"},
+		"golang-tips":    {"golang", "tips"},
+		"go-series-1":    {"go", "archived-series"},
+		"rust-intro":     {"rust"},
+		"go-rust-compat": {"go", "rust"},
+	}
+	for slug, tags := range posts {
+		_, err := store.Create(ctx, &downcache.Post{
+			PostType:   "articles",
+			Slug:       slug,
+			Taxonomies: map[string][]string{"tags": tags},
+		})
+		require.NoError(t, err)
+	}
+
+	cases := []struct {
+		name     string
+		groups   []downcache.TaxonomyFilterGroup
+		expected []string
+	}{
+		{
+			name: "Any of",
+			groups: []downcache.TaxonomyFilterGroup{
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "go"}, {Key: "tags", Value: "golang"}}},
+			},
+			expected: []string{"go-intro", "go-rust-compat", "go-series-1", "golang-tips"},
+		},
+		{
+			name: "Any of, but not",
+			groups: []downcache.TaxonomyFilterGroup{
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "go"}, {Key: "tags", Value: "golang"}}},
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "archived-series"}}, Negate: true},
+			},
+			expected: []string{"go-intro", "go-rust-compat", "golang-tips"},
+		},
+		{
+			name: "All of across groups",
+			groups: []downcache.TaxonomyFilterGroup{
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "go"}}},
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "rust"}}},
+			},
+			expected: []string{"go-rust-compat"},
+		},
+		{
+			name: "Negated any of",
+			groups: []downcache.TaxonomyFilterGroup{
+				{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "go"}, {Key: "tags", Value: "golang"}}, Negate: true},
+			},
+			expected: []string{"rust-intro"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, _, err := cm.Search(ctx, downcache.FilterOptions{
+				FilterPostType:       downcache.PostTypeKeyArticle,
+				FilterTaxonomyGroups: tc.groups,
+			})
+			require.NoError(t, err)
+
+			var slugs []string
+			for _, post := range results {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.Equal(t, tc.expected, slugs)
+		})
+	}
+}
+
+func TestCacheManager_SearchCursor(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "notes", Slug: "welcome", Pinned: true})
+	for i := 1; i <= 5; i++ {
+		_, _ = store.Create(ctx, &downcache.Post{
+			PostType:  "notes",
+			Slug:      fmt.Sprintf("note-%d", i),
+			Published: sql.NullString{String: fmt.Sprintf("2024-01-0%dT10:00:00Z", i), Valid: true},
+		})
+	}
+
+	search := func(filter downcache.FilterOptions) ([]string, downcache.Paginator) {
+		t.Helper()
+		filter.FilterPostType = "notes"
+		filter.PageSize = 2
+		filter.SplitPinned = true
+		posts, total, err := cm.Search(ctx, filter)
+		require.NoError(t, err)
+
+		var slugs []string
+		for _, post := range posts {
+			slugs = append(slugs, post.Slug)
+		}
+		return slugs, downcache.NewCursorPaginator(posts, total, filter)
+	}
+
+	// Pinned posts are only prepended to the first page
+	slugs, page := search(downcache.FilterOptions{})
+	assert.Equal(t, []string{"welcome", "note-5", "note-4"}, slugs)
+	assert.True(t, page.HasNext)
+	assert.False(t, page.HasPrev)
+
+	// A new post doesn't shift the following pages
+	_, _ = store.Create(ctx, &downcache.Post{
+		PostType:  "notes",
+		Slug:      "note-6",
+		Published: sql.NullString{String: "2024-01-06T10:00:00Z", Valid: true},
+	})
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+	assert.True(t, page.HasNext)
+	assert.True(t, page.HasPrev)
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-1"}, slugs)
+	assert.False(t, page.HasNext)
+	assert.Empty(t, page.NextCursor)
+
+	slugs, page = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+
+	slugs, page = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-5", "note-4"}, slugs)
+
+	slugs, _ = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-6"}, slugs)
+
+	// Cursors are tied to the sort order
+	_, _, err := cm.Search(ctx, downcache.FilterOptions{After: page.NextCursor, SortBy: []string{"name"}})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+
+	_, _, err = cm.Search(ctx, downcache.FilterOptions{After: page.NextCursor, Before: page.NextCursor})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+}
+
+func TestCacheManager_SearchSchedule(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	date := func(d time.Duration) sql.NullString {
+		return sql.NullString{String: now.Add(d).Format(time.RFC3339), Valid: true}
+	}
+
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "undated"})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "live", Published: date(-time.Hour), Expires: date(time.Hour)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "scheduled", Published: date(24 * time.Hour)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "expired", Published: date(-48 * time.Hour), Expires: date(-24 * time.Hour)})
+
+	cases := []struct {
+		name     string
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"Now", downcache.FilterOptions{}, []string{"undated", "live"}},
+		{"As of tomorrow", downcache.FilterOptions{AsOf: now.Add(36 * time.Hour)}, []string{"undated", "scheduled"}},
+		{"As of two days ago", downcache.FilterOptions{AsOf: now.Add(-36 * time.Hour)}, []string{"undated", "expired"}},
+		{"Include scheduled", downcache.FilterOptions{IncludeScheduled: true}, []string{"undated", "live", "scheduled", "expired"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.filter.FilterPostType = downcache.PostTypeKeyAny
+			posts, _, err := cm.Search(ctx, tc.filter)
+			require.NoError(t, err)
+
+			var slugs []string
+			for _, post := range posts {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.ElementsMatch(t, tc.expected, slugs)
+		})
+	}
+}
+
+func TestScheduler_Check(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	day := func(d int) sql.NullString {
+		return sql.NullString{String: fmt.Sprintf("2024-03-%02dT09:00:00Z", d), Valid: true}
+	}
+
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "goes-live", Status: "published", Published: day(2)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "expires", Status: "published", Published: day(1), Expires: day(3)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "never-live", Status: "published", Published: day(5), Expires: day(4)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "draft", Status: "draft", Published: day(2)})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "later", Status: "published", Published: day(20)})
+
+	scheduler := cm.Scheduler(time.Minute)
+	events, err := scheduler.Check(ctx, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	var got []string
+	for _, event := range events {
+		got = append(got, fmt.Sprintf("%s %s %s", event.At.Format("2006-01-02"), event.Type, event.Post.Slug))
+	}
+
+	assert.Equal(t, []string{
+		"2024-03-02 live goes-live",
+		"2024-03-03 expired expires",
+	}, got)
+}
+
+func TestCacheManager_Viewer(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	tomorrow := sql.NullString{String: time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339), Valid: true}
+
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "public", Author: "jane", Status: "published", Visibility: "public"})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "unlisted", Author: "jane", Status: "published", Visibility: "unlisted"})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "jane-draft", Author: "jane", Status: "draft", Visibility: "public"})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "bob-private", Author: "bob", Status: "published", Visibility: "private"})
+	_, _ = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "jane-scheduled", Author: "jane", Status: "published", Visibility: "public", Published: tomorrow})
+
+	jane := downcache.Viewer{Role: downcache.ViewerAuthor, Author: "jane"}
+	admin := downcache.Viewer{Role: downcache.ViewerAdmin}
+
+	t.Run("Search", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			ctx      context.Context
+			filter   downcache.FilterOptions
+			expected []string
+		}{
+			{"Anonymous by default", ctx, downcache.FilterOptions{}, []string{"public"}},
+			{"Anonymous draft filter", ctx, downcache.FilterOptions{FilterStatus: "draft"}, nil},
+			{"Author", ctx, downcache.FilterOptions{Viewer: jane}, []string{"public", "unlisted", "jane-draft"}},
+			{"Admin from context", downcache.WithViewer(ctx, admin), downcache.FilterOptions{}, []string{"public", "unlisted", "jane-draft", "bob-private"}},
+			{"Admin drafts", ctx, downcache.FilterOptions{Viewer: admin, FilterStatus: "draft"}, []string{"jane-draft"}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.filter.FilterPostType = downcache.PostTypeKeyAny
+				posts, _, err := cm.Search(tc.ctx, tc.filter)
+				require.NoError(t, err)
+
+				var slugs []string
+				for _, post := range posts {
+					slugs = append(slugs, post.Slug)
+				}
+				assert.ElementsMatch(t, tc.expected, slugs)
+			})
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		cases := []struct {
+			slug      string
+			anonymous bool
+			author    bool
+		}{
+			{slug: "public", anonymous: true, author: true},
+			{slug: "unlisted", anonymous: true, author: true},
+			{slug: "jane-draft", anonymous: false, author: true},
+			{slug: "bob-private", anonymous: false, author: false},
+			{slug: "jane-scheduled", anonymous: false, author: true},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.slug, func(t *testing.T) {
+				_, err := cm.Get(ctx, "articles", tc.slug)
+				if tc.anonymous {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, downcache.ErrNotFound)
+				}
+
+				_, err = cm.Get(downcache.WithViewer(ctx, jane), "articles", tc.slug)
+				if tc.author {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, downcache.ErrNotFound)
+				}
+
+				_, err = cm.Get(downcache.WithViewer(ctx, admin), "articles", tc.slug)
+				assert.NoError(t, err)
+			})
+		}
+	})
+}
@@ -1,5 +1,70 @@
 package downcache
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var ErrInvalidPostMeta = errors.New("invalid post metadata")
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var ErrInvalidPostPath = errors.New("invalid post path")
+
+var ErrNotFound = errors.New("post not found")
+
+var ErrInvalidPreviewToken = errors.New("invalid preview token")
+
+var ErrPreviewDisabled = errors.New("preview tokens are disabled")
+
+var ErrRevisionsDisabled = errors.New("revisions are disabled")
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+var ErrPostExists = errors.New("post already exists")
+
+var ErrHasChildren = errors.New("section has child posts")
+
+var ErrTrashDisabled = errors.New("trash is not supported by the filesystem")
+
+var ErrNotInTrash = errors.New("post is not in the trash")
+
+var ErrReadOnly = errors.New("read-only")
+
+var ErrConflict = errors.New("post has changed")
+
+var ErrSlugCollision = errors.New("slug collision")
+
+// ConflictError is returned when an update's IfMatch ETag doesn't match the current version of the post.
+// It matches ErrConflict with errors.Is.
+type ConflictError struct {
+	IfMatch string // The ETag the update expected
+	Current *Post  // The current version of the post, whose ETag can be used to retry the update
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s: expected etag %q, current etag %q",
+		ErrConflict, PostPathID(e.Current.PostType, e.Current.Slug), e.IfMatch, e.Current.ETag)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// SlugCollisionError is returned by SyncAll when more than one file has the same post type and slug, such as
+// articles/Hello World.md and articles/hello-world.md. It matches ErrSlugCollision with errors.Is.
+type SlugCollisionError struct {
+	PostID string   // The post type and slug of the files (see PostPathID)
+	Paths  []string // The paths of the files, in the order they were found. The first is the one that was synced.
+}
+
+func (e *SlugCollisionError) Error() string {
+	return fmt.Sprintf("%s: %s is the slug of %s", ErrSlugCollision, e.PostID, strings.Join(e.Paths, " and "))
+}
+
+func (e *SlugCollisionError) Unwrap() error {
+	return ErrSlugCollision
+}
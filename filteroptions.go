@@ -1,5 +1,11 @@
 package downcache
 
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
 type FilterType string
 
 const (
@@ -12,23 +18,136 @@ func (ft FilterType) String() string {
 	return string(ft)
 }
 
+// KeyValueFilter filters posts by a property or taxonomy key. Property filters support every FilterOp, comparing
+// Value against the property using the property's type (see MatchProperty).
 type KeyValueFilter struct {
-	Key   string
-	Value string
+	Key    string
+	Value  string
+	Op     FilterOp // The comparison operator. Default is FilterOpEq.
+	Values []string // The values to compare against for FilterOpIn
+}
+
+// TaxonomyFilterGroup matches posts that have any of its taxonomy terms. A negated group matches posts that
+// have none of its terms. Every group in FilterOptions must match, so groups are combined with AND.
+type TaxonomyFilterGroup struct {
+	Terms  []KeyValueFilter // The taxonomy terms, where Key is the taxonomy (e.g. tags) and Value is the term
+	Negate bool             // Whether posts with any of the terms are excluded
 }
 
 // FilterOptions contains the options to filter posts.
 type FilterOptions struct {
-	PageNum            int              // The page number to retrieve
-	PageSize           int              // The number of items per page
-	SortBy             []string         // The frontmatter fields to sort by. Default is ["-featured", "-published", "name]
-	FilterAuthor       string           // The authors to filter by
-	FilterProperties   []KeyValueFilter // The frontmatter fields to filter by
-	FilterTaxonomies   []KeyValueFilter // The taxonomies to filter by
-	FilterSearch       string           // A search string to filter by. Searches the post content, title, etc.
-	FilterPostType     PostType         // The type of post to filter by (e.g. PostTypeKeyArticle, PostTypeKeyPage). Default is PostTypeKeyAny.
-	FilterStatus       string           // The status of the post to filter by (e.g. "published", "draft"). Default is "published".
-	FilterVisibility   string           // The visibility of the post to filter by (e.g. "public", "private"). Default is "public".
-	SplitPinned        bool             // Whether to split featured items from the main list
-	IncludeUnpublished bool
+	PageNum                 int                   // The page number to retrieve
+	PageSize                int                   // The number of items per page
+	After                   string                // An opaque cursor (see Paginator.NextCursor). Only posts after it are returned and PageNum is ignored.
+	Before                  string                // An opaque cursor (see Paginator.PrevCursor). Only posts before it are returned and PageNum is ignored.
+	SortBy                  []string              // The fields to sort by: pinned, published, name or score. Default is DefaultSortBy, or ["-score"] when highlighting.
+	FilterAuthor            string                // The authors to filter by
+	FilterProperties        []KeyValueFilter      // The frontmatter fields to filter by
+	FilterTaxonomies        []KeyValueFilter      // The taxonomies to filter by
+	FilterExcludeTaxonomies []KeyValueFilter      // The taxonomy terms a post must not have
+	FilterTaxonomyGroups    []TaxonomyFilterGroup // Grouped taxonomy filters, e.g. (tags:go OR tags:golang) AND NOT tags:draft
+	FilterSearch            string                // A search string to filter by. Searches the post content, title, etc.
+	FilterText              TextQuery             // A parsed full-text expression to filter by. Takes precedence over FilterSearch if set.
+	FilterPublishedAfter    time.Time             // Only include posts published on or after this time, if set
+	FilterPublishedBefore   time.Time             // Only include posts published before this time, if set
+	FilterExpiresAfter      time.Time             // Only include posts that expire on or after this time, if set
+	FilterExpiresBefore     time.Time             // Only include posts that expire before this time, if set
+	AsOf                    time.Time             // Only include posts that are live at this time: not scheduled for later and not expired. Default is now.
+	IncludeScheduled        bool                  // Whether to include scheduled and expired posts, ignoring AsOf
+	FilterPermalink         string                // The permalink of the post to filter by, if set
+	FilterAlias             string                // An alias of the post to filter by, if set
+	FilterPostType          PostType              // The type of post to filter by (e.g. PostTypeKeyArticle, PostTypeKeyPage). Default is PostTypeKeyAny.
+	FilterStatus            string                // The status of the post to filter by (e.g. "published", "draft"). Default is any status the viewer can see.
+	FilterVisibility        string                // The visibility of the post to filter by (e.g. "public", "private"). Default is any visibility the viewer can see.
+	Viewer                  Viewer                // The viewer to filter posts for. Default is the viewer in the context (see WithViewer), or AnonymousViewer.
+	SplitPinned             bool                  // Whether to split featured items from the main list
+	IncludeUnpublished      bool                  // Deprecated: use a Viewer with ViewerAdmin or ViewerAuthor instead. It is ignored.
+	Highlight               bool                  // Whether to return highlighted snippets and a relevance score for FilterSearch matches
+	HighlightOptions        HighlightOptions      // The markers and snippet length to use when Highlight is true
+}
+
+// TextQuery returns the full-text expression to filter by. FilterText is used if set, otherwise FilterSearch is
+// parsed with ParseTextQuery.
+func (fo FilterOptions) TextQuery() TextQuery {
+	if !fo.FilterText.IsEmpty() {
+		return fo.FilterText
+	}
+	return ParseTextQuery(fo.FilterSearch)
+}
+
+// Sort returns the fields to sort by. SortBy is used if set. Otherwise, highlighted searches are sorted by score and
+// everything else by DefaultSortBy.
+func (fo FilterOptions) Sort() []string {
+	if len(fo.SortBy) > 0 {
+		return fo.SortBy
+	}
+
+	if fo.Highlight && !fo.TextQuery().IsEmpty() {
+		return []string{"-score"}
+	}
+
+	return DefaultSortBy
+}
+
+// Cursor decodes the After or Before cursor. It returns false if neither is set, and whether the cursor is a
+// Before cursor. The cursor must have been created with the same sort fields as the options.
+func (fo FilterOptions) Cursor() (cursor Cursor, ok bool, before bool, err error) {
+	token := fo.After
+	if fo.Before != "" {
+		if fo.After != "" {
+			return Cursor{}, false, false, fmt.Errorf("%w: After and Before can't both be set", ErrInvalidCursor)
+		}
+		token = fo.Before
+		before = true
+	}
+
+	if token == "" {
+		return Cursor{}, false, false, nil
+	}
+
+	cursor, err = DecodeCursor(token)
+	if err != nil {
+		return Cursor{}, false, false, err
+	}
+
+	if !slices.Equal(cursor.SortBy, fo.Sort()) {
+		return Cursor{}, false, false, fmt.Errorf("%w: the cursor was created with a different sort order", ErrInvalidCursor)
+	}
+
+	return cursor, true, before, nil
+}
+
+// LiveAsOf returns the time posts must be live at, which is AsOf or the current time. It returns false if
+// IncludeScheduled is set, in which case scheduled and expired posts aren't filtered.
+func (fo FilterOptions) LiveAsOf() (time.Time, bool) {
+	if fo.IncludeScheduled {
+		return time.Time{}, false
+	}
+
+	if fo.AsOf.IsZero() {
+		return time.Now().UTC(), true
+	}
+
+	return fo.AsOf.UTC(), true
+}
+
+// TaxonomyGroups returns all the taxonomy filters as groups. Each FilterTaxonomies term becomes a group that must
+// match, each FilterExcludeTaxonomies term becomes a negated group, followed by FilterTaxonomyGroups.
+func (fo FilterOptions) TaxonomyGroups() []TaxonomyFilterGroup {
+	groups := make([]TaxonomyFilterGroup, 0, len(fo.FilterTaxonomies)+len(fo.FilterExcludeTaxonomies)+len(fo.FilterTaxonomyGroups))
+	for _, tax := range fo.FilterTaxonomies {
+		groups = append(groups, TaxonomyFilterGroup{Terms: []KeyValueFilter{tax}})
+	}
+
+	for _, tax := range fo.FilterExcludeTaxonomies {
+		groups = append(groups, TaxonomyFilterGroup{Terms: []KeyValueFilter{tax}, Negate: true})
+	}
+
+	for _, group := range fo.FilterTaxonomyGroups {
+		if len(group.Terms) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
 }
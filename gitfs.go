@@ -0,0 +1,416 @@
+package downcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// GitOp is the kind of change a GitMarkdownFS commits.
+type GitOp string
+
+const (
+	GitOpWrite   GitOp = "write"
+	GitOpMove    GitOp = "move"
+	GitOpDelete  GitOp = "delete"  // Deleted, or moved to the trash
+	GitOpRestore GitOp = "restore" // Restored from the trash
+	GitOpPurge   GitOp = "purge"   // Removed from the trash
+)
+
+// DefaultGitMessage is the default commit message template. See GitChange for the fields it can use.
+const DefaultGitMessage = `{{if eq .Op "move"}}Move {{.OldPostType}}/{{.OldSlug}} to {{.PostType}}/{{.Slug}}` +
+	`{{else if eq .Op "delete"}}Delete {{.PostType}}/{{.Slug}}` +
+	`{{else if eq .Op "restore"}}Restore {{.PostType}}/{{.Slug}}` +
+	`{{else if eq .Op "purge"}}Purge {{.PostType}}/{{.Slug}} from the trash` +
+	`{{else}}Update {{.PostType}}/{{.Slug}}{{end}}`
+
+// GitChange describes a change for the commit message template.
+type GitChange struct {
+	Op          GitOp
+	PostType    string
+	Slug        string
+	OldPostType string // The post's type before a move
+	OldSlug     string // The post's slug before a move
+	Name        string // The post's name, when it is written
+}
+
+// GitCommit is a commit that changed a post.
+type GitCommit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Message string // The first line of the commit message
+}
+
+// GitOption configures a GitMarkdownFS.
+type GitOption func(*GitMarkdownFS)
+
+// WithGitAuthor sets the author and committer of the commits. By default, the repository's git config is used.
+func WithGitAuthor(name, email string) GitOption {
+	return func(g *GitMarkdownFS) {
+		g.authorName = name
+		g.authorEmail = email
+	}
+}
+
+// WithGitMessage sets the text/template used for commit messages, which is executed with a GitChange.
+func WithGitMessage(tmpl string) GitOption {
+	return func(g *GitMarkdownFS) {
+		g.messageTmpl = tmpl
+	}
+}
+
+// WithGitDates makes Walk and Read set each post's Created and Updated dates from the first and last commits
+// that changed its file, rather than the file's modification time, which is reset by every checkout.
+// Files that haven't been committed still use their modification time.
+func WithGitDates() GitOption {
+	return func(g *GitMarkdownFS) {
+		g.gitDates = true
+	}
+}
+
+// WithGitWalkOptions configures the files that are posts, as NewLocalMarkdownFS's options do.
+func WithGitWalkOptions(opts ...WalkOption) GitOption {
+	return func(g *GitMarkdownFS) {
+		g.cfg = newWalkConfig(opts)
+	}
+}
+
+// GitMarkdownFS implements MarkdownFS for a directory in a git working tree. Every Write, Move and Delete commits
+// the files it changed, and nothing else.
+type GitMarkdownFS struct {
+	*LocalMarkdownFS
+	authorName  string
+	authorEmail string
+	messageTmpl string
+	message     *template.Template
+	gitDates    bool
+	mu          sync.Mutex // Serializes changes, as git can only update the index for one at a time
+}
+
+var _ MarkdownFS = (*GitMarkdownFS)(nil)
+
+// NewGitMarkdownFS returns a GitMarkdownFS for rootDir, which must be in a git working tree. It may be a
+// subdirectory of the repository.
+func NewGitMarkdownFS(rootDir string, proc MarkdownProcessor, format FrontmatterFormat, opts ...GitOption) (*GitMarkdownFS, error) {
+	g := &GitMarkdownFS{
+		LocalMarkdownFS: NewLocalMarkdownFS(rootDir, proc, format),
+		messageTmpl:     DefaultGitMessage,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	message, err := template.New("message").Parse(g.messageTmpl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing git message template: %w", err)
+	}
+	g.message = message
+
+	if _, err := g.git(context.Background(), "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%s is not in a git working tree: %w", rootDir, err)
+	}
+
+	return g, nil
+}
+
+func (g *GitMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error) {
+	if !g.gitDates {
+		return g.LocalMarkdownFS.Walk(ctx)
+	}
+
+	history, err := g.fileHistory(ctx)
+	if err != nil {
+		posts := make(chan *Post)
+		errs := make(chan error, 1)
+		errs <- err
+		close(posts)
+		close(errs)
+		return posts, errs
+	}
+
+	return g.walk(ctx, func(relPath string, info os.FileInfo) (string, string) {
+		if dates, ok := history[filepath.ToSlash(relPath)]; ok {
+			return FormatDate(dates[0]), FormatDate(dates[1])
+		}
+		return modTimeDates(relPath, info)
+	})
+}
+
+func (g *GitMarkdownFS) Read(ctx context.Context, postType, slug string) (*Post, error) {
+	if !g.gitDates {
+		return g.LocalMarkdownFS.Read(ctx, postType, slug)
+	}
+
+	commits, err := g.History(ctx, postType, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.read(postType, slug, func(relPath string, info os.FileInfo) (string, string) {
+		if len(commits) == 0 {
+			return modTimeDates(relPath, info)
+		}
+		return FormatDate(commits[len(commits)-1].Date), FormatDate(commits[0].Date)
+	})
+}
+
+func (g *GitMarkdownFS) Write(ctx context.Context, post *Post) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.LocalMarkdownFS.Write(ctx, post); err != nil {
+		return err
+	}
+
+	return g.commit(ctx, GitChange{Op: GitOpWrite, PostType: post.PostType, Slug: post.Slug, Name: post.Name},
+		g.changePath(post.PostType, post.Slug))
+}
+
+func (g *GitMarkdownFS) Move(ctx context.Context, oldType, oldSlug, newType, newSlug string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	oldPath := g.changePath(oldType, oldSlug)
+	if err := g.LocalMarkdownFS.Move(ctx, oldType, oldSlug, newType, newSlug); err != nil {
+		return err
+	}
+
+	change := GitChange{Op: GitOpMove, PostType: newType, Slug: newSlug, OldPostType: oldType, OldSlug: oldSlug}
+	return g.commit(ctx, change, oldPath, g.changePath(newType, newSlug))
+}
+
+func (g *GitMarkdownFS) Delete(ctx context.Context, postType, slug string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	path := g.changePath(postType, slug)
+	if err := g.LocalMarkdownFS.Delete(ctx, postType, slug); err != nil {
+		return err
+	}
+
+	return g.commit(ctx, GitChange{Op: GitOpDelete, PostType: postType, Slug: slug}, path)
+}
+
+func (g *GitMarkdownFS) Trash(ctx context.Context, postType, slug string, deleted time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	path := g.changePath(postType, slug)
+	if err := g.LocalMarkdownFS.Trash(ctx, postType, slug, deleted); err != nil {
+		return err
+	}
+
+	return g.commit(ctx, GitChange{Op: GitOpDelete, PostType: postType, Slug: slug},
+		append(g.trashPaths(postType, slug), path)...)
+}
+
+func (g *GitMarkdownFS) Untrash(ctx context.Context, postType, slug string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	trashPaths := g.trashPaths(postType, slug)
+	if err := g.LocalMarkdownFS.Untrash(ctx, postType, slug); err != nil {
+		return err
+	}
+
+	return g.commit(ctx, GitChange{Op: GitOpRestore, PostType: postType, Slug: slug},
+		append(trashPaths, g.changePath(postType, slug))...)
+}
+
+func (g *GitMarkdownFS) Purge(ctx context.Context, postType, slug string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	trashPaths := g.trashPaths(postType, slug)
+	if err := g.LocalMarkdownFS.Purge(ctx, postType, slug); err != nil {
+		return err
+	}
+
+	return g.commit(ctx, GitChange{Op: GitOpPurge, PostType: postType, Slug: slug}, trashPaths...)
+}
+
+// History returns the commits that changed a post's file, newest first, following it across renames.
+func (g *GitMarkdownFS) History(ctx context.Context, postType, slug string) ([]GitCommit, error) {
+	if !g.hasCommits(ctx) {
+		return nil, nil
+	}
+
+	out, err := g.git(ctx, "log", "--follow", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s", "--", g.relPath(postType, slug))
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []GitCommit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing commit date %q: %w", fields[3], err)
+		}
+
+		commits = append(commits, GitCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Message: fields[4],
+		})
+	}
+
+	return commits, nil
+}
+
+// commit stages the paths and commits them, if they changed.
+func (g *GitMarkdownFS) commit(ctx context.Context, change GitChange, paths ...string) error {
+	// Git rejects paths that neither exist nor are tracked, such as a file removed before it was ever committed
+	var known []string
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(g.rootDir, path)); err == nil {
+			known = append(known, path)
+		} else if _, err := g.git(ctx, "ls-files", "--error-unmatch", "--", path); err == nil {
+			known = append(known, path)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+	paths = known
+
+	args := append([]string{"add", "--all", "--"}, paths...)
+	if _, err := g.git(ctx, args...); err != nil {
+		return err
+	}
+
+	// Nothing to commit if the files were written with the same contents
+	args = append([]string{"diff", "--cached", "--quiet", "--"}, paths...)
+	if _, err := g.git(ctx, args...); err == nil {
+		return nil
+	}
+
+	var message strings.Builder
+	if err := g.message.Execute(&message, change); err != nil {
+		return fmt.Errorf("error executing git message template: %w", err)
+	}
+
+	args = []string{"commit", "--quiet", "--message", message.String()}
+	if g.authorName != "" {
+		args = append([]string{"-c", "user.name=" + g.authorName, "-c", "user.email=" + g.authorEmail}, args...)
+	}
+	args = append(append(args, "--"), paths...)
+
+	_, err := g.git(ctx, args...)
+	return err
+}
+
+// fileHistory returns the dates of the first and last commits for every file under the root directory, keyed
+// by their slash separated paths relative to the root.
+func (g *GitMarkdownFS) fileHistory(ctx context.Context) (map[string][2]time.Time, error) {
+	history := make(map[string][2]time.Time)
+	if !g.hasCommits(ctx) {
+		return history, nil
+	}
+
+	// Commits are listed newest first, each as a line with its date followed by the files it changed
+	out, err := g.git(ctx, "-c", "core.quotepath=off", "log", "--format=%x1e%aI", "--name-only", "--relative", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var date time.Time
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\x1e"):
+			date, err = time.Parse(time.RFC3339, line[1:])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing commit date %q: %w", line[1:], err)
+			}
+		case line != "":
+			dates, ok := history[line]
+			if !ok {
+				dates[1] = date
+			}
+			dates[0] = date
+			history[line] = dates
+		}
+	}
+
+	return history, nil
+}
+
+// hasCommits returns true if the repository has at least one commit.
+func (g *GitMarkdownFS) hasCommits(ctx context.Context) bool {
+	_, err := g.git(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+// relPath returns the path of a post's file relative to the root directory, which is the index file of a page
+// bundle if the post is one.
+func (g *GitMarkdownFS) relPath(postType, slug string) string {
+	path, _ := g.postPath(postType, slug)
+	return g.rel(path)
+}
+
+// changePath returns the path a change to a post touches relative to the root directory: its file, or its page
+// bundle's directory. A section's directory holds its child posts too, so only its index file is returned.
+func (g *GitMarkdownFS) changePath(postType, slug string) string {
+	path, bundle := g.postPath(postType, slug)
+	if bundle && !g.cfg.isSection(path) {
+		path = filepath.Dir(path)
+	}
+	return g.rel(path)
+}
+
+// trashPaths returns the paths of a post's metadata, and its file or page bundle, in the trash relative to the root.
+func (g *GitMarkdownFS) trashPaths(postType, slug string) []string {
+	paths := []string{g.rel(g.buildTrashBase(postType, slug) + ".json")}
+	if path, bundle := g.trashedPath(postType, slug); path != "" {
+		if bundle {
+			path = filepath.Dir(path)
+		}
+		paths = append(paths, g.rel(path))
+	}
+	return paths
+}
+
+// rel returns a path relative to the root directory, with forward slashes.
+func (g *GitMarkdownFS) rel(path string) string {
+	if rel, err := filepath.Rel(g.rootDir, path); err == nil {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// git runs a git command in the root directory and returns its output.
+func (g *GitMarkdownFS) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.rootDir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return string(out), nil
+}
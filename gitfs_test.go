@@ -0,0 +1,217 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+// newGitRepo creates a git repository with a content directory, and returns the content directory.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	runGit(t, repo, nil, "init", "--quiet")
+	runGit(t, repo, nil, "config", "user.name", "Repo User")
+	runGit(t, repo, nil, "config", "user.email", "repo@example.com")
+	runGit(t, repo, nil, "config", "commit.gpgsign", "false")
+
+	content := filepath.Join(repo, "content")
+	require.NoError(t, os.MkdirAll(content, 0o755))
+	return content
+}
+
+// runGit runs a git command in dir with the extra environment variables, and returns its output.
+func runGit(t *testing.T, dir string, env []string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitMarkdownFS_Commits(t *testing.T) {
+	dir := newGitRepo(t)
+	fsm, err := downcache.NewGitMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML,
+		downcache.WithGitAuthor("Editor", "editor@example.com"),
+		downcache.WithGitMessage(`{{.Op}} {{.PostType}}/{{.Slug}}{{with .Name}}: {{.}}{{end}}`))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, fsm.Write(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "Post", Content: "Hello"}))
+	assert.Equal(t, "write articles/post: Post|Editor <editor@example.com>", runGit(t, dir, nil, "log", "-1", "--format=%s|%an <%ae>"))
+
+	// Writing the same contents again doesn't commit
+	require.NoError(t, fsm.Write(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "Post", Content: "Hello"}))
+	assert.Equal(t, "1", runGit(t, dir, nil, "rev-list", "--count", "HEAD"))
+
+	// Only the post's files are committed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unrelated.txt"), []byte("Not mine"), 0o644))
+	require.NoError(t, fsm.Move(ctx, "articles", "post", "pages", "moved"))
+	assert.Equal(t, "move pages/moved", runGit(t, dir, nil, "log", "-1", "--format=%s"))
+	assert.Equal(t, "?? content/unrelated.txt", runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	history, err := fsm.History(ctx, "pages", "moved")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "move pages/moved", history[0].Message)
+	assert.Equal(t, "write articles/post: Post", history[1].Message)
+	assert.Equal(t, "Editor", history[1].Author)
+
+	require.NoError(t, fsm.Delete(ctx, "pages", "moved"))
+	assert.Equal(t, "delete pages/moved", runGit(t, dir, nil, "log", "-1", "--format=%s"))
+	assert.Equal(t, "?? content/unrelated.txt", runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+}
+
+func TestGitMarkdownFS_Dates(t *testing.T) {
+	dir := newGitRepo(t)
+	fsm, err := downcache.NewGitMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML, downcache.WithGitDates())
+	require.NoError(t, err)
+
+	// Commit a post in 2020, update it in 2021, then check it out again so its modification time is now
+	path := filepath.Join(dir, "articles", "post.md")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	for _, date := range []string{"2020-01-02T03:04:05Z", "2021-06-07T08:09:10Z"} {
+		require.NoError(t, os.WriteFile(path, []byte("---\nname: Post\n---\n\n"+date), 0o644))
+		runGit(t, dir, nil, "add", ".")
+		runGit(t, dir, []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "commit", "--quiet", "-m", date)
+	}
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now()))
+
+	// An uncommitted post keeps its modification time
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "articles", "draft.md"), []byte("---\nname: Draft\n---\n"), 0o644))
+
+	ctx := context.Background()
+	posts, errs := fsm.Walk(ctx)
+	walked := make(map[string]*downcache.Post)
+	for post := range posts {
+		walked[post.Slug] = post
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Contains(t, walked, "post")
+	assert.Equal(t, "2020-01-02T03:04:05Z", walked["post"].Created)
+	assert.Equal(t, "2021-06-07T08:09:10Z", walked["post"].Updated)
+	require.Contains(t, walked, "draft")
+	assert.True(t, strings.HasPrefix(walked["draft"].Updated, time.Now().UTC().Format("2006-01-02")), walked["draft"].Updated)
+
+	post, err := fsm.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, walked["post"].Created, post.Created)
+	assert.Equal(t, walked["post"].Updated, post.Updated)
+}
+
+func TestGitMarkdownFS_NotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	_, err := downcache.NewGitMarkdownFS(t.TempDir(), realProcessor, downcache.FrontmatterYAML)
+	assert.Error(t, err)
+}
+
+func TestGitMarkdownFS_Trash(t *testing.T) {
+	dir := newGitRepo(t)
+	fsm, err := downcache.NewGitMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	require.NoError(t, err)
+	cm := downcache.NewDownCache(fsm, downcache.NewMemoryCacheStore())
+
+	ctx := context.Background()
+	_, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "Post", Content: "Hello"})
+	require.NoError(t, err)
+
+	require.NoError(t, cm.Delete(ctx, "articles", "post"))
+	assert.Equal(t, "Delete articles/post", runGit(t, dir, nil, "log", "-1", "--format=%s"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	_, err = cm.Restore(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "Restore articles/post", runGit(t, dir, nil, "log", "-1", "--format=%s"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	require.NoError(t, cm.Delete(ctx, "articles", "post"))
+	_, err = cm.PurgeTrash(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Purge articles/post from the trash", runGit(t, dir, nil, "log", "-1", "--format=%s"))
+	assert.Empty(t, runGit(t, dir, nil, "ls-files"))
+}
+
+func TestGitMarkdownFS_Bundles(t *testing.T) {
+	dir := newGitRepo(t)
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "index.md"), []byte("---\nname: Trip\n---\n\nSee the photo"))
+	writeTestFile(t, filepath.Join(dir, "articles", "trip", "photo.png"), pngHeader)
+	runGit(t, dir, nil, "add", ".")
+	runGit(t, dir, nil, "commit", "--quiet", "-m", "Add trip")
+
+	fsm, err := downcache.NewGitMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	require.NoError(t, err)
+	cm := downcache.NewDownCache(fsm, downcache.NewMemoryCacheStore())
+
+	ctx := context.Background()
+	require.NoError(t, cm.SyncAll(ctx))
+
+	// Page bundles are moved, deleted, restored and purged with their assets
+	post, err := fsm.Read(ctx, "articles", "trip")
+	require.NoError(t, err)
+	post.Slug = "road-trip"
+	require.NoError(t, cm.Update(ctx, "articles", "trip", post))
+	assert.Equal(t, "articles/road-trip/index.md\narticles/road-trip/photo.png", runGit(t, dir, nil, "ls-files"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	require.NoError(t, cm.Delete(ctx, "articles", "road-trip"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	_, err = cm.Restore(ctx, "articles", "road-trip")
+	require.NoError(t, err)
+	assert.Equal(t, "articles/road-trip/index.md\narticles/road-trip/photo.png", runGit(t, dir, nil, "ls-files"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	require.NoError(t, cm.Delete(ctx, "articles", "road-trip"))
+	_, err = cm.PurgeTrash(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, runGit(t, dir, nil, "ls-files"))
+	assert.Empty(t, runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+}
+
+func TestGitMarkdownFS_Sections(t *testing.T) {
+	dir := newGitRepo(t)
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "index.md"), []byte("---\nname: Guides\n---\n"))
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "setup.md"), []byte("---\nname: Setup\n---\n"))
+	runGit(t, dir, nil, "add", ".")
+	runGit(t, dir, nil, "commit", "--quiet", "-m", "Add guides")
+
+	fsm, err := downcache.NewGitMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	require.NoError(t, err)
+
+	// Writing a section only commits its index file, not changes to its child posts
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "setup.md"), []byte("---\nname: Setup, edited\n---\n"))
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "install.md"), []byte("---\nname: Install\n---\n"))
+
+	ctx := context.Background()
+	post, err := fsm.Read(ctx, "docs", "guides")
+	require.NoError(t, err)
+	post.Name = "All guides"
+	require.NoError(t, fsm.Write(ctx, post))
+	assert.Equal(t, "content/docs/guides/index.md", runGit(t, dir, nil, "show", "--name-only", "--format=", "HEAD"))
+	assert.Equal(t, "M content/docs/guides/setup.md\n?? content/docs/guides/install.md",
+		runGit(t, dir, nil, "status", "--porcelain", "--untracked-files=all"))
+
+	assert.ErrorIs(t, fsm.Delete(ctx, "docs", "guides"), downcache.ErrHasChildren)
+	assert.Equal(t, "2", runGit(t, dir, nil, "rev-list", "--count", "HEAD"))
+}
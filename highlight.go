@@ -0,0 +1,164 @@
+package downcache
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultHighlightPre is the default marker inserted before a highlighted match.
+	DefaultHighlightPre = "<mark>"
+	// DefaultHighlightPost is the default marker inserted after a highlighted match.
+	DefaultHighlightPost = "</mark>"
+	// DefaultHighlightEllipsis is the default marker used when a snippet is truncated.
+	DefaultHighlightEllipsis = "…"
+	// DefaultSnippetWords is the default number of words in a snippet.
+	DefaultSnippetWords = 16
+)
+
+// HighlightOptions controls how search matches are highlighted in snippets.
+type HighlightOptions struct {
+	Pre      string // The marker inserted before a match. Default is DefaultHighlightPre.
+	Post     string // The marker inserted after a match. Default is DefaultHighlightPost.
+	Ellipsis string // The marker used when a snippet is truncated. Default is DefaultHighlightEllipsis.
+	Words    int    // The approximate number of words in a snippet. Default is DefaultSnippetWords.
+}
+
+// WithDefaults returns a copy of the options with any empty values set to their defaults.
+func (ho HighlightOptions) WithDefaults() HighlightOptions {
+	if ho.Pre == "" {
+		ho.Pre = DefaultHighlightPre
+	}
+
+	if ho.Post == "" {
+		ho.Post = DefaultHighlightPost
+	}
+
+	if ho.Ellipsis == "" {
+		ho.Ellipsis = DefaultHighlightEllipsis
+	}
+
+	if ho.Words <= 0 {
+		ho.Words = DefaultSnippetWords
+	}
+
+	return ho
+}
+
+// Snippet returns a windowed excerpt of text centered around the first case-insensitive occurrence of term,
+// with every occurrence of term inside the excerpt wrapped in the highlight markers.
+// It also returns the number of occurrences of term in the full text. If term is not found, it returns an empty
+// string and zero.
+func Snippet(text, term string, opts HighlightOptions) (string, int) {
+	opts = opts.WithDefaults()
+	term = strings.TrimSpace(term)
+	if text == "" || term == "" {
+		return "", 0
+	}
+
+	lowerText := strings.ToLower(text)
+	lowerTerm := strings.ToLower(term)
+
+	// strings.ToLower can change the byte length of some runes, in which case the
+	// byte offsets can't be mapped back onto the original text safely.
+	if len(lowerText) != len(text) {
+		lowerText = text
+		lowerTerm = term
+	}
+
+	count := strings.Count(lowerText, lowerTerm)
+	if count == 0 {
+		return "", 0
+	}
+
+	first := strings.Index(lowerText, lowerTerm)
+	last := first + len(lowerTerm)
+
+	// Build a window of words around the first match
+	spans := wordSpans(text)
+	matchWord := 0
+	for i, span := range spans {
+		if span[1] > first {
+			matchWord = i
+			break
+		}
+	}
+
+	from := max(0, matchWord-opts.Words/2)
+	to := min(len(spans), from+opts.Words)
+	start := spans[from][0]
+	end := max(spans[to-1][1], last)
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString(opts.Ellipsis)
+	}
+	sb.WriteString(highlightRange(text, lowerText, lowerTerm, start, end, opts))
+	if end < len(text) {
+		sb.WriteString(opts.Ellipsis)
+	}
+
+	return strings.Join(strings.Fields(sb.String()), " "), count
+}
+
+// Highlight wraps every case-insensitive occurrence of term in text with the highlight markers and
+// returns the full text.
+func Highlight(text, term string, opts HighlightOptions) string {
+	opts = opts.WithDefaults()
+	term = strings.TrimSpace(term)
+	if text == "" || term == "" {
+		return text
+	}
+
+	lowerText := strings.ToLower(text)
+	lowerTerm := strings.ToLower(term)
+	if len(lowerText) != len(text) {
+		lowerText = text
+		lowerTerm = term
+	}
+
+	return highlightRange(text, lowerText, lowerTerm, 0, len(text), opts)
+}
+
+// highlightRange wraps every occurrence of lowerTerm in text[start:end] with the highlight markers.
+func highlightRange(text, lowerText, lowerTerm string, start, end int, opts HighlightOptions) string {
+	var sb strings.Builder
+	pos := start
+	for pos < end {
+		idx := strings.Index(lowerText[pos:end], lowerTerm)
+		if idx == -1 {
+			break
+		}
+		matchStart := pos + idx
+		matchEnd := matchStart + len(lowerTerm)
+		sb.WriteString(text[pos:matchStart])
+		sb.WriteString(opts.Pre)
+		sb.WriteString(text[matchStart:matchEnd])
+		sb.WriteString(opts.Post)
+		pos = matchEnd
+	}
+	sb.WriteString(text[pos:end])
+	return sb.String()
+}
+
+// wordSpans returns the start and end byte offsets of each whitespace-separated word in text.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
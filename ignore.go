@@ -0,0 +1,137 @@
+package downcache
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files listing paths for Walk to skip, with the same syntax as .gitignore files.
+// The patterns in an ignore file apply to the paths in its directory and below. Later patterns override earlier
+// ones, and patterns in deeper directories override those in their parents.
+const IgnoreFileName = ".downcacheignore"
+
+// ignoreRule is a pattern from an ignore file.
+type ignoreRule struct {
+	base    string // The slash separated directory of the ignore file relative to the root, or "" for the root
+	pattern *regexp.Regexp
+	negate  bool // Whether the pattern re-includes paths, i.e. starts with "!"
+	dirOnly bool // Whether the pattern only matches directories, i.e. ends with "/"
+}
+
+// ignoreRules are the patterns from the ignore files found so far, in the order they apply.
+type ignoreRules []ignoreRule
+
+// ignored returns true if the slash separated path relative to the root is ignored.
+func (rules ignoreRules) ignored(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		target := relPath
+		if rule.base != "" {
+			var ok bool
+			if target, ok = strings.CutPrefix(relPath, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreFile returns the rules in an ignore file in the base directory.
+func parseIgnoreFile(base string, data []byte) ignoreRules {
+	var rules ignoreRules
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// Trailing spaces are ignored unless they are escaped
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// Patterns with a slash are relative to the ignore file's directory. Others match at any depth.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		// Invalid patterns, such as an empty character class, never match, as with git
+		var err error
+		if rule.pattern, err = compileIgnorePattern(line, anchored); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// compileIgnorePattern converts a gitignore pattern to a regular expression matching slash separated paths.
+func compileIgnorePattern(pattern string, anchored bool) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			re.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			i++
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
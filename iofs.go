@@ -0,0 +1,205 @@
+package downcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// IOFSMarkdownFS implements a read-only MarkdownFS on an io/fs.FS, such as an embed.FS, fstest.MapFS or zip.Reader.
+// Write, Move and Delete return ErrReadOnly, and a DownCache using it is read-only.
+type IOFSMarkdownFS struct {
+	fsys fs.FS
+	proc MarkdownProcessor
+	cfg  walkConfig // Decides the files that are posts
+}
+
+var _ MarkdownFS = (*IOFSMarkdownFS)(nil)
+
+// NewIOFSMarkdownFS returns an IOFSMarkdownFS for the file system, whose top-level directories are the post types.
+// Use fs.Sub to use a subdirectory, for example of an embed.FS. Walk skips the same files as LocalMarkdownFS's.
+func NewIOFSMarkdownFS(fsys fs.FS, proc MarkdownProcessor, opts ...WalkOption) *IOFSMarkdownFS {
+	return &IOFSMarkdownFS{fsys: fsys, proc: proc, cfg: newWalkConfig(opts)}
+}
+
+// ReadOnly returns true, as an io/fs.FS can't be written to.
+func (ifs *IOFSMarkdownFS) ReadOnly() bool {
+	return true
+}
+
+// Slugifier returns the Slugifier of the post type, set with WithSlugifier.
+func (ifs *IOFSMarkdownFS) Slugifier(postType string) Slugifier {
+	return ifs.cfg.slugifierFor(postType)
+}
+
+func (ifs *IOFSMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error) {
+	posts := make(chan *Post)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(posts)
+		defer close(errs)
+
+		var ignore ignoreRules
+		err := fs.WalkDir(ifs.fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if filePath != "." && (hiddenOrTemp(d.Name()) || ignore.ignored(filePath, d.IsDir())) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
+			if d.IsDir() {
+				data, err := fs.ReadFile(ifs.fsys, path.Join(filePath, IgnoreFileName))
+				if err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+				base := filePath
+				if base == "." {
+					base = ""
+				}
+				ignore = append(ignore, parseIgnoreFile(base, data)...)
+				return nil
+			}
+			if !ifs.cfg.isPost(filePath) {
+				return nil
+			}
+
+			parts := strings.Split(filePath, "/")
+			postType := parts[0]
+			slug := ifs.cfg.slugifyPath("", filePath, postType)
+			if len(parts) < 2 {
+				var ok bool
+				if postType, ok, err = ifs.cfg.rootType(filePath); !ok {
+					return err
+				}
+				slug = ifs.cfg.slugifyPath("", filePath, "")
+			}
+
+			post, err := ifs.readFile(filePath)
+			if err != nil {
+				return err
+			}
+			post.PostType = postType
+			post.Slug = ifs.cfg.slug(postType, slug)
+			post.sourcePath = filePath
+			setFileDate(post, slug)
+
+			select {
+			case posts <- post:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			return nil
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return posts, errs
+}
+
+func (ifs *IOFSMarkdownFS) Read(_ context.Context, postType, slug string) (*Post, error) {
+	filePath := ifs.findSlug(postType, path.Join(postType, slug))
+	if filePath == "" && postType == ifs.cfg.rootPostType {
+		filePath = ifs.findSlug(postType, slug)
+	}
+	if filePath == "" {
+		return nil, &fs.PathError{Op: "open", Path: path.Join(postType, slug+ifs.cfg.extensions[0]), Err: fs.ErrNotExist}
+	}
+
+	post, err := ifs.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	post.PostType = postType
+	post.Slug = slug
+	setFileDate(post, SlugifyPath("", filePath, ""))
+
+	return post, nil
+}
+
+func (ifs *IOFSMarkdownFS) Write(_ context.Context, post *Post) error {
+	return fmt.Errorf("%w: can't write %s", ErrReadOnly, PostPathID(post.PostType, post.Slug))
+}
+
+func (ifs *IOFSMarkdownFS) Delete(_ context.Context, postType, slug string) error {
+	return fmt.Errorf("%w: can't delete %s", ErrReadOnly, PostPathID(postType, slug))
+}
+
+func (ifs *IOFSMarkdownFS) Move(_ context.Context, oldType, oldSlug, _, _ string) error {
+	return fmt.Errorf("%w: can't move %s", ErrReadOnly, PostPathID(oldType, oldSlug))
+}
+
+// readFile reads and processes a markdown file. Its created and updated dates are its modification time, if the
+// file system has one. Files in an embed.FS don't.
+func (ifs *IOFSMarkdownFS) readFile(filePath string) (*Post, error) {
+	content, err := fs.ReadFile(ifs.fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := ifs.proc.Process(content)
+	if err != nil {
+		return nil, fmt.Errorf("error processing markdown file %s: %w", filePath, err)
+	}
+
+	info, err := fs.Stat(ifs.fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.ModTime().IsZero() {
+		setDates(post, FormatDate(info.ModTime()), FormatDate(info.ModTime()))
+	}
+
+	return post, nil
+}
+
+// findFile returns the path of the file with the path without an extension and one of the extensions of posts, or
+// "" if there isn't one.
+func (ifs *IOFSMarkdownFS) findFile(base string) string {
+	for _, ext := range ifs.cfg.extensions {
+		if info, err := fs.Stat(ifs.fsys, base+ext); err == nil && info.Mode().IsRegular() {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// findSlug returns the path of the file of a post of the post type, given its path without an extension, as
+// findFile does. If the post type's slugs don't include the dates in their file names, a file with a date is found
+// too, and the earliest is returned if there are several.
+func (ifs *IOFSMarkdownFS) findSlug(postType, base string) string {
+	if filePath := ifs.findFile(base); filePath != "" || !ifs.cfg.stripsDate(postType) {
+		return filePath
+	}
+
+	dir, name := path.Split(base)
+	entries, err := fs.ReadDir(ifs.fsys, path.Clean(dir))
+	if err != nil {
+		return ""
+	}
+
+	for _, entry := range entries {
+		prefix := fileDatePrefix(entry.Name())
+		if prefix == "" || !strings.HasPrefix(entry.Name()[len(prefix):], name) {
+			continue
+		}
+		if filePath := ifs.findFile(path.Join(dir, prefix+name)); filePath != "" {
+			return filePath
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,139 @@
+package downcache_test
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+//go:embed testdata
+var embeddedTestData embed.FS
+
+func TestIOFSMarkdownFS_Embed(t *testing.T) {
+	content, err := fs.Sub(embeddedTestData, "testdata")
+	require.NoError(t, err)
+	fsm := downcache.NewIOFSMarkdownFS(content, realProcessor)
+
+	ctx := context.Background()
+	posts, errs := fsm.Walk(ctx)
+	var ids []string
+	for post := range posts {
+		ids = append(ids, downcache.PostPathID(post.PostType, post.Slug))
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"articles/nested/post3", "articles/post1", "articles/post2", "notes/quick-note", "pages/about"}, ids)
+
+	post, err := fsm.Read(ctx, "articles", "post1")
+	require.NoError(t, err)
+	assert.Equal(t, "First Blog Post", post.Name)
+	assert.Empty(t, post.Updated) // Embedded files have no modification time
+
+	_, err = fsm.Read(ctx, "articles", "missing")
+	assert.Error(t, err)
+}
+
+func TestIOFSMarkdownFS_ReadOnly(t *testing.T) {
+	fsm := downcache.NewIOFSMarkdownFS(fstest.MapFS{
+		"articles/post.md":       {Data: []byte("---\nname: Post\n---\n\nHello")},
+		".trash/articles/old.md": {Data: []byte("---\nname: Old\n---\n\nDeleted")},
+	}, realProcessor)
+
+	ctx := context.Background()
+	post := &downcache.Post{PostType: "articles", Slug: "new", Name: "New"}
+	assert.ErrorIs(t, fsm.Write(ctx, post), downcache.ErrReadOnly)
+	assert.ErrorIs(t, fsm.Move(ctx, "articles", "post", "pages", "post"), downcache.ErrReadOnly)
+	assert.ErrorIs(t, fsm.Delete(ctx, "articles", "post"), downcache.ErrReadOnly)
+
+	// A DownCache using it is read-only, but can still be synced and searched
+	cm := downcache.NewDownCache(fsm, downcache.NewMemoryCacheStore())
+	assert.True(t, cm.ReadOnly())
+	require.NoError(t, cm.SyncAll(ctx))
+
+	posts, total, err := cm.Search(ctx, downcache.FilterOptions{FilterPostType: downcache.PostTypeKeyAny})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "Post", posts[0].Name)
+
+	_, err = cm.Create(ctx, post)
+	assert.ErrorIs(t, err, downcache.ErrReadOnly)
+	assert.ErrorIs(t, cm.Update(ctx, "articles", "post", post), downcache.ErrReadOnly)
+	assert.ErrorIs(t, cm.Delete(ctx, "articles", "post"), downcache.ErrReadOnly)
+}
+
+func TestDownCache_WithReadOnly(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	cm := downcache.NewDownCache(fs, downcache.NewMemoryCacheStore(), downcache.WithReadOnly())
+	assert.True(t, cm.ReadOnly())
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post"})
+	assert.ErrorIs(t, err, downcache.ErrReadOnly)
+	_, err = fs.Read(ctx, "articles", "post")
+	assert.Error(t, err)
+}
+
+func TestIOFSMarkdownFS_WalkRules(t *testing.T) {
+	fsm := downcache.NewIOFSMarkdownFS(fstest.MapFS{
+		downcache.IgnoreFileName: {Data: []byte("README.md\n")},
+		"README.md":              {Data: []byte("Read me")},
+		"about.markdown":         {Data: []byte("---\nname: About\n---\n")},
+		"articles/post.markdown": {Data: []byte("---\nname: Post\n---\n")},
+		"articles/post.md~":      {Data: []byte("Backup")},
+		"articles/.draft.md":     {Data: []byte("Hidden")},
+	}, realProcessor, downcache.WithExtensions(".md", ".markdown"), downcache.WithRootPostType("pages"))
+
+	ctx := context.Background()
+	posts, errs := fsm.Walk(ctx)
+	var ids []string
+	for post := range posts {
+		ids = append(ids, downcache.PostPathID(post.PostType, post.Slug))
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	sort.Strings(ids)
+	assert.Equal(t, []string{"articles/post", "pages/about"}, ids)
+
+	post, err := fsm.Read(ctx, "pages", "about")
+	require.NoError(t, err)
+	assert.Equal(t, "About", post.Name)
+
+	_, err = fsm.Read(ctx, "articles", "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestIOFSMarkdownFS_FileDates(t *testing.T) {
+	fsm := downcache.NewIOFSMarkdownFS(fstest.MapFS{
+		"articles/2024-08-21-post.md": {Data: []byte("---\nname: Post\n---\n")},
+	}, realProcessor, downcache.StripSlugDates("articles"))
+
+	ctx := context.Background()
+	posts, errs := fsm.Walk(ctx)
+	var walked []*downcache.Post
+	for post := range posts {
+		walked = append(walked, post)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, walked, 1)
+	assert.Equal(t, "post", walked[0].Slug)
+	assert.Equal(t, "2024-08-21", walked[0].FileTimePath)
+	assert.Equal(t, "2024-08-21", walked[0].Published.String)
+
+	post, err := fsm.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "Post", post.Name)
+	assert.Equal(t, "2024-08-21", post.FileTimePath)
+}
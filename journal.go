@@ -0,0 +1,257 @@
+package downcache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalFileName is the name of the write-ahead journal in the data directory.
+const JournalFileName = "downcache.journal"
+
+// JournalOp is the kind of write recorded in the journal.
+type JournalOp string
+
+const (
+	JournalOpCreate JournalOp = "create"
+	JournalOpUpdate JournalOp = "update"
+	JournalOpDelete JournalOp = "delete"
+)
+
+// JournalEntry is a write to the filesystem and store that was started but not finished, for example because the
+// process crashed part way through.
+type JournalEntry struct {
+	ID    int64     `json:"id"`
+	Op    JournalOp `json:"op"`
+	Paths []string  `json:"paths,omitempty"` // The PostPathIDs of the posts the write touched
+	Done  bool      `json:"done,omitempty"`  // Set on the record written when the write finishes
+}
+
+// WithDataDir sets the directory DownCache keeps its own state in. If it is set, every Create, Update and Delete is
+// recorded in a write-ahead journal there, so writes interrupted by a crash can be repaired by Recover.
+func WithDataDir(dir string) Option {
+	return func(cm *DownCache) {
+		cm.journal = &journal{path: filepath.Join(dir, JournalFileName)}
+	}
+}
+
+// journal is an append-only log of JSON records. A write appends an entry before it starts and a done record with
+// the same ID when the filesystem and store agree again. When no writes are in progress, the file is truncated.
+// A nil journal records nothing.
+type journal struct {
+	mu      sync.Mutex
+	path    string
+	nextID  int64
+	loaded  bool
+	pending map[int64]bool // The IDs of entries without a done record, including any left by a previous process
+}
+
+// begin records the start of a write and returns its ID.
+func (j *journal) begin(op JournalOp, paths ...string) (int64, error) {
+	if j == nil {
+		return 0, nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.load(); err != nil {
+		return 0, err
+	}
+
+	j.nextID++
+	entry := JournalEntry{ID: j.nextID, Op: op, Paths: paths}
+	if err := j.append(entry); err != nil {
+		return 0, err
+	}
+
+	j.pending[entry.ID] = true
+	return entry.ID, nil
+}
+
+// commit records that a write finished, leaving the filesystem and store in agreement.
+func (j *journal) commit(id int64) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.append(JournalEntry{ID: id, Done: true}); err != nil {
+		return err
+	}
+
+	delete(j.pending, id)
+	if len(j.pending) == 0 {
+		return j.truncate()
+	}
+
+	return nil
+}
+
+// unfinished returns the entries without a done record, oldest first.
+func (j *journal) unfinished() ([]JournalEntry, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.read()
+}
+
+// reset empties the journal once every unfinished entry has been repaired.
+func (j *journal) reset() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.load(); err != nil {
+		return err
+	}
+
+	clear(j.pending)
+	return j.truncate()
+}
+
+// load reads the entries left unfinished by a previous process the first time the journal is used, so they are
+// kept until they are repaired.
+func (j *journal) load() error {
+	if j.loaded {
+		return nil
+	}
+
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	j.pending = make(map[int64]bool)
+	for _, entry := range entries {
+		j.pending[entry.ID] = true
+	}
+
+	// IDs only need to be unique within the file, which is truncated when nothing is pending
+	j.nextID = time.Now().UnixNano()
+	j.loaded = true
+	return nil
+}
+
+// read returns the entries in the file without a done record.
+func (j *journal) read() ([]JournalEntry, error) {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	done := make(map[int64]bool)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A record cut short by a crash. The write it started never began, or its entry is already recorded.
+			continue
+		}
+
+		if entry.Done {
+			done[entry.ID] = true
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading journal: %w", err)
+	}
+
+	unfinished := entries[:0]
+	for _, entry := range entries {
+		if !done[entry.ID] {
+			unfinished = append(unfinished, entry)
+		}
+	}
+
+	return unfinished, nil
+}
+
+// append writes a record to the end of the file and syncs it to disk.
+func (j *journal) append(entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding journal entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("error creating journal directory: %w", err)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing journal: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error syncing journal: %w", err)
+	}
+
+	return nil
+}
+
+// truncate empties the file.
+func (j *journal) truncate() error {
+	if err := os.Truncate(j.path, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error truncating journal: %w", err)
+	}
+	return nil
+}
+
+// Recover repairs the posts touched by writes that were interrupted, for example by a crash, using the journal in
+// the data directory (see WithDataDir). Each post is brought into line with the filesystem, which is the source of
+// truth: it is added to or updated in the store if its file exists, and removed from the store if it doesn't.
+// Call Recover at startup, before serving requests. It returns the entries that were repaired.
+func (cm *DownCache) Recover(ctx context.Context) ([]JournalEntry, error) {
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	entries, err := cm.journal.unfinished()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		for _, path := range entry.Paths {
+			postType, slug, _ := strings.Cut(path, "/")
+			if err := cm.repair(ctx, postType, slug); err != nil {
+				return nil, fmt.Errorf("error repairing %s after interrupted %s: %w", path, entry.Op, err)
+			}
+		}
+	}
+
+	if err := cm.journal.reset(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
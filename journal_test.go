@@ -0,0 +1,138 @@
+package downcache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+// failingStore is a MemoryCacheStore whose updates and deletes can be made to fail.
+type failingStore struct {
+	*downcache.MemoryCacheStore
+	failUpdate bool
+	failDelete bool
+}
+
+func (s *failingStore) Update(ctx context.Context, oldType, oldSlug string, post *downcache.Post) error {
+	if s.failUpdate {
+		return errors.New("update failed")
+	}
+	return s.MemoryCacheStore.Update(ctx, oldType, oldSlug, post)
+}
+
+func (s *failingStore) Delete(ctx context.Context, postType, slug string) error {
+	if s.failDelete {
+		return errors.New("delete failed")
+	}
+	return s.MemoryCacheStore.Delete(ctx, postType, slug)
+}
+
+func TestDownCache_Recover(t *testing.T) {
+	contentDir := t.TempDir()
+	dataDir := t.TempDir()
+	fs := downcache.NewLocalMarkdownFS(contentDir, &downcache.DefaultMarkdownProcessor{}, downcache.FrontmatterYAML)
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store, downcache.WithDataDir(dataDir))
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "deleted", Name: "Deleted"})
+	require.NoError(t, err)
+	_, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "finished", Name: "Finished"})
+	require.NoError(t, err)
+
+	// Finished writes leave nothing in the journal
+	journalPath := filepath.Join(dataDir, downcache.JournalFileName)
+	data, err := os.ReadFile(journalPath)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	// Simulate a crash after a new file was written, and another after a file was deleted, before the store was
+	// updated. The last line was cut short by the crash.
+	require.NoError(t, os.WriteFile(filepath.Join(contentDir, "articles", "created.md"), []byte("---\nname: Created\n---\n\nHello"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(contentDir, "articles", "deleted.md")))
+	journal := `{"id":1,"op":"create","paths":["articles/created"]}
+{"id":2,"op":"update","paths":["articles/finished","articles/finished"]}
+{"id":2,"done":true}
+{"id":3,"op":"delete","paths":["articles/deleted"]}
+{"id":4,"op":"upd`
+	require.NoError(t, os.WriteFile(journalPath, []byte(journal), 0o644))
+
+	// A new process recovers at startup
+	cm = downcache.NewDownCache(fs, store, downcache.WithDataDir(dataDir))
+	entries, err := cm.Recover(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, downcache.JournalOpCreate, entries[0].Op)
+	assert.Equal(t, downcache.JournalOpDelete, entries[1].Op)
+
+	created, err := store.Get(ctx, "articles", "created")
+	require.NoError(t, err)
+	assert.Equal(t, "Created", created.Name)
+	_, err = store.Get(ctx, "articles", "deleted")
+	assert.Error(t, err)
+
+	data, err = os.ReadFile(journalPath)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	// Recovering again has nothing to do
+	entries, err = cm.Recover(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDownCache_UpdateRollback(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := &failingStore{MemoryCacheStore: downcache.NewMemoryCacheStore()}
+	cm := downcache.NewDownCache(fs, store, downcache.WithDataDir(t.TempDir()))
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "Original", Content: "Original"})
+	require.NoError(t, err)
+
+	store.failUpdate = true
+	err = cm.Update(ctx, "articles", "post", &downcache.Post{PostType: "pages", Slug: "moved", Name: "Updated", Content: "Updated"})
+	require.Error(t, err)
+
+	// The file is moved back and its previous contents restored
+	post, err := fs.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "Original", post.Name)
+	assert.Equal(t, "Original", post.Content)
+	_, err = fs.Read(ctx, "pages", "moved")
+	assert.Error(t, err)
+
+	entries, err := cm.Recover(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDownCache_DeleteRollback(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := &failingStore{MemoryCacheStore: downcache.NewMemoryCacheStore()}
+	cm := downcache.NewDownCache(fs, store, downcache.WithDataDir(t.TempDir()))
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "Post"})
+	require.NoError(t, err)
+
+	store.failDelete = true
+	require.Error(t, cm.Delete(ctx, "articles", "post"))
+
+	// The file is restored, so the store isn't left with a post that has no file
+	post, err := fs.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "Post", post.Name)
+
+	store.failDelete = false
+	require.NoError(t, cm.Delete(ctx, "articles", "post"))
+	_, err = store.Get(ctx, "articles", "post")
+	assert.Error(t, err)
+}
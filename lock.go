@@ -0,0 +1,140 @@
+package downcache
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// pathLocks is a set of mutexes keyed by file path, so writes to different files can run concurrently.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*pathLock
+}
+
+// pathLock is a mutex for one path, with the number of goroutines holding or waiting for it.
+type pathLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks the paths, in sorted order so two goroutines locking the same paths can't deadlock, and returns a
+// function that unlocks them.
+func (pl *pathLocks) lock(paths ...string) func() {
+	paths = slices.Clone(paths)
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	pl.mu.Lock()
+	if pl.locks == nil {
+		pl.locks = make(map[string]*pathLock)
+	}
+	held := make([]*pathLock, 0, len(paths))
+	for _, path := range paths {
+		lock, ok := pl.locks[path]
+		if !ok {
+			lock = &pathLock{}
+			pl.locks[path] = lock
+		}
+		lock.refs++
+		held = append(held, lock)
+	}
+	pl.mu.Unlock()
+
+	for _, lock := range held {
+		lock.mu.Lock()
+	}
+
+	return func() {
+		pl.mu.Lock()
+		defer pl.mu.Unlock()
+
+		for i, lock := range held {
+			lock.mu.Unlock()
+			lock.refs--
+			if lock.refs == 0 {
+				delete(pl.locks, paths[i])
+			}
+		}
+	}
+}
+
+// dirLock is an advisory lock on a directory shared by other processes. It is held while any goroutine in this
+// process has acquired it, so goroutines don't exclude each other, only other processes.
+type dirLock struct {
+	mu      sync.Mutex
+	dir     string
+	file    *os.File
+	refs    int
+	locking chan struct{} // Closed when the goroutine waiting for other processes has the lock or has failed
+}
+
+// acquire locks the directory, waiting for other processes to release it, and returns a function that releases it.
+// dl.mu isn't held while waiting for other processes, so goroutines acquiring it meanwhile wait for the result instead.
+func (dl *dirLock) acquire() (func(), error) {
+	dl.mu.Lock()
+	for dl.locking != nil {
+		locking := dl.locking
+		dl.mu.Unlock()
+		<-locking
+		dl.mu.Lock()
+	}
+
+	if dl.refs > 0 {
+		dl.refs++
+		dl.mu.Unlock()
+		return dl.release, nil
+	}
+
+	locking := make(chan struct{})
+	dl.locking = locking
+	dl.mu.Unlock()
+
+	file, err := dl.lock()
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.locking = nil
+	close(locking)
+	if err != nil {
+		return nil, err
+	}
+	dl.file = file
+	dl.refs++
+
+	return dl.release, nil
+}
+
+// lock opens the directory and locks it, waiting for other processes to release it.
+func (dl *dirLock) lock() (*os.File, error) {
+	if err := os.MkdirAll(dl.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(dl.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error locking %s: %w", dl.dir, err)
+	}
+
+	return file, nil
+}
+
+// release releases the lock once every goroutine that acquired it has released it.
+func (dl *dirLock) release() {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.refs--
+	if dl.refs == 0 {
+		_ = unlockFile(dl.file)
+		dl.file.Close()
+		dl.file = nil
+	}
+}
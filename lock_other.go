@@ -0,0 +1,20 @@
+//go:build !unix
+
+package downcache
+
+import "os"
+
+// lockFile does nothing on platforms without flock, so writes are only locked within the process.
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without flock.
+func unlockFile(_ *os.File) error {
+	return nil
+}
+
+// syncDir does nothing on platforms where directories can't be synced.
+func syncDir(_ string) error {
+	return nil
+}
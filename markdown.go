@@ -88,6 +88,26 @@ func GenerateETag(content string) string {
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
+// stripFrontmatter returns the content without its leading YAML (---) or TOML (+++) frontmatter block, if it has one.
+func stripFrontmatter(content string) string {
+	for _, delim := range []string{"---", "+++"} {
+		first, rest, found := strings.Cut(content, "\n")
+		if !found || strings.TrimRight(first, "\r") != delim {
+			continue
+		}
+
+		for rest != "" {
+			var line string
+			line, rest, _ = strings.Cut(rest, "\n")
+			if strings.TrimRight(line, "\r") == delim {
+				return strings.TrimLeft(rest, "\r\n")
+			}
+		}
+	}
+
+	return content
+}
+
 // EstimateReadingTime estimates the reading time of the content.
 func EstimateReadingTime(content string) string {
 	// Define reading speed in words per minute
@@ -129,6 +149,7 @@ func MarkdownToPost(md goldmark.Markdown, content []byte) (*Post, error) {
 		return &Post{
 			Content: rawContent,
 			HTML:    html,
+			ETag:    GenerateETag(rawContent),
 		}, nil
 	}
 
@@ -136,6 +157,7 @@ func MarkdownToPost(md goldmark.Markdown, content []byte) (*Post, error) {
 		return &Post{
 			Content: rawContent,
 			HTML:    html,
+			ETag:    GenerateETag(rawContent),
 		}, fmt.Errorf("failed to decode frontmatter: %w", err)
 	}
 
@@ -154,17 +176,24 @@ func MarkdownToPost(md goldmark.Markdown, content []byte) (*Post, error) {
 		ETag:              GenerateETag(rawContent),
 		EstimatedReadTime: EstimateReadingTime(rawContent),
 		Pinned:            meta.Pinned,
+		Weight:            meta.Weight,
 		Photo:             meta.Photo,
-		Properties:        meta.Properties,
+		Properties:        NormalizeProperties(meta.Properties),
 		Published: sql.NullString{
-			String: meta.Published,
-			Valid:  strings.TrimSpace(meta.Published) != "",
+			String: string(meta.Published),
+			Valid:  strings.TrimSpace(string(meta.Published)) != "",
+		},
+		Expires: sql.NullString{
+			String: string(meta.Expires),
+			Valid:  strings.TrimSpace(string(meta.Expires)) != "",
 		},
+		Updated:    strings.TrimSpace(string(meta.Updated)),
 		Status:     meta.Status,
 		Subtitle:   meta.Subtitle,
 		Summary:    meta.Summary,
 		Taxonomies: meta.Taxonomies,
 		Name:       meta.Name,
 		Visibility: meta.Visibility,
+		Aliases:    meta.Aliases,
 	}, nil
 }
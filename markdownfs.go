@@ -2,6 +2,7 @@ package downcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,18 +18,57 @@ type MarkdownFS interface {
 	Move(ctx context.Context, oldType, oldSlug, newType, newSlug string) error
 }
 
-// LocalMarkdownFS implements MarkdownFS for the local file system
+// LocalMarkdownFS implements MarkdownFS for the local file system. Files are written atomically, so readers never
+// see a partly written file. Writes to the same file are serialized, and while a file is being changed, the root
+// directory is locked so other processes using a LocalMarkdownFS on the same directory wait their turn.
 type LocalMarkdownFS struct {
-	rootDir string
-	proc    MarkdownProcessor
-	format  FrontmatterFormat
+	rootDir  string
+	proc     MarkdownProcessor
+	format   FrontmatterFormat
+	cfg      walkConfig // Decides the files that are posts
+	locks    pathLocks  // Locks files being changed by this process
+	rootLock *dirLock   // Locks the root directory against other processes
 }
 
-func NewLocalMarkdownFS(rootDir string, proc MarkdownProcessor, format FrontmatterFormat) *LocalMarkdownFS {
-	return &LocalMarkdownFS{rootDir: rootDir, proc: proc, format: format}
+// NewLocalMarkdownFS returns a LocalMarkdownFS for rootDir, whose top-level directories are the post types.
+// Walk skips hidden files and directories, editor temporary and backup files, and the paths listed in
+// IgnoreFileName files.
+func NewLocalMarkdownFS(rootDir string, proc MarkdownProcessor, format FrontmatterFormat, opts ...WalkOption) *LocalMarkdownFS {
+	return &LocalMarkdownFS{
+		rootDir:  rootDir,
+		proc:     proc,
+		format:   format,
+		cfg:      newWalkConfig(opts),
+		rootLock: &dirLock{dir: rootDir},
+	}
+}
+
+// fileDates returns the created and updated dates of a file, given its path relative to the root directory.
+type fileDates func(relPath string, info os.FileInfo) (created, updated string)
+
+// modTimeDates uses the file's modification time as both its created and updated dates.
+func modTimeDates(_ string, info os.FileInfo) (string, string) {
+	return FormatDate(info.ModTime()), FormatDate(info.ModTime())
+}
+
+// setDates sets the post's created and updated dates. An updated date in the frontmatter is kept.
+func setDates(post *Post, created, updated string) {
+	post.Created = created
+	if post.Updated == "" {
+		post.Updated = updated
+	}
+}
+
+// Slugifier returns the Slugifier of the post type, set with WithSlugifier.
+func (fs *LocalMarkdownFS) Slugifier(postType string) Slugifier {
+	return fs.cfg.slugifierFor(postType)
 }
 
 func (fs *LocalMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error) {
+	return fs.walk(ctx, modTimeDates)
+}
+
+func (fs *LocalMarkdownFS) walk(ctx context.Context, dates fileDates) (<-chan *Post, <-chan error) {
 	posts := make(chan *Post)
 	errs := make(chan error, 1)
 
@@ -36,26 +76,64 @@ func (fs *LocalMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error
 		defer close(posts)
 		defer close(errs)
 
+		var ignore ignoreRules
 		err := filepath.Walk(fs.rootDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			if info.IsDir() || filepath.Ext(path) != ".md" {
-				return nil
-			}
 
 			relPath, err := filepath.Rel(fs.rootDir, path)
 			if err != nil {
 				return err
 			}
 
+			if relPath != "." && (hiddenOrTemp(info.Name()) || ignore.ignored(filepath.ToSlash(relPath), info.IsDir())) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
 			parts := strings.Split(relPath, string(os.PathSeparator))
-			if len(parts) < 2 {
-				return fmt.Errorf("invalid file path structure: %s", relPath)
+
+			// A directory below a post type with an index file is a page bundle. Everything else in it is an asset.
+			bundle := false
+			if info.IsDir() {
+				rules, err := readIgnoreFile(path, relPath)
+				if err != nil {
+					return err
+				}
+				ignore = append(ignore, rules...)
+
+				if len(parts) < 2 {
+					return nil
+				}
+				index := fs.findFile(filepath.Join(path, BundleIndexName))
+				if index == "" {
+					return nil
+				}
+				bundle = true
+				path = index
+				relPath = filepath.Join(relPath, filepath.Base(index))
+				if info, err = os.Stat(path); err != nil {
+					return err
+				}
+			} else if !fs.cfg.isPost(path) {
+				return nil
+			} else if len(parts) > 2 && path == fs.findFile(filepath.Join(filepath.Dir(path), BundleIndexName)) {
+				// The index file of a section, which was read with its directory
+				return nil
 			}
 
 			postType := parts[0]
-			slug := SlugifyPath(fs.rootDir, path, PostType(postType))
+			slug := fs.cfg.slugifyPath(fs.rootDir, path, postType)
+			if len(parts) < 2 {
+				var ok bool
+				if postType, ok, err = fs.cfg.rootType(relPath); !ok {
+					return err
+				}
+				slug = fs.cfg.slugifyPath(fs.rootDir, path, "")
+			}
 
 			content, err := os.ReadFile(path)
 			if err != nil {
@@ -68,9 +146,17 @@ func (fs *LocalMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error
 			}
 
 			post.PostType = postType
-			post.Slug = slug.Slug
-			post.Created = info.ModTime().String()
-			post.Updated = info.ModTime().String()
+			post.Slug = fs.cfg.slug(postType, slug)
+			post.sourcePath = filepath.ToSlash(relPath)
+			created, updated := dates(relPath, info)
+			setDates(post, created, updated)
+			setFileDate(post, slug)
+
+			if bundle {
+				if post.Assets, err = bundleAssets(path, fs.cfg); err != nil {
+					return err
+				}
+			}
 
 			select {
 			case posts <- post:
@@ -78,6 +164,10 @@ func (fs *LocalMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error
 				return ctx.Err()
 			}
 
+			// The posts in a section are walked, but everything else in a page bundle is an asset
+			if bundle && !fs.cfg.isSection(path) {
+				return filepath.SkipDir
+			}
 			return nil
 		})
 		if err != nil {
@@ -89,7 +179,11 @@ func (fs *LocalMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error
 }
 
 func (fs *LocalMarkdownFS) Read(_ context.Context, postType, slug string) (*Post, error) {
-	path := fs.buildPath(postType, slug)
+	return fs.read(postType, slug, modTimeDates)
+}
+
+func (fs *LocalMarkdownFS) read(postType, slug string, dates fileDates) (*Post, error) {
+	path, bundle := fs.postPath(postType, slug)
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -105,21 +199,28 @@ func (fs *LocalMarkdownFS) Read(_ context.Context, postType, slug string) (*Post
 		return nil, err
 	}
 
+	relPath, err := filepath.Rel(fs.rootDir, path)
+	if err != nil {
+		return nil, err
+	}
+
 	post.PostType = postType
 	post.Slug = slug
-	post.Created = info.ModTime().String()
-	post.Updated = info.ModTime().String()
+	created, updated := dates(relPath, info)
+	setDates(post, created, updated)
+	setFileDate(post, SlugifyPath(fs.rootDir, path, ""))
+
+	if bundle {
+		if post.Assets, err = bundleAssets(path, fs.cfg); err != nil {
+			return nil, err
+		}
+	}
 
 	return post, nil
 }
 
 func (fs *LocalMarkdownFS) Write(_ context.Context, post *Post) error {
-	path := fs.buildPath(post.PostType, post.Slug)
-
-	err := os.MkdirAll(filepath.Dir(path), 0o755)
-	if err != nil {
-		return err
-	}
+	key := fs.buildPath(post.PostType, post.Slug)
 
 	// Generate frontmatter
 	frontmatter, err := fs.proc.GenerateFrontmatter(post.Meta(), FrontmatterYAML)
@@ -127,38 +228,282 @@ func (fs *LocalMarkdownFS) Write(_ context.Context, post *Post) error {
 		return err
 	}
 
-	// Combine frontmatter and content
+	// Combine frontmatter and content, replacing the frontmatter of a post that was read from a file
+	body := stripFrontmatter(post.Content)
 	switch fs.format {
 	case FrontmatterYAML:
-		post.Content = fmt.Sprintf("---\n%s---\n\n%s", frontmatter, post.Content)
+		post.Content = fmt.Sprintf("---\n%s---\n\n%s", frontmatter, body)
 	case FrontmatterTOML:
-		post.Content = fmt.Sprintf("+++\n%s+++\n\n%s", frontmatter, post.Content)
+		post.Content = fmt.Sprintf("+++\n%s+++\n\n%s", frontmatter, body)
 	default:
 		return fmt.Errorf("unsupported frontmatter format: %s", fs.format)
 	}
 
-	return os.WriteFile(path, []byte(post.Content), 0o644)
+	unlock, err := fs.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A new post keeps the date of the file it was read from, if its post type's slugs don't include it
+	path, bundle := fs.postPath(post.PostType, post.Slug)
+	if ext := filepath.Ext(path); !bundle && !fileExists(path) && post.FileTimePath != "" {
+		path = fs.datedBase(post.PostType, strings.TrimSuffix(path, ext), post.FileTimePath+"-") + ext
+	}
+	if err := writeFileAtomic(path, []byte(post.Content), 0o644); err != nil {
+		return err
+	}
+
+	// The ETag and assets of the post as written, so they match the post when read back
+	post.ETag = GenerateETag(post.Content)
+	if bundle {
+		if post.Assets, err = bundleAssets(path, fs.cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (fs *LocalMarkdownFS) Delete(_ context.Context, postType, slug string) error {
-	path := fs.buildPath(postType, slug)
-	return os.Remove(path)
+	unlock, err := fs.lock(fs.buildPath(postType, slug))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A page bundle is deleted with its assets
+	path, bundle := fs.postPath(postType, slug)
+	if err := fs.checkChildren(postType, slug, path, bundle); err != nil {
+		return err
+	}
+	if bundle {
+		path = filepath.Dir(path)
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
 }
 
 func (fs *LocalMarkdownFS) Move(_ context.Context, oldType, oldSlug, newType, newSlug string) error {
-	oldPath := fs.buildPath(oldType, oldSlug)
-	newPath := fs.buildPath(newType, newSlug)
+	unlock, err := fs.lock(fs.buildPath(oldType, oldSlug), fs.buildPath(newType, newSlug))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A page bundle is moved with its assets. The date of a file whose slug doesn't include it is kept.
+	oldPath, bundle := fs.postPath(oldType, oldSlug)
+	if err := fs.checkChildren(oldType, oldSlug, oldPath, bundle); err != nil {
+		return err
+	}
+	prefix := ""
+	if fs.cfg.stripsDate(oldType) {
+		prefix = postDatePrefix(oldPath, bundle)
+	}
+	newPath := fs.datedBase(newType, filepath.Join(fs.rootDir, newType, newSlug), prefix) + filepath.Ext(oldPath)
+	if bundle {
+		oldPath = filepath.Dir(oldPath)
+		newPath = fs.datedBase(newType, fs.buildBundleDir(newType, newSlug), prefix)
+	}
 
 	// Ensure the directory for the new path exists
-	err := os.MkdirAll(filepath.Dir(newPath), 0o755)
+	err = os.MkdirAll(filepath.Dir(newPath), 0o755)
 	if err != nil {
 		return err
 	}
 
 	// Move the file
-	return os.Rename(oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(oldPath)); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(newPath))
 }
 
+// lock locks the paths against other goroutines, and the root directory against other processes, and returns a
+// function that unlocks them.
+func (fs *LocalMarkdownFS) lock(paths ...string) (func(), error) {
+	unlockPaths := fs.locks.lock(paths...)
+
+	release, err := fs.rootLock.acquire()
+	if err != nil {
+		unlockPaths()
+		return nil, err
+	}
+
+	return func() {
+		release()
+		unlockPaths()
+	}, nil
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory, syncs it to disk and renames it over
+// the path, so the path always holds either the old or the new contents.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// buildPath returns the path of a new post's file.
 func (fs *LocalMarkdownFS) buildPath(postType, slug string) string {
-	return filepath.Join(fs.rootDir, postType, slug+".md")
+	return filepath.Join(fs.rootDir, postType, slug+fs.cfg.extensions[0])
+}
+
+func (fs *LocalMarkdownFS) buildBundleDir(postType, slug string) string {
+	return filepath.Join(fs.rootDir, postType, slug)
+}
+
+// postPath returns the path of a post's file, which is the index file of a page bundle if the post is one, and
+// whether it is. Files directly in the root directory are found for the WithRootPostType post type. If the post
+// doesn't exist, the path for a new file is returned.
+func (fs *LocalMarkdownFS) postPath(postType, slug string) (string, bool) {
+	path, bundle := fs.findSlug(postType, filepath.Join(fs.rootDir, postType, slug))
+	if path != "" {
+		return path, bundle
+	}
+
+	if postType == fs.cfg.rootPostType {
+		if path, bundle := fs.findSlug(postType, filepath.Join(fs.rootDir, slug)); path != "" && !bundle {
+			return path, false
+		}
+	}
+
+	return fs.buildPath(postType, slug), false
+}
+
+// findSlug returns the path of a post of the post type, given its path without an extension, as findPost does. If
+// the post type's slugs don't include the dates in their file names, a file or page bundle with a date is found too.
+func (fs *LocalMarkdownFS) findSlug(postType, base string) (string, bool) {
+	if path, bundle := fs.findPost(base); path != "" {
+		return path, bundle
+	}
+	if fs.cfg.stripsDate(postType) {
+		return fs.findDated(base)
+	}
+	return "", false
+}
+
+// findDated returns the path of a post whose file or page bundle name is the base's name with a date prefix, given
+// its path without an extension, as findPost does. If there are several, the earliest is returned.
+func (fs *LocalMarkdownFS) findDated(base string) (string, bool) {
+	dir, name := filepath.Split(base)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, entry := range entries {
+		prefix := fileDatePrefix(entry.Name())
+		if prefix == "" || !strings.HasPrefix(entry.Name()[len(prefix):], name) {
+			continue
+		}
+		if path, bundle := fs.findPost(filepath.Join(dir, prefix+name)); path != "" {
+			return path, bundle
+		}
+	}
+
+	return "", false
+}
+
+// datedBase adds a date prefix to the name of a post's path without an extension, if the post type's slugs don't
+// include the dates in their file names.
+func (fs *LocalMarkdownFS) datedBase(postType, base, prefix string) string {
+	if prefix == "" || !fs.cfg.stripsDate(postType) {
+		return base
+	}
+	dir, name := filepath.Split(base)
+	return filepath.Join(dir, prefix+name)
+}
+
+// postDatePrefix returns the date prefix of the name of a post's file, or of its page bundle's directory.
+func postDatePrefix(path string, bundle bool) string {
+	if bundle {
+		path = filepath.Dir(path)
+	}
+	return fileDatePrefix(filepath.Base(path))
+}
+
+// findPost returns the path of a post, given its path without an extension: a file with one of the extensions of
+// posts, or the index file of a page bundle in the directory with that path. It returns "" if there isn't one.
+func (fs *LocalMarkdownFS) findPost(base string) (string, bool) {
+	if path := fs.findFile(base); path != "" {
+		return path, false
+	}
+	if path := fs.findFile(filepath.Join(base, BundleIndexName)); path != "" {
+		return path, true
+	}
+	return "", false
+}
+
+// findFile returns the path of the file with the path without an extension and one of the extensions of posts, or
+// "" if there isn't one.
+func (fs *LocalMarkdownFS) findFile(base string) string {
+	for _, ext := range fs.cfg.extensions {
+		if fileExists(base + ext) {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// readIgnoreFile returns the rules in the directory's ignore file, if it has one.
+func readIgnoreFile(dir, relDir string) (ignoreRules, error) {
+	data, err := os.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if relDir == "." {
+		relDir = ""
+	}
+	return parseIgnoreFile(filepath.ToSlash(relDir), data), nil
+}
+
+// fileExists returns true if there is a regular file at the path.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
 }
@@ -4,8 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -279,3 +284,195 @@ This is concurrent post %d.`, i, i, i)
 		assert.NoError(t, err)
 	}
 }
+
+func TestLocalFileSystemManager_ConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	// Two instances on the same directory stand in for two processes
+	instances := []*downcache.LocalMarkdownFS{
+		downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML),
+		downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML),
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fsm := instances[i%len(instances)]
+			assert.NoError(t, fsm.Write(ctx, &downcache.Post{
+				PostType: "articles",
+				Slug:     "shared",
+				Name:     fmt.Sprintf("Writer %d", i),
+				Content:  strings.Repeat(fmt.Sprintf("Written by writer %d.\n", i), 1000),
+			}))
+			assert.NoError(t, fsm.Move(ctx, "articles", "shared", "articles", "shared"))
+		}()
+	}
+	wg.Wait()
+
+	// The file holds exactly one writer's contents
+	post, err := instances[0].Read(ctx, "articles", "shared")
+	require.NoError(t, err)
+	var writer int
+	_, err = fmt.Sscanf(post.Name, "Writer %d", &writer)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, strings.Count(post.Content, fmt.Sprintf("Written by writer %d.\n", writer)))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "articles"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "shared.md", entries[0].Name())
+}
+
+func TestLocalFileSystemManager_WalkRules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		downcache.IgnoreFileName:                     "# Not posts\nREADME.md\ndrafts/\n/articles/private.md\n",
+		"README.md":                                  "Read me",
+		"CHANGELOG.md":                               "---\nname: Changelog\n---\n",
+		"about.markdown":                             "---\nname: About\n---\n",
+		"articles/post.md":                           "---\nname: Post\n---\n",
+		"articles/long.markdown":                     "---\nname: Long\n---\n",
+		"articles/component.MDX":                     "---\nname: Component\n---\n",
+		"articles/private.md":                        "---\nname: Private\n---\n",
+		"articles/post.md~":                          "Backup",
+		"articles/#post.md#":                         "Autosave",
+		"articles/.#post.md":                         "Lock",
+		"articles/old.md.bak":                        "Backup",
+		"articles/drafts/wip.md":                     "---\nname: WIP\n---\n",
+		"articles/notes/" + downcache.IgnoreFileName: "*.md\n!keep.md\n",
+		"articles/notes/keep.md":                     "---\nname: Keep\n---\n",
+		"articles/notes/skip.md":                     "---\nname: Skip\n---\n",
+		".git/description.md":                        "Hidden",
+		"pages/contact.txt":                          "Not markdown",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), []byte(content))
+	}
+
+	walk := func(fsm downcache.MarkdownFS) ([]string, error) {
+		posts, errs := fsm.Walk(context.Background())
+		var ids []string
+		for post := range posts {
+			ids = append(ids, downcache.PostPathID(post.PostType, post.Slug))
+		}
+		sort.Strings(ids)
+		return ids, <-errs
+	}
+
+	// Files directly in the root directory fail the walk by default
+	_, err := walk(downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML))
+	assert.ErrorContains(t, err, "invalid file path structure: CHANGELOG.md")
+
+	ids, err := walk(downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML, downcache.SkipRootFiles()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"articles/notes/keep", "articles/post"}, ids)
+
+	// Or are posts of the root post type
+	fsm := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML,
+		downcache.WithExtensions(".md", "markdown", ".mdx"), downcache.WithRootPostType("pages"))
+	ids, err = walk(fsm)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"articles/component",
+		"articles/long",
+		"articles/notes/keep",
+		"articles/post",
+		"pages/about",
+		"pages/changelog",
+	}, ids)
+
+	ctx := context.Background()
+	post, err := fsm.Read(ctx, "articles", "long")
+	require.NoError(t, err)
+	assert.Equal(t, "Long", post.Name)
+
+	post, err = fsm.Read(ctx, "pages", "about")
+	require.NoError(t, err)
+	assert.Equal(t, "About", post.Name)
+
+	// New posts are written with the first extension
+	require.NoError(t, fsm.Write(ctx, &downcache.Post{PostType: "pages", Slug: "new", Name: "New"}))
+	assert.FileExists(t, filepath.Join(dir, "pages", "new.md"))
+}
+
+func TestLocalFileSystemManager_FileDates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"articles/2024-08-21-jekyll-post.md": "---\nname: Jekyll Post\n---\n",
+		"articles/2024-08-22-dated.md":       "---\nname: Dated\npublished: 2024-09-01\n---\n",
+		"articles/2024-08-23-trip/index.md":  "---\nname: Trip\n---\n",
+		"articles/2024-08-23-trip/photo.png": string(pngHeader),
+		"notes/2024-08-24-note.md":           "---\nname: Note\n---\n",
+		"notes/undated.md":                   "---\nname: Undated\n---\n",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), []byte(content))
+	}
+
+	ctx := context.Background()
+	fsm := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML, downcache.StripSlugDates("articles"))
+
+	posts, errs := fsm.Walk(ctx)
+	walked := map[string]*downcache.Post{}
+	for post := range posts {
+		walked[downcache.PostPathID(post.PostType, post.Slug)] = post
+	}
+	require.NoError(t, <-errs)
+
+	// The file date is the published date, unless the frontmatter has one, and is only stripped from articles
+	expected := map[string][2]string{
+		"articles/jekyll-post":  {"2024-08-21", "2024-08-21"},
+		"articles/dated":        {"2024-08-22", "2024-09-01"},
+		"articles/trip":         {"2024-08-23", "2024-08-23"},
+		"notes/2024-08-24-note": {"2024-08-24", "2024-08-24"},
+		"notes/undated":         {"", ""},
+	}
+	require.Len(t, walked, len(expected))
+	for id, dates := range expected {
+		post, ok := walked[id]
+		require.True(t, ok, id)
+		assert.Equal(t, dates[0], post.FileTimePath, id)
+		assert.Equal(t, dates[1], post.Published.String, id)
+		assert.Equal(t, dates[1] != "", post.Published.Valid, id)
+	}
+
+	// Posts are read by their slugs without the dates
+	post, err := fsm.Read(ctx, "articles", "jekyll-post")
+	require.NoError(t, err)
+	assert.Equal(t, "Jekyll Post", post.Name)
+	assert.Equal(t, "2024-08-21", post.FileTimePath)
+	assert.Equal(t, "2024-08-21", post.Published.String)
+
+	post, err = fsm.Read(ctx, "articles", "trip")
+	require.NoError(t, err)
+	assert.Len(t, post.Assets, 1)
+
+	// Writes, moves and the trash keep the dates in the file names
+	post.Name = "Road Trip"
+	require.NoError(t, fsm.Write(ctx, post))
+	assert.FileExists(t, filepath.Join(dir, "articles", "2024-08-23-trip", "index.md"))
+	assert.NoFileExists(t, filepath.Join(dir, "articles", "trip.md"))
+
+	require.NoError(t, fsm.Move(ctx, "articles", "jekyll-post", "articles", "moved"))
+	assert.FileExists(t, filepath.Join(dir, "articles", "2024-08-21-moved.md"))
+
+	require.NoError(t, fsm.Trash(ctx, "articles", "moved", time.Now()))
+	assert.NoFileExists(t, filepath.Join(dir, "articles", "2024-08-21-moved.md"))
+	trashed, err := fsm.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, "2024-08-21", trashed[0].Post.FileTimePath)
+
+	require.NoError(t, fsm.Untrash(ctx, "articles", "moved"))
+	assert.FileExists(t, filepath.Join(dir, "articles", "2024-08-21-moved.md"))
+
+	require.NoError(t, fsm.Delete(ctx, "articles", "trip"))
+	assert.NoDirExists(t, filepath.Join(dir, "articles", "2024-08-23-trip"))
+
+	// A new post keeps the date of the file it came from
+	require.NoError(t, fsm.Write(ctx, &downcache.Post{PostType: "articles", Slug: "new", FileTimePath: "2024-10-01"}))
+	assert.FileExists(t, filepath.Join(dir, "articles", "2024-10-01-new.md"))
+}
@@ -0,0 +1,166 @@
+package downcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ShadowedPost is a post in a lower layer of an OverlayMarkdownFS that is hidden by a post with the same type and
+// slug in a higher layer.
+type ShadowedPost struct {
+	PostType   string
+	Slug       string
+	Layer      int // The index of the layer with the hidden post
+	ShadowedBy int // The index of the layer with the post that hides it
+}
+
+// OverlayOption configures an OverlayMarkdownFS.
+type OverlayOption func(*OverlayMarkdownFS)
+
+// WithShadowedHandler sets a function that Walk calls for each post hidden by a post in a higher layer.
+func WithShadowedHandler(handler func(ShadowedPost)) OverlayOption {
+	return func(o *OverlayMarkdownFS) {
+		o.onShadowed = handler
+	}
+}
+
+// OverlayMarkdownFS implements MarkdownFS by stacking several MarkdownFS layers, such as a site's own content over
+// shared content over theme defaults. A post in a higher layer hides posts with the same type and slug in lower
+// layers. Writes go to the top layer, so updating a post from a lower layer copies it to the top layer. Posts that
+// are only in lower layers can't be moved or deleted.
+type OverlayMarkdownFS struct {
+	layers     []MarkdownFS
+	onShadowed func(ShadowedPost)
+}
+
+var _ MarkdownFS = (*OverlayMarkdownFS)(nil)
+
+// NewOverlayMarkdownFS returns an OverlayMarkdownFS for the layers, highest priority first. The first layer is the
+// top layer that writes go to.
+func NewOverlayMarkdownFS(layers []MarkdownFS, opts ...OverlayOption) *OverlayMarkdownFS {
+	o := &OverlayMarkdownFS{layers: layers}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ReadOnly returns true if the top layer is read-only.
+func (o *OverlayMarkdownFS) ReadOnly() bool {
+	ro, ok := o.top().(interface{ ReadOnly() bool })
+	return ok && ro.ReadOnly()
+}
+
+// Slugifier returns the Slugifier of the post type in the top layer, if it has one.
+func (o *OverlayMarkdownFS) Slugifier(postType string) Slugifier {
+	return fsSlugifier(o.top(), postType)
+}
+
+// Walk walks each layer in turn, highest priority first, returning the posts that aren't hidden by a higher layer.
+func (o *OverlayMarkdownFS) Walk(ctx context.Context) (<-chan *Post, <-chan error) {
+	posts := make(chan *Post)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(posts)
+		defer close(errs)
+
+		// The layer each post was found in
+		seen := make(map[string]int)
+
+		for i, layer := range o.layers {
+			layerPosts, layerErrs := layer.Walk(ctx)
+			for post := range layerPosts {
+				id := PostPathID(post.PostType, post.Slug)
+				// Posts with the same slug in one layer are passed on, so SyncAll reports the collision
+				if by, ok := seen[id]; ok && by != i {
+					if o.onShadowed != nil {
+						o.onShadowed(ShadowedPost{PostType: post.PostType, Slug: post.Slug, Layer: i, ShadowedBy: by})
+					}
+					continue
+				}
+				seen[id] = i
+
+				select {
+				case posts <- post:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+
+			for err := range layerErrs {
+				errs <- fmt.Errorf("error walking layer %d: %w", i, err)
+				return
+			}
+		}
+	}()
+
+	return posts, errs
+}
+
+// Read returns the post from the highest priority layer that has it.
+func (o *OverlayMarkdownFS) Read(ctx context.Context, postType, slug string) (*Post, error) {
+	post, _, err := o.find(ctx, postType, slug)
+	return post, err
+}
+
+// Write writes the post to the top layer.
+func (o *OverlayMarkdownFS) Write(ctx context.Context, post *Post) error {
+	return o.top().Write(ctx, post)
+}
+
+// Delete deletes the post from the top layer, which reveals any post with the same type and slug in a lower layer.
+func (o *OverlayMarkdownFS) Delete(ctx context.Context, postType, slug string) error {
+	if err := o.checkTop(ctx, postType, slug); err != nil {
+		return err
+	}
+	return o.top().Delete(ctx, postType, slug)
+}
+
+// Move moves the post within the top layer.
+func (o *OverlayMarkdownFS) Move(ctx context.Context, oldType, oldSlug, newType, newSlug string) error {
+	if err := o.checkTop(ctx, oldType, oldSlug); err != nil {
+		return err
+	}
+	return o.top().Move(ctx, oldType, oldSlug, newType, newSlug)
+}
+
+// find returns the post from the highest priority layer that has it, and the index of the layer.
+func (o *OverlayMarkdownFS) find(ctx context.Context, postType, slug string) (*Post, int, error) {
+	notFound := fmt.Errorf("%s: %w", PostPathID(postType, slug), fs.ErrNotExist)
+
+	for i, layer := range o.layers {
+		post, err := layer.Read(ctx, postType, slug)
+		if err == nil {
+			return post, i, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, i, fmt.Errorf("error reading layer %d: %w", i, err)
+		}
+		notFound = err
+	}
+
+	return nil, -1, notFound
+}
+
+// checkTop returns ErrReadOnly if the post is only in a lower layer, which can't be changed.
+func (o *OverlayMarkdownFS) checkTop(ctx context.Context, postType, slug string) error {
+	_, layer, err := o.find(ctx, postType, slug)
+	if err != nil {
+		return err
+	}
+
+	if layer != 0 {
+		return fmt.Errorf("%w: %s is in layer %d", ErrReadOnly, PostPathID(postType, slug), layer)
+	}
+
+	return nil
+}
+
+// top returns the layer writes go to.
+func (o *OverlayMarkdownFS) top() MarkdownFS {
+	return o.layers[0]
+}
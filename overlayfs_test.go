@@ -0,0 +1,96 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestOverlayMarkdownFS(t *testing.T) {
+	theme := downcache.NewIOFSMarkdownFS(fstest.MapFS{
+		"pages/about.md":   {Data: []byte("---\nname: Theme About\n---\n\nAbout")},
+		"pages/privacy.md": {Data: []byte("---\nname: Theme Privacy\n---\n\nPrivacy")},
+		"pages/terms.md":   {Data: []byte("---\nname: Theme Terms\n---\n\nTerms")},
+	}, realProcessor)
+	shared := downcache.NewIOFSMarkdownFS(fstest.MapFS{
+		"pages/privacy.md": {Data: []byte("---\nname: Team Privacy\n---\n\nPrivacy")},
+	}, realProcessor)
+
+	dir := t.TempDir()
+	site := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pages"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pages", "about.md"), []byte("---\nname: Site About\n---\n\nAbout us"), 0o644))
+
+	var shadowed []downcache.ShadowedPost
+	overlay := downcache.NewOverlayMarkdownFS([]downcache.MarkdownFS{site, shared, theme},
+		downcache.WithShadowedHandler(func(sp downcache.ShadowedPost) {
+			shadowed = append(shadowed, sp)
+		}))
+	assert.False(t, overlay.ReadOnly())
+
+	// Walk returns one post per slug, from the highest layer that has it
+	ctx := context.Background()
+	posts, errs := overlay.Walk(ctx)
+	names := make(map[string]string)
+	for post := range posts {
+		names[downcache.PostPathID(post.PostType, post.Slug)] = post.Name
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[string]string{
+		"pages/about":   "Site About",
+		"pages/privacy": "Team Privacy",
+		"pages/terms":   "Theme Terms",
+	}, names)
+
+	sort.Slice(shadowed, func(i, j int) bool { return shadowed[i].Slug < shadowed[j].Slug })
+	assert.Equal(t, []downcache.ShadowedPost{
+		{PostType: "pages", Slug: "about", Layer: 2, ShadowedBy: 0},
+		{PostType: "pages", Slug: "privacy", Layer: 2, ShadowedBy: 1},
+	}, shadowed)
+
+	// Read resolves by priority
+	post, err := overlay.Read(ctx, "pages", "privacy")
+	require.NoError(t, err)
+	assert.Equal(t, "Team Privacy", post.Name)
+
+	_, err = overlay.Read(ctx, "pages", "missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Posts only in lower layers can't be moved or deleted
+	assert.ErrorIs(t, overlay.Delete(ctx, "pages", "terms"), downcache.ErrReadOnly)
+	assert.ErrorIs(t, overlay.Move(ctx, "pages", "terms", "pages", "legal"), downcache.ErrReadOnly)
+
+	cm := downcache.NewDownCache(overlay, downcache.NewMemoryCacheStore())
+	require.NoError(t, cm.SyncAll(ctx))
+
+	// Updating a post from a lower layer copies it to the top layer
+	post.Name = "Site Privacy"
+	require.NoError(t, cm.Update(ctx, "pages", "privacy", post))
+	assert.FileExists(t, filepath.Join(dir, "pages", "privacy.md"))
+
+	got, err := cm.Get(ctx, "pages", "privacy")
+	require.NoError(t, err)
+	assert.Equal(t, "Site Privacy", got.Name)
+
+	// Deleting it from the top layer reveals the lower version again
+	require.NoError(t, cm.Delete(ctx, "pages", "privacy"))
+	assert.NoFileExists(t, filepath.Join(dir, "pages", "privacy.md"))
+
+	got, err = cm.Get(ctx, "pages", "privacy")
+	require.NoError(t, err)
+	assert.Equal(t, "Team Privacy", got.Name)
+
+	issues, err := cm.Verify(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+}
@@ -16,7 +16,9 @@ type Paginator struct {
 	AllPosts         []*Post
 	FeaturedPosts    []*Post
 	NonFeaturedPosts []*Post
-	Visible          bool // True by default, but can be set to false in the view. E.g. on the home page.
+	Visible          bool   // True by default, but can be set to false in the view. E.g. on the home page.
+	NextCursor       string // The cursor to pass as FilterOptions.After for the next page, if HasNext
+	PrevCursor       string // The cursor to pass as FilterOptions.Before for the previous page, if HasPrev
 }
 
 // NewPaginator returns a Paginator struct with the given parameters.
@@ -69,3 +71,55 @@ func NewPaginator(docs []*Post, total, currentPage, pageSize int, includeFeature
 		Visible:          true,
 	}
 }
+
+// NewCursorPaginator returns a Paginator for a page of posts returned by a search with the given filter options,
+// with cursors for the next and previous pages. Cursor pagination has no page numbers, so HasNext is reported
+// whenever the page is full, which can result in an empty last page.
+func NewCursorPaginator(docs []*Post, total int, filter FilterOptions) Paginator {
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	paginator := NewPaginator(docs, total, 0, pageSize, filter.SplitPinned)
+	paginator.CurrentPage = 0
+	paginator.NextPage = 0
+	paginator.PrevPage = 0
+
+	// Pinned posts are prepended to the first page when split, so they aren't part of the cursor order
+	page := docs
+	if filter.SplitPinned {
+		page = paginator.NonFeaturedPosts
+	}
+
+	full := len(page) >= pageSize
+	switch {
+	case filter.Before != "":
+		paginator.HasNext = true
+		paginator.HasPrev = full
+	case filter.After != "":
+		paginator.HasNext = full
+		paginator.HasPrev = true
+	default:
+		paginator.HasNext = full && total > len(docs)
+		paginator.HasPrev = false
+	}
+
+	if len(page) == 0 {
+		// There are no posts to create cursors from
+		paginator.HasNext = false
+		paginator.HasPrev = false
+		return paginator
+	}
+
+	sortBy := filter.Sort()
+	if paginator.HasNext {
+		paginator.NextCursor = NewCursor(page[len(page)-1], sortBy).Encode()
+	}
+
+	if paginator.HasPrev {
+		paginator.PrevCursor = NewCursor(page[0], sortBy).Encode()
+	}
+
+	return paginator
+}
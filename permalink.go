@@ -0,0 +1,165 @@
+package downcache
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultPermalink is the permalink pattern of post types without one set with WithPermalink.
+const DefaultPermalink = "/:type/:slug"
+
+// permalinkToken matches the tokens in a permalink pattern, such as :year and :taxonomy.categories.
+var permalinkToken = regexp.MustCompile(`:(?:taxonomy\.([A-Za-z0-9_-]+)|([a-z]+))`)
+
+// WithPermalink sets the permalink pattern of a post type, which sets the Permalink of its posts when they are added
+// to the store. See ExpandPermalink for the tokens a pattern can use, such as "/:year/:month/:slug/".
+func WithPermalink(postType, pattern string) Option {
+	return func(cm *DownCache) {
+		if cm.permalinks == nil {
+			cm.permalinks = make(map[string]string)
+		}
+		cm.permalinks[postType] = pattern
+	}
+}
+
+// ExpandPermalink returns a post's permalink, replacing the tokens in the pattern with the post's values:
+//
+//   - :year, :month and :day are the published date in the location, or UTC if it is nil, with zero padding
+//   - :type is the post type
+//   - :slug is the slug, including any directories
+//   - :section is the directories of the slug, such as guides/setup for the slug guides/setup/install
+//   - :filename is the last part of the slug
+//   - :title is the slugified name, or the last part of the slug if the post doesn't have a name
+//   - :taxonomy.name is the slugified first term of the taxonomy, such as :taxonomy.categories
+//
+// Path segments left empty, such as the date of a post without a published date, are removed. Unknown tokens are
+// left as they are. The permalink always starts with a slash, and ends with one if the pattern does.
+func ExpandPermalink(pattern string, post *Post, loc *time.Location) string {
+	return expandPermalink(pattern, post, loc, DefaultSlugifier)
+}
+
+// expandPermalink is ExpandPermalink, slugifying names and taxonomy terms with the slugifier.
+func expandPermalink(pattern string, post *Post, loc *time.Location, slugifier Slugifier) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	published := post.PublishedTime()
+	if !published.IsZero() {
+		published = published.In(loc)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(pattern, "/") {
+		hasTokens := false
+		segment = permalinkToken.ReplaceAllStringFunc(segment, func(token string) string {
+			value, ok := permalinkValue(permalinkToken.FindStringSubmatch(token), post, published, slugifier)
+			if !ok {
+				return token
+			}
+			hasTokens = true
+			return value
+		})
+		segment = strings.Trim(segment, "/")
+		if segment != "" || !hasTokens {
+			segments = append(segments, segment)
+		}
+	}
+
+	permalink := path.Clean("/" + strings.Join(segments, "/"))
+	if strings.HasSuffix(pattern, "/") && permalink != "/" {
+		permalink += "/"
+	}
+	return permalink
+}
+
+// permalinkValue returns the value of a permalink token's submatches, or false if the token is unknown.
+func permalinkValue(token []string, post *Post, published time.Time, slugifier Slugifier) (string, bool) {
+	date := func(format string) string {
+		if published.IsZero() {
+			return ""
+		}
+		return published.Format(format)
+	}
+
+	if token[1] != "" {
+		if terms := post.Taxonomies[token[1]]; len(terms) > 0 {
+			return slugifier.Slugify(terms[0]), true
+		}
+		return "", true
+	}
+
+	switch token[2] {
+	case "year":
+		return date("2006"), true
+	case "month":
+		return date("01"), true
+	case "day":
+		return date("02"), true
+	case "type":
+		return post.PostType, true
+	case "slug":
+		return post.Slug, true
+	case "section":
+		if dir := path.Dir(post.Slug); dir != "." {
+			return dir, true
+		}
+		return "", true
+	case "filename":
+		return path.Base(post.Slug), true
+	case "title":
+		if post.Name == "" {
+			return path.Base(post.Slug), true
+		}
+		return slugifier.Slugify(post.Name), true
+	default:
+		return "", false
+	}
+}
+
+// permalink returns the permalink of a post, from its post type's pattern.
+func (cm *DownCache) permalink(post *Post) string {
+	pattern, ok := cm.permalinks[post.PostType]
+	if !ok {
+		pattern = DefaultPermalink
+	}
+	return expandPermalink(pattern, post, cm.location, cm.slugifier(post.PostType))
+}
+
+// ResolvePermalink returns the post with the permalink, such as a request's URL path, for routers. The path matches
+// with or without a trailing slash. As with Get, ErrNotFound is returned if the viewer in the context can't see the
+// post.
+func (cm *DownCache) ResolvePermalink(ctx context.Context, permalink string) (*Post, error) {
+	permalink = "/" + strings.TrimPrefix(permalink, "/")
+	for _, candidate := range pathCandidates(permalink) {
+		posts, _, err := cm.Search(ctx, FilterOptions{
+			PageSize:         1,
+			FilterPermalink:  candidate,
+			FilterPostType:   PostTypeKeyAny,
+			Viewer:           Viewer{Role: ViewerAdmin},
+			IncludeScheduled: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(posts) > 0 {
+			return cm.Get(ctx, posts[0].PostType, posts[0].Slug)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no post has the permalink %s", ErrNotFound, permalink)
+}
+
+// pathCandidates returns a URL path as it is, and with its trailing slash removed or added.
+func pathCandidates(urlPath string) []string {
+	candidates := []string{urlPath}
+	if trimmed := strings.TrimSuffix(urlPath, "/"); trimmed != urlPath && trimmed != "" {
+		candidates = append(candidates, trimmed)
+	} else if trimmed == urlPath {
+		candidates = append(candidates, urlPath+"/")
+	}
+	return candidates
+}
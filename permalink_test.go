@@ -0,0 +1,87 @@
+package downcache_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestExpandPermalink(t *testing.T) {
+	post := &downcache.Post{
+		PostType:   "articles",
+		Slug:       "guides/setup/install",
+		Name:       "Installing DownCache!",
+		Published:  sql.NullString{String: "2024-03-10T03:00:00Z", Valid: true},
+		Taxonomies: map[string][]string{"categories": {"How To", "Go"}},
+	}
+	undated := &downcache.Post{PostType: "pages", Slug: "about"}
+
+	cases := []struct {
+		pattern  string
+		post     *downcache.Post
+		loc      *time.Location
+		expected string
+	}{
+		{downcache.DefaultPermalink, post, nil, "/articles/guides/setup/install"},
+		{"/:year/:month/:day/:filename/", post, nil, "/2024/03/10/install/"},
+		{"/:year/:month/:day/:filename/", post, time.FixedZone("PST", -8*60*60), "/2024/03/09/install/"},
+		{"/:type/:section/:title", post, nil, "/articles/guides/setup/installing-downcache"},
+		{"/:taxonomy.categories/:filename", post, nil, "/how-to/install"},
+		{"/blog/:year-:month/:filename.html", post, nil, "/blog/2024-03/install.html"},
+		{"/:year/:month/:slug/", undated, nil, "/about/"},
+		{"/:type/:section/:title", undated, nil, "/pages/about"},
+		{"/:taxonomy.tags/:slug", undated, nil, "/about"},
+		{"/:unknown/:slug", undated, nil, "/:unknown/about"},
+		{":slug", undated, nil, "/about"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			assert.Equal(t, tc.expected, downcache.ExpandPermalink(tc.pattern, tc.post, tc.loc))
+		})
+	}
+}
+
+func TestDownCache_ResolvePermalink(t *testing.T) {
+	fs := NewInMemoryFileSystem()
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store, downcache.WithPermalink("articles", "/:year/:month/:slug/"))
+
+	ctx := context.Background()
+	published := sql.NullString{String: "2024-03-10", Valid: true}
+	_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: "hello", Published: published, Status: "published", Visibility: "public"})
+	_ = fs.Write(ctx, &downcache.Post{PostType: "articles", Slug: "draft", Published: published, Status: "draft", Visibility: "public"})
+	_ = fs.Write(ctx, &downcache.Post{PostType: "pages", Slug: "about", Status: "published", Visibility: "public"})
+	require.NoError(t, cm.SyncAll(ctx))
+
+	post, err := cm.ResolvePermalink(ctx, "/2024/03/hello/")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", post.Slug)
+	assert.Equal(t, "/2024/03/hello/", post.Permalink)
+
+	// The trailing slash is optional
+	post, err = cm.ResolvePermalink(ctx, "/2024/03/hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", post.Slug)
+
+	post, err = cm.ResolvePermalink(ctx, "/pages/about/")
+	require.NoError(t, err)
+	assert.Equal(t, "about", post.Slug)
+
+	// Drafts are only resolved for viewers who can see them
+	_, err = cm.ResolvePermalink(ctx, "/2024/03/draft/")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+
+	post, err = cm.ResolvePermalink(downcache.WithViewer(ctx, downcache.Viewer{Role: downcache.ViewerAdmin}), "/2024/03/draft/")
+	require.NoError(t, err)
+	assert.Equal(t, "draft", post.Slug)
+
+	_, err = cm.ResolvePermalink(ctx, "/2024/03/missing/")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+}
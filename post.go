@@ -10,46 +10,58 @@ import (
 
 // Post represents a Markdown post
 type Post struct {
-	ID                int64               `json:"id"`                // ID is the unique identifier for the post
-	PostID            string              `json:"post_id"`           // PostID is the unique identifier for the post (post type + slug)
-	Slug              string              `json:"slug"`              // Slug is the URL-friendly version of the name
-	PostType          string              `json:"postType"`          // PostType is the type of post (e.g. post, page)
-	Author            string              `json:"author"`            // Author is a list of author
-	Content           string              `json:"content"`           // Content is raw content of the post
-	HTML              string              `json:"html"`              // HTML is the HTML content of the post
-	ETag              string              `json:"etag"`              // ETag is the entity tag
-	EstimatedReadTime string              `json:"estimatedReadTime"` // EstimatedReadTime is the estimated reading time
-	Pinned            bool                `json:"pinned"`            // Pinned is true if the post is pinned
-	Photo             string              `json:"photo"`             // Photo is the URL of the featured image
-	FileTimePath      string              `json:"fileTimePath"`      // FileTimePath is the file time path in the format YYYY-MM-DD for the original file path
-	Name              string              `json:"name"`              // Name is the name/title of the post
-	Properties        map[string]string   `json:"properties"`        // Properties is a map of additional, arbitrary key-value pairs. This can be used to store additional metadata such as extra microformat properties.
-	Published         sql.NullString      `json:"published"`         // Published is the published date
-	Status            string              `json:"status"`            // Status is the status of the post (should be one of draft, published, or archived)
-	Subtitle          string              `json:"subtitle"`          // Subtitle is the subtitle
-	Summary           string              `json:"summary"`           // Summary is the summary
-	Taxonomies        map[string][]string `json:"taxonomies"`        // Taxonomies is a map of taxonomies (e.g. tags, categories)
-	Visibility        string              `json:"visibility"`        // Visibility is the visibility of the post (should be one of public, private, or unlisted)
-	Created           string              `json:"created"`           // Created is the creation date
-	Updated           string              `json:"updated"`           // Updated is the last modified date
-	publishedTime     time.Time           // publishedDate is the parsed published date
+	ID                int64               `json:"id"`                   // ID is the unique identifier for the post
+	PostID            string              `json:"post_id"`              // PostID is the unique identifier for the post (post type + slug)
+	Slug              string              `json:"slug"`                 // Slug is the URL-friendly version of the name
+	PostType          string              `json:"postType"`             // PostType is the type of post (e.g. post, page)
+	Author            string              `json:"author"`               // Author is a list of author
+	Content           string              `json:"content"`              // Content is raw content of the post
+	HTML              string              `json:"html"`                 // HTML is the HTML content of the post
+	ETag              string              `json:"etag"`                 // ETag is the entity tag
+	EstimatedReadTime string              `json:"estimatedReadTime"`    // EstimatedReadTime is the estimated reading time
+	Pinned            bool                `json:"pinned"`               // Pinned is true if the post is pinned
+	Weight            int                 `json:"weight,omitempty"`     // Weight orders the post among its siblings in a Tree, lightest first
+	Photo             string              `json:"photo"`                // Photo is the URL of the featured image
+	Assets            []Asset             `json:"assets,omitempty"`     // Assets are the files in the post's page bundle, if it is in one
+	Permalink         string              `json:"permalink,omitempty"`  // Permalink is the post's URL path, from its post type's permalink pattern (see WithPermalink)
+	Aliases           []string            `json:"aliases,omitempty"`    // Aliases are the post's previous URL paths, which redirect to its permalink (see ResolveAlias)
+	FileTimePath      string              `json:"fileTimePath"`         // FileTimePath is the file time path in the format YYYY-MM-DD for the original file path
+	Name              string              `json:"name"`                 // Name is the name/title of the post
+	Properties        map[string]any      `json:"properties"`           // Properties is a map of additional, arbitrary key-value pairs. This can be used to store additional metadata such as extra microformat properties. Values are normalized to a PropertyType.
+	Published         sql.NullString      `json:"published"`            // Published is the published date
+	Expires           sql.NullString      `json:"expires"`              // Expires is the date after which the post is no longer live
+	Status            string              `json:"status"`               // Status is the status of the post (should be one of draft, published, or archived)
+	Subtitle          string              `json:"subtitle"`             // Subtitle is the subtitle
+	Summary           string              `json:"summary"`              // Summary is the summary
+	Taxonomies        map[string][]string `json:"taxonomies"`           // Taxonomies is a map of taxonomies (e.g. tags, categories)
+	Visibility        string              `json:"visibility"`           // Visibility is the visibility of the post (should be one of public, private, or unlisted)
+	Created           string              `json:"created"`              // Created is the creation date, from the file system
+	Updated           string              `json:"updated"`              // Updated is the last modified date, from the frontmatter or the file system
+	Score             float64             `json:"score,omitempty"`      // Score is the relevance score of the post for a search query
+	Highlights        map[string]string   `json:"highlights,omitempty"` // Highlights is a map of field names (e.g. name, content) to highlighted snippets for a search query
 	pageID            string              // pageID is the unique identifier for the post
+	sourcePath        string              // sourcePath is the path of the post's file, relative to the root directory, if it was found by Walk
 }
 
 // PostMeta represents the frontmatter of a post
 type PostMeta struct {
 	Author     string              `yaml:"author,omitempty" toml:"author,omitempty"`
 	Pinned     bool                `yaml:"pinned,omitempty" toml:"pinned,omitempty"`
+	Weight     int                 `yaml:"weight,omitempty" toml:"weight,omitempty"`
 	Name       string              `yaml:"name,omitempty" toml:"name,omitempty"`
 	Photo      string              `yaml:"photo,omitempty" toml:"photo,omitempty"`
-	Properties map[string]string   `yaml:"properties,omitempty" toml:"properties,omitempty"`
-	Published  string              `yaml:"published,omitempty" toml:"published,omitempty"`
+	Properties map[string]any      `yaml:"properties,omitempty" toml:"properties,omitempty"`
+	Published  FrontmatterDate     `yaml:"published,omitempty" toml:"published,omitempty"`
+	Expires    FrontmatterDate     `yaml:"expires,omitempty" toml:"expires,omitempty"`
 	Status     string              `yaml:"status,omitempty" toml:"status,omitempty"`
 	Subtitle   string              `yaml:"subtitle,omitempty" toml:"subtitle,omitempty"`
 	Summary    string              `yaml:"summary,omitempty" toml:"summary,omitempty"`
 	Taxonomies map[string][]string `yaml:"taxonomies,omitempty" toml:"taxonomies,omitempty"`
-	// Updated    time.Time           `yaml:"updated,omitempty" toml:"updated,omitempty"`
-	Visibility string `yaml:"visibility,omitempty" toml:"visibility,omitempty"`
+	Visibility string              `yaml:"visibility,omitempty" toml:"visibility,omitempty"`
+	Aliases    []string            `yaml:"aliases,omitempty" toml:"aliases,omitempty"`
+	// Updated overrides the file's modification time as the post's last modified date. It isn't written by Meta,
+	// so writing a post leaves its modification time as its last modified date.
+	Updated FrontmatterDate `yaml:"updated,omitempty" toml:"updated,omitempty"`
 }
 
 func (dm *PostMeta) Validate() error {
@@ -85,56 +97,55 @@ func (p *Post) Meta() *PostMeta {
 	return &PostMeta{
 		Author:     p.Author,
 		Pinned:     p.Pinned,
+		Weight:     p.Weight,
 		Name:       p.Name,
 		Photo:      p.Photo,
 		Properties: p.Properties,
-		Published:  p.Published.String,
+		Published:  FrontmatterDate(p.Published.String),
+		Expires:    FrontmatterDate(p.Expires.String),
 		Status:     p.Status,
 		Subtitle:   p.Subtitle,
 		Summary:    p.Summary,
 		Taxonomies: p.Taxonomies,
 		Visibility: p.Visibility,
+		Aliases:    p.Aliases,
 	}
 }
 
+// SourcePath returns the path of the post's file relative to the root directory, such as articles/Hello World.md,
+// if the post was found by Walk.
+func (p *Post) SourcePath() string {
+	return p.sourcePath
+}
+
 // SlugWithoutDate returns the slug without a file time path (if it exists)
 func (p *Post) SlugWithoutDate() string {
 	if p.HasFileTimeInSlug() {
-		// Find the last slash in the file time path
-		lastSlash := strings.LastIndex(p.Slug, "/")
-
-		// Get the last part of the slug after the last slash
-		filePart := p.Slug[lastSlash+1:]
-
-		// Find the 2006-01-02 date in the file part
-		if hasFileTimeInSlug(filePart) {
-			// Return the first part of the file part before the date + the rest of the slug after the date
-			return p.Slug[:lastSlash] + "/" + filePart[11:]
-		}
+		return stripFileDate(p.Slug)
 	}
 	return p.Slug
 }
 
 // SlugWithYear returns the slug with the published year prepended as a directory (if it exists)
 func (p *Post) SlugWithYear() string {
-	if p.HasPublished() {
-		return fmt.Sprintf("%d/%s", p.publishedTime.Year(), p.SlugWithoutDate())
+	if published := p.PublishedTime(); !published.IsZero() {
+		return fmt.Sprintf("%d/%s", published.Year(), p.SlugWithoutDate())
 	}
 	return p.Slug
 }
 
 // SlugWithYearMonth returns the slug with the published year and month prepended as a directory (if it exists)
 func (p *Post) SlugWithYearMonth() string {
-	if p.HasPublished() {
-		return fmt.Sprintf("%d/%02d/%s", p.publishedTime.Year(), p.publishedTime.Month(), p.SlugWithoutDate())
+	if published := p.PublishedTime(); !published.IsZero() {
+		return fmt.Sprintf("%d/%02d/%s", published.Year(), published.Month(), p.SlugWithoutDate())
 	}
 	return p.Slug
 }
 
 // SlugWithYearMonthDay returns the slug with the published year, month, and day prepended as a directory (if it exists)
 func (p *Post) SlugWithYearMonthDay() string {
-	if p.HasPublished() {
-		return fmt.Sprintf("%d/%02d/%02d/%s", p.publishedTime.Year(), p.publishedTime.Month(), p.publishedTime.Day(), p.SlugWithoutDate())
+	if published := p.PublishedTime(); !published.IsZero() {
+		return fmt.Sprintf("%d/%02d/%02d/%s", published.Year(), published.Month(), published.Day(), p.SlugWithoutDate())
 	}
 	return p.Slug
 }
@@ -172,51 +183,75 @@ func (p *Post) FileTimeInSlug() string {
 	return ""
 }
 
-// HasPublished returns true if the post has a published date
+// HasPublished returns true if the post has a valid published date
 func (p *Post) HasPublished() bool {
-	if p.Published.Valid {
-		// Attempt to parse the published date
-		dt, err := time.Parse("2006-01-02", p.Published.String)
-		if err == nil {
-			dt, err = time.Parse("2006-01-02 15:04:05", p.Published.String)
-			if err == nil {
-				dt, err = time.Parse(time.RFC3339, p.Published.String)
-				if err != nil {
-					return false
-				}
-			}
-		}
-		p.publishedTime = dt
-		return !p.publishedTime.IsZero()
-	}
-
-	return false
+	return !p.PublishedTime().IsZero()
 }
 
-// PublishedTime returns the published date as a time.Time
+// PublishedTime returns the published date in UTC, or the zero time if the post doesn't have a valid one
 func (p *Post) PublishedTime() time.Time {
-	if p.HasPublished() {
-		return p.publishedTime
-	}
-	return time.Time{}
+	return parsePostDate(p.Published)
 }
 
-// PublishedDate returns the published date in the format Jan 2, 2006
+// PublishedDate returns the published date in the format Jan 2, 2006, in UTC
 func (p *Post) PublishedDate() string {
-	if !p.HasPublished() {
-		return ""
-	}
-
-	return p.publishedTime.Format("Jan 2, 2006")
+	return p.FormatPublished("Jan 2, 2006", time.UTC)
 }
 
-// PublishedYear returns the year of the published date
+// PublishedYear returns the year of the published date, in UTC
 func (p *Post) PublishedYear() int {
-	if !p.HasPublished() {
+	published := p.PublishedTime()
+	if published.IsZero() {
 		return 0
 	}
+	return published.Year()
+}
+
+// FormatPublished formats the published date in the location with the layout, or returns "" if the post doesn't
+// have a valid published date. Templates can use it to show dates in the site's time zone.
+func (p *Post) FormatPublished(layout string, loc *time.Location) string {
+	return formatDate(p.PublishedTime(), layout, loc)
+}
+
+// HasExpires returns true if the post has a valid expiry date
+func (p *Post) HasExpires() bool {
+	return !p.ExpiresTime().IsZero()
+}
+
+// ExpiresTime returns the expiry date in UTC, or the zero time if the post doesn't expire
+func (p *Post) ExpiresTime() time.Time {
+	return parsePostDate(p.Expires)
+}
+
+// IsScheduled returns true if the post has a published date after the given time
+func (p *Post) IsScheduled(at time.Time) bool {
+	published := parsePostDate(p.Published)
+	return !published.IsZero() && published.After(at)
+}
+
+// IsExpired returns true if the post has an expiry date on or before the given time
+func (p *Post) IsExpired(at time.Time) bool {
+	expires := p.ExpiresTime()
+	return !expires.IsZero() && !expires.After(at)
+}
+
+// IsLive returns true if the post is neither scheduled nor expired at the given time
+func (p *Post) IsLive(at time.Time) bool {
+	return !p.IsScheduled(at) && !p.IsExpired(at)
+}
+
+// parsePostDate parses a stored date, returning the zero time if it is missing or invalid. Dates without a time zone
+// are in UTC, as the dates of posts in the store have been normalized.
+func parsePostDate(value sql.NullString) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
 
-	return p.publishedTime.Year()
+	dt, err := ParseDate(value.String, time.UTC)
+	if err != nil {
+		return time.Time{}
+	}
+	return dt
 }
 
 // HasUpdated returns true if the post has a last modified date
@@ -224,6 +259,17 @@ func (p *Post) HasUpdated() bool {
 	return p.Updated != ""
 }
 
+// UpdatedTime returns the last modified date in UTC, or the zero time if the post doesn't have a valid one
+func (p *Post) UpdatedTime() time.Time {
+	return parsePostDate(sql.NullString{String: p.Updated, Valid: p.Updated != ""})
+}
+
+// FormatUpdated formats the last modified date in the location with the layout, or returns "" if the post doesn't
+// have a valid one.
+func (p *Post) FormatUpdated(layout string, loc *time.Location) string {
+	return formatDate(p.UpdatedTime(), layout, loc)
+}
+
 // HasAuthor returns true if the post has author
 func (p *Post) HasAuthor() bool {
 	return len(p.Author) > 0
@@ -22,7 +22,7 @@ type TestPost struct {
 	FileTimePath      *string              `json:"fileTimePath"`      // FileTimePath is the file time path in the format YYYY-MM-DD for the original file path
 	Updated           *string              `json:"updated"`           // Updated is the last modified date
 	Name              *string              `json:"name"`              // Name is the name/title of the post
-	Properties        *map[string]string   `json:"properties"`        // Properties is a map of additional, arbitrary key-value pairs. This can be used to store additional metadata such as extra microformat properties.
+	Properties        *map[string]any      `json:"properties"`        // Properties is a map of additional, arbitrary key-value pairs. This can be used to store additional metadata such as extra microformat properties.
 	Published         *string              `json:"published"`         // Published is the published date
 	Status            *string              `json:"status"`            // Status is the status of the post (should be one of draft, published, or archived)
 	Subtitle          *string              `json:"subtitle"`          // Subtitle is the subtitle
@@ -191,7 +191,7 @@ func TestPostMethods(t *testing.T) {
 		FileTimePath:      "",
 		Updated:           updatedTime,
 		Name:              "Test",
-		Properties:        map[string]string{},
+		Properties:        map[string]any{},
 		Published:         sql.NullString{String: publishedTime, Valid: true},
 		Status:            "published",
 		Subtitle:          "Subtitle test",
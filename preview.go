@@ -0,0 +1,147 @@
+package downcache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPreviewTTL is how long a preview token is valid for when no TTL is given.
+	DefaultPreviewTTL = 24 * time.Hour
+	// PreviewQueryParam is the query parameter PreviewMiddleware reads preview tokens from.
+	PreviewQueryParam = "preview"
+	// PreviewHeader is the header PreviewMiddleware reads preview tokens from.
+	PreviewHeader = "X-Preview-Token"
+)
+
+// PreviewClaims are the verified contents of a preview token.
+type PreviewClaims struct {
+	PostID  string    // The PostPathID of the post the token grants access to
+	Expires time.Time // When the token expires
+}
+
+// previewPayload is the signed part of a preview token.
+type previewPayload struct {
+	PostID  string `json:"p"`
+	Expires int64  `json:"e"`
+}
+
+// previewContextKey is the context key for verified PreviewClaims.
+type previewContextKey struct{}
+
+// WithPreviewSecret sets the secret used to sign and verify preview tokens. Preview tokens are disabled if the
+// secret is empty. Use at least 32 random bytes, and keep the secret the same across restarts so tokens stay valid.
+func WithPreviewSecret(secret []byte) Option {
+	return func(cm *DownCache) {
+		cm.previewSecret = secret
+	}
+}
+
+// PreviewToken returns a signed token that lets anyone who has it fetch the post with Get, whatever its status,
+// visibility or schedule, until the token expires. If ttl is zero or negative, DefaultPreviewTTL is used.
+func (cm *DownCache) PreviewToken(postType, slug string, ttl time.Duration) (string, error) {
+	if len(cm.previewSecret) == 0 {
+		return "", ErrPreviewDisabled
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultPreviewTTL
+	}
+
+	payload, err := json.Marshal(previewPayload{
+		PostID:  PostPathID(postType, slug),
+		Expires: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error encoding preview token: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + cm.signPreview(encoded), nil
+}
+
+// VerifyPreviewToken checks the token's signature and expiry, and returns its claims.
+func (cm *DownCache) VerifyPreviewToken(token string) (PreviewClaims, error) {
+	if len(cm.previewSecret) == 0 {
+		return PreviewClaims{}, ErrPreviewDisabled
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(cm.signPreview(encoded))) {
+		return PreviewClaims{}, fmt.Errorf("%w: bad signature", ErrInvalidPreviewToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return PreviewClaims{}, fmt.Errorf("%w: %w", ErrInvalidPreviewToken, err)
+	}
+
+	var payload previewPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return PreviewClaims{}, fmt.Errorf("%w: %w", ErrInvalidPreviewToken, err)
+	}
+
+	claims := PreviewClaims{PostID: payload.PostID, Expires: time.Unix(payload.Expires, 0).UTC()}
+	if !time.Now().Before(claims.Expires) {
+		return PreviewClaims{}, fmt.Errorf("%w: expired", ErrInvalidPreviewToken)
+	}
+
+	return claims, nil
+}
+
+// WithPreviewToken verifies the token and returns a copy of the context that lets Get fetch the token's post.
+func (cm *DownCache) WithPreviewToken(ctx context.Context, token string) (context.Context, error) {
+	claims, err := cm.VerifyPreviewToken(token)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, previewContextKey{}, claims), nil
+}
+
+// PreviewMiddleware verifies the preview token in the PreviewQueryParam query parameter or PreviewHeader header,
+// if there is one, and adds it to the request context so handlers can fetch the post with Get.
+// Requests with an invalid or expired token are rejected with 403 Forbidden.
+func (cm *DownCache) PreviewMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(PreviewQueryParam)
+		if token == "" {
+			token = r.Header.Get(PreviewHeader)
+		}
+
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, err := cm.WithPreviewToken(r.Context(), token)
+		if err != nil {
+			http.Error(w, "invalid or expired preview token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// previewAllowed returns true if the context has unexpired preview claims for the post.
+func (cm *DownCache) previewAllowed(ctx context.Context, postType, slug string) bool {
+	claims, ok := ctx.Value(previewContextKey{}).(PreviewClaims)
+	if !ok {
+		return false
+	}
+	return claims.PostID == PostPathID(postType, slug) && time.Now().Before(claims.Expires)
+}
+
+// signPreview returns the base64 encoded HMAC-SHA256 signature of the encoded payload.
+func (cm *DownCache) signPreview(encoded string) string {
+	mac := hmac.New(sha256.New, cm.previewSecret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package downcache_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func newPreviewCache(t *testing.T) *downcache.DownCache {
+	t.Helper()
+
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(NewInMemoryFileSystem(), store, downcache.WithPreviewSecret([]byte("0123456789abcdef0123456789abcdef")))
+
+	ctx := context.Background()
+	_, err := store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "draft", Name: "Draft", Status: "draft"})
+	require.NoError(t, err)
+	_, err = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "other-draft", Name: "Other draft", Status: "draft"})
+	require.NoError(t, err)
+
+	return cm
+}
+
+func TestDownCache_PreviewToken(t *testing.T) {
+	cm := newPreviewCache(t)
+	ctx := context.Background()
+
+	token, err := cm.PreviewToken("articles", "draft", time.Hour)
+	require.NoError(t, err)
+
+	claims, err := cm.VerifyPreviewToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "articles/draft", claims.PostID)
+
+	// Without the token the draft is hidden
+	_, err = cm.Get(ctx, "articles", "draft")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+
+	previewCtx, err := cm.WithPreviewToken(ctx, token)
+	require.NoError(t, err)
+
+	post, err := cm.Get(previewCtx, "articles", "draft")
+	require.NoError(t, err)
+	assert.Equal(t, "Draft", post.Name)
+
+	// The token only grants access to its own post
+	_, err = cm.Get(previewCtx, "articles", "other-draft")
+	assert.ErrorIs(t, err, downcache.ErrNotFound)
+
+	// Tampered, expired and foreign tokens are rejected
+	payload, signature, _ := strings.Cut(token, ".")
+	_, err = cm.VerifyPreviewToken(payload + "x." + signature)
+	assert.ErrorIs(t, err, downcache.ErrInvalidPreviewToken)
+
+	expired, err := cm.PreviewToken("articles", "draft", time.Nanosecond)
+	require.NoError(t, err)
+	_, err = cm.VerifyPreviewToken(expired)
+	assert.ErrorIs(t, err, downcache.ErrInvalidPreviewToken)
+
+	other := downcache.NewDownCache(NewInMemoryFileSystem(), downcache.NewMemoryCacheStore(), downcache.WithPreviewSecret([]byte("another secret")))
+	_, err = other.VerifyPreviewToken(token)
+	assert.ErrorIs(t, err, downcache.ErrInvalidPreviewToken)
+
+	// Tokens can't be created without a secret
+	_, err = downcache.NewDownCache(NewInMemoryFileSystem(), downcache.NewMemoryCacheStore()).PreviewToken("articles", "draft", time.Hour)
+	assert.ErrorIs(t, err, downcache.ErrPreviewDisabled)
+}
+
+func TestDownCache_PreviewMiddleware(t *testing.T) {
+	cm := newPreviewCache(t)
+
+	handler := cm.PreviewMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, err := cm.Get(r.Context(), "articles", "draft")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(post.Name))
+	}))
+
+	token, err := cm.PreviewToken("articles", "draft", time.Hour)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		target string
+		header string
+		status int
+	}{
+		{name: "No token", target: "/articles/draft", status: http.StatusNotFound},
+		{name: "Query token", target: "/articles/draft?preview=" + token, status: http.StatusOK},
+		{name: "Header token", target: "/articles/draft", header: token, status: http.StatusOK},
+		{name: "Invalid token", target: "/articles/draft?preview=invalid", status: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.header != "" {
+				req.Header.Set(downcache.PreviewHeader, tc.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package downcache
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyType is the type of a frontmatter property value.
+type PropertyType string
+
+const (
+	PropertyTypeString PropertyType = "string"
+	PropertyTypeNumber PropertyType = "number"
+	PropertyTypeBool   PropertyType = "bool"
+	PropertyTypeDate   PropertyType = "date"
+	PropertyTypeList   PropertyType = "list"
+)
+
+// FilterOp is a comparison operator used by a KeyValueFilter.
+type FilterOp string
+
+const (
+	FilterOpEq     FilterOp = "eq"     // The value is equal to the filter value. A list matches if any element is equal.
+	FilterOpNe     FilterOp = "ne"     // The value is not equal to the filter value. A list matches if no element is equal.
+	FilterOpGt     FilterOp = "gt"     // The value is greater than the filter value.
+	FilterOpGte    FilterOp = "gte"    // The value is greater than or equal to the filter value.
+	FilterOpLt     FilterOp = "lt"     // The value is less than the filter value.
+	FilterOpLte    FilterOp = "lte"    // The value is less than or equal to the filter value.
+	FilterOpIn     FilterOp = "in"     // The value is equal to one of the filter values. A list matches if any element is.
+	FilterOpExists FilterOp = "exists" // The property exists, regardless of its value.
+	FilterOpPrefix FilterOp = "prefix" // The string value starts with the filter value. A list matches if any element does.
+)
+
+// propertyDateLayouts are the layouts used to parse date filter values and date strings.
+var propertyDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// NormalizeProperties returns a copy of the properties with every value normalized by NormalizePropertyValue.
+func NormalizeProperties(props map[string]any) map[string]any {
+	if props == nil {
+		return nil
+	}
+
+	normalized := make(map[string]any, len(props))
+	for key, value := range props {
+		normalized[key] = NormalizePropertyValue(value)
+	}
+	return normalized
+}
+
+// NormalizePropertyValue converts a decoded frontmatter value into one of the supported property types:
+// string, float64, bool, time.Time (in UTC) or []any of those types. Any other value is converted to a string.
+func NormalizePropertyValue(value any) any {
+	switch v := value.(type) {
+	case string, bool, float64:
+		return v
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		// TOML local dates and times have no offset, so keep their wall clock as UTC
+		if strings.HasSuffix(v.Location().String(), "-local") {
+			return time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+		}
+		return v.UTC()
+	case []string:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, item)
+		}
+		return list
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, NormalizePropertyValue(item))
+		}
+		return list
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// PropertyTypeOf returns the type of a normalized property value.
+func PropertyTypeOf(value any) PropertyType {
+	switch value.(type) {
+	case float64:
+		return PropertyTypeNumber
+	case bool:
+		return PropertyTypeBool
+	case time.Time:
+		return PropertyTypeDate
+	case []any:
+		return PropertyTypeList
+	default:
+		return PropertyTypeString
+	}
+}
+
+// FormatPropertyValue returns the string form of a normalized scalar property value. Dates are formatted as RFC3339
+// in UTC.
+func FormatPropertyValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// ParsePropertyValue parses a filter value into the given property type. Lists are parsed as strings, since
+// list filters compare against each element.
+func ParsePropertyValue(value string, propType PropertyType) (any, error) {
+	value = strings.TrimSpace(value)
+	switch propType {
+	case PropertyTypeNumber:
+		return strconv.ParseFloat(value, 64)
+	case PropertyTypeBool:
+		// Only accept true and false, so values like 1 and 0 are inferred as numbers
+		switch strings.ToLower(value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid bool '%s'", value)
+	case PropertyTypeDate:
+		for _, layout := range propertyDateLayouts {
+			if dt, err := time.Parse(layout, value); err == nil {
+				return dt.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date '%s'", value)
+	default:
+		return value, nil
+	}
+}
+
+// InferPropertyType returns the most specific property type a filter value can be parsed as.
+func InferPropertyType(value string) PropertyType {
+	for _, propType := range []PropertyType{PropertyTypeBool, PropertyTypeNumber, PropertyTypeDate} {
+		if _, err := ParsePropertyValue(value, propType); err == nil {
+			return propType
+		}
+	}
+	return PropertyTypeString
+}
+
+// Operator returns the filter's operator, defaulting to FilterOpEq.
+func (kv KeyValueFilter) Operator() FilterOp {
+	if kv.Op == "" {
+		return FilterOpEq
+	}
+	return kv.Op
+}
+
+// FilterValues returns the values to compare against. For FilterOpIn this is Values, falling back to Value.
+func (kv KeyValueFilter) FilterValues() []string {
+	if kv.Operator() == FilterOpIn && len(kv.Values) > 0 {
+		return kv.Values
+	}
+	return []string{kv.Value}
+}
+
+// MatchProperty returns true if the property value matches the filter. The filter value is parsed into the type of
+// the property, and the filter doesn't match if that fails. The exists argument reports whether the property is set.
+//
+// FilterOpNe is the negation of FilterOpEq, so it also matches posts without the property.
+func (kv KeyValueFilter) MatchProperty(value any, exists bool) bool {
+	switch kv.Operator() {
+	case FilterOpExists:
+		return exists
+	case FilterOpNe:
+		eq := kv
+		eq.Op = FilterOpEq
+		return !eq.MatchProperty(value, exists)
+	}
+
+	if !exists {
+		return false
+	}
+
+	value = NormalizePropertyValue(value)
+	if list, ok := value.([]any); ok {
+		return slices.ContainsFunc(list, kv.matchScalar)
+	}
+
+	return kv.matchScalar(value)
+}
+
+// matchScalar compares a single normalized value against the filter.
+func (kv KeyValueFilter) matchScalar(value any) bool {
+	op := kv.Operator()
+	propType := PropertyTypeOf(value)
+
+	if op == FilterOpPrefix {
+		return strings.HasPrefix(FormatPropertyValue(value), kv.Value)
+	}
+
+	for _, filterValue := range kv.FilterValues() {
+		parsed, err := ParsePropertyValue(filterValue, propType)
+		if err != nil {
+			continue
+		}
+
+		cmp := compareProperty(value, parsed)
+		var matched bool
+		switch op {
+		case FilterOpEq, FilterOpIn:
+			matched = cmp == 0
+		case FilterOpGt:
+			matched = cmp > 0
+		case FilterOpGte:
+			matched = cmp >= 0
+		case FilterOpLt:
+			matched = cmp < 0
+		case FilterOpLte:
+			matched = cmp <= 0
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compareProperty compares two normalized values of the same type.
+func compareProperty(a, b any) int {
+	switch av := a.(type) {
+	case float64:
+		return compareFloat(av, b.(float64))
+	case bool:
+		return compareBool(av, b.(bool))
+	case time.Time:
+		return compareTime(av, b.(time.Time))
+	default:
+		return strings.Compare(FormatPropertyValue(a), FormatPropertyValue(b))
+	}
+}
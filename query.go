@@ -0,0 +1,349 @@
+package downcache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TextTerm is a single word or exact phrase in a full-text query.
+type TextTerm struct {
+	Value  string // The word or phrase, without quotes
+	Phrase bool   // True if the term is an exact phrase
+	Negate bool   // True if the term must not match
+}
+
+// TextClause is a list of terms that must all match.
+type TextClause []TextTerm
+
+// TextQuery is a backend-neutral full-text expression. A post matches when any of its clauses match.
+// For example, `go "exact phrase" -draft OR rust` has two clauses: [go, "exact phrase", -draft] and [rust].
+type TextQuery []TextClause
+
+// queryQualifiers maps the supported query qualifiers to the taxonomy they filter on. Qualifiers with an
+// empty taxonomy are handled individually by ParseQuery.
+var queryQualifiers = map[string]string{
+	"tag":        "tags",
+	"tags":       "tags",
+	"category":   "categories",
+	"categories": "categories",
+	"author":     "",
+	"type":       "",
+	"status":     "",
+	"visibility": "",
+	"after":      "",
+	"before":     "",
+}
+
+// queryDateLayouts are the layouts accepted by the after: and before: qualifiers.
+var queryDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// queryToken is a single token in a query string.
+type queryToken struct {
+	key    string // The qualifier key, if the token is a qualifier (e.g. "tag" in tag:go)
+	value  string
+	phrase bool
+	negate bool
+	or     bool
+}
+
+// ParseQuery parses a GitHub-style search query into FilterOptions.
+//
+// The following qualifiers are supported:
+//   - tag:go or category:news filter by taxonomy term. Separate terms with commas (tag:go,golang) to match posts
+//     with any of the terms. Prefix with a minus sign or NOT (-tag:draft or NOT tag:draft) to exclude terms.
+//   - author:bob filters by author.
+//   - type:articles filters by post type.
+//   - status:draft and visibility:private filter by status and visibility.
+//   - after:2024-01-01 and before:2024-02-01 filter by published date. Dates can be YYYY-MM-DD or RFC3339.
+//
+// Qualifier values can be quoted, e.g. author:"Jane Smith". Everything else is parsed as a full-text expression
+// (see ParseTextQuery), which is returned in FilterText and, in normalized form, in FilterSearch.
+// Unknown qualifiers are treated as full-text words.
+func ParseQuery(input string) (FilterOptions, error) {
+	opts := FilterOptions{FilterPostType: PostTypeKeyAny}
+
+	var text []queryToken
+	for _, tok := range tokenizeQuery(input) {
+		key := strings.ToLower(tok.key)
+		taxonomy, ok := queryQualifiers[key]
+		if !ok {
+			text = append(text, tok)
+			continue
+		}
+
+		if tok.value == "" {
+			return FilterOptions{}, fmt.Errorf("%w: qualifier '%s' has no value", ErrInvalidQuery, key)
+		}
+
+		if taxonomy != "" {
+			terms := strings.Split(tok.value, ",")
+			if len(terms) > 1 {
+				group := TaxonomyFilterGroup{Negate: tok.negate}
+				for _, term := range terms {
+					if term = strings.TrimSpace(term); term != "" {
+						group.Terms = append(group.Terms, KeyValueFilter{Key: taxonomy, Value: term})
+					}
+				}
+				opts.FilterTaxonomyGroups = append(opts.FilterTaxonomyGroups, group)
+				continue
+			}
+
+			filter := KeyValueFilter{Key: taxonomy, Value: tok.value}
+			if tok.negate {
+				opts.FilterExcludeTaxonomies = append(opts.FilterExcludeTaxonomies, filter)
+			} else {
+				opts.FilterTaxonomies = append(opts.FilterTaxonomies, filter)
+			}
+			continue
+		}
+
+		if tok.negate {
+			return FilterOptions{}, fmt.Errorf("%w: qualifier '%s' can't be excluded", ErrInvalidQuery, key)
+		}
+
+		switch key {
+		case "author":
+			opts.FilterAuthor = tok.value
+		case "type":
+			opts.FilterPostType = PostType(tok.value)
+		case "status":
+			opts.FilterStatus = tok.value
+		case "visibility":
+			opts.FilterVisibility = tok.value
+		case "after", "before":
+			dt, err := parseQueryDate(tok.value)
+			if err != nil {
+				return FilterOptions{}, fmt.Errorf("%w: %s: %w", ErrInvalidQuery, key, err)
+			}
+			if key == "after" {
+				opts.FilterPublishedAfter = dt
+			} else {
+				opts.FilterPublishedBefore = dt
+			}
+		}
+	}
+
+	textQuery := buildTextQuery(text)
+	for _, clause := range textQuery {
+		if !clause.hasPositive() {
+			return FilterOptions{}, fmt.Errorf("%w: a clause can't contain only excluded terms", ErrInvalidQuery)
+		}
+	}
+
+	opts.FilterText = textQuery
+	opts.FilterSearch = textQuery.String()
+
+	return opts, nil
+}
+
+// ParseTextQuery parses a full-text expression. Words and "quoted phrases" in a clause must all match, a leading
+// minus sign or NOT excludes a word or phrase, and OR separates alternative clauses. Qualifiers are not recognized and are
+// treated as plain words. Clauses that only contain excluded terms are ignored.
+func ParseTextQuery(input string) TextQuery {
+	textQuery := buildTextQuery(tokenizeQuery(input))
+
+	valid := make(TextQuery, 0, len(textQuery))
+	for _, clause := range textQuery {
+		if clause.hasPositive() {
+			valid = append(valid, clause)
+		}
+	}
+
+	return valid
+}
+
+// IsEmpty returns true if the query has no terms.
+func (tq TextQuery) IsEmpty() bool {
+	return len(tq) == 0
+}
+
+// String returns the normalized form of the query.
+func (tq TextQuery) String() string {
+	clauses := make([]string, 0, len(tq))
+	for _, clause := range tq {
+		terms := make([]string, 0, len(clause))
+		for _, term := range clause {
+			value := term.Value
+			if term.Phrase {
+				value = `"` + value + `"`
+			}
+			if term.Negate {
+				value = "-" + value
+			}
+			terms = append(terms, value)
+		}
+		clauses = append(clauses, strings.Join(terms, " "))
+	}
+	return strings.Join(clauses, " OR ")
+}
+
+// Words returns the unique values of the terms that must match, in the order they appear. This is useful for
+// highlighting matches.
+func (tq TextQuery) Words() []string {
+	var words []string
+	for _, clause := range tq {
+		for _, term := range clause {
+			if !term.Negate {
+				words = append(words, term.Value)
+			}
+		}
+	}
+	return unique(words)
+}
+
+// Match returns true if the text matches the query. Terms are matched as case-insensitive substrings.
+func (tq TextQuery) Match(text string) bool {
+	if tq.IsEmpty() {
+		return true
+	}
+
+	text = strings.ToLower(text)
+	for _, clause := range tq {
+		if clause.match(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns true if all the terms in the clause match the lowercase text.
+func (tc TextClause) match(text string) bool {
+	for _, term := range tc {
+		if strings.Contains(text, strings.ToLower(term.Value)) == term.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPositive returns true if the clause has at least one term that must match.
+func (tc TextClause) hasPositive() bool {
+	for _, term := range tc {
+		if !term.Negate {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTextQuery groups the tokens into clauses separated by OR.
+func buildTextQuery(tokens []queryToken) TextQuery {
+	var textQuery TextQuery
+	var clause TextClause
+	for _, tok := range tokens {
+		if tok.or {
+			if len(clause) > 0 {
+				textQuery = append(textQuery, clause)
+			}
+			clause = nil
+			continue
+		}
+		// Qualifiers are plain words in a full-text expression
+		value := tok.value
+		if tok.key != "" {
+			value = tok.key + ":" + value
+		}
+		clause = append(clause, TextTerm{Value: value, Phrase: tok.phrase, Negate: tok.negate})
+	}
+
+	if len(clause) > 0 {
+		textQuery = append(textQuery, clause)
+	}
+
+	return textQuery
+}
+
+// tokenizeQuery splits a query into tokens, respecting quotes. Unterminated quotes run to the end of the input.
+func tokenizeQuery(input string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(input)
+	negateNext := false
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := queryToken{negate: negateNext}
+		negateNext = false
+		if runes[i] == '-' {
+			tok.negate = true
+			i++
+		}
+
+		// Read a word, which may be a qualifier key
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' && runes[i] != ':' {
+			i++
+		}
+		word := string(runes[start:i])
+
+		// A colon without a key before it separates nothing, so skip it
+		if word == "" && i < len(runes) && runes[i] == ':' {
+			negateNext = tok.negate
+			i++
+			continue
+		}
+
+		if i < len(runes) && runes[i] == ':' && word != "" {
+			tok.key = word
+			i++
+			start = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				i++
+			}
+			word = string(runes[start:i])
+		}
+
+		// Read a quoted value
+		if word == "" && i < len(runes) && runes[i] == '"' {
+			i++
+			start = i
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			word = string(runes[start:i])
+			tok.phrase = tok.key == ""
+			if i < len(runes) {
+				i++
+			}
+		}
+
+		word = strings.TrimSpace(word)
+		if word == "" && tok.key == "" {
+			continue
+		}
+
+		if tok.key == "" && !tok.negate && !tok.phrase {
+			switch word {
+			case "OR":
+				tok.or = true
+			case "NOT":
+				// NOT negates the next token, like a leading minus sign
+				negateNext = true
+				continue
+			}
+		}
+
+		tok.value = word
+		tokens = append(tokens, tok)
+	}
+
+	return tokens
+}
+
+// parseQueryDate parses a date in one of the queryDateLayouts.
+func parseQueryDate(value string) (time.Time, error) {
+	for _, layout := range queryDateLayouts {
+		if dt, err := time.Parse(layout, value); err == nil {
+			return dt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
@@ -0,0 +1,159 @@
+package downcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected downcache.FilterOptions
+	}{
+		{
+			name:  "Empty",
+			input: "",
+			expected: downcache.FilterOptions{
+				FilterPostType: downcache.PostTypeKeyAny,
+			},
+		},
+		{
+			name:  "Qualifiers",
+			input: `tag:go author:bob type:articles after:2024-01-01 -tag:draft`,
+			expected: downcache.FilterOptions{
+				FilterAuthor:            "bob",
+				FilterPostType:          downcache.PostTypeKeyArticle,
+				FilterTaxonomies:        []downcache.KeyValueFilter{{Key: "tags", Value: "go"}},
+				FilterExcludeTaxonomies: []downcache.KeyValueFilter{{Key: "tags", Value: "draft"}},
+				FilterPublishedAfter:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "Quoted qualifier",
+			input: `author:"Jane Smith" category:news`,
+			expected: downcache.FilterOptions{
+				FilterAuthor:     "Jane Smith",
+				FilterPostType:   downcache.PostTypeKeyAny,
+				FilterTaxonomies: []downcache.KeyValueFilter{{Key: "categories", Value: "news"}},
+			},
+		},
+		{
+			name:  "Full-text with qualifiers",
+			input: `golang "exact phrase" -rust OR gopher status:draft`,
+			expected: downcache.FilterOptions{
+				FilterPostType: downcache.PostTypeKeyAny,
+				FilterStatus:   "draft",
+				FilterSearch:   `golang "exact phrase" -rust OR gopher`,
+				FilterText: downcache.TextQuery{
+					{
+						{Value: "golang"},
+						{Value: "exact phrase", Phrase: true},
+						{Value: "rust", Negate: true},
+					},
+					{
+						{Value: "gopher"},
+					},
+				},
+			},
+		},
+		{
+			name:  "Unknown qualifier is text",
+			input: `foo:bar`,
+			expected: downcache.FilterOptions{
+				FilterPostType: downcache.PostTypeKeyAny,
+				FilterSearch:   `foo:bar`,
+				FilterText:     downcache.TextQuery{{{Value: "foo:bar"}}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := downcache.ParseQuery(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, opts)
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{name: "Invalid date", input: "after:yesterday"},
+		{name: "Missing value", input: "tag:"},
+		{name: "Excluded author", input: "-author:bob"},
+		{name: "Only excluded terms", input: "-draft"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := downcache.ParseQuery(tc.input)
+			assert.ErrorIs(t, err, downcache.ErrInvalidQuery)
+		})
+	}
+}
+
+func TestParseTextQuery_Colons(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected downcache.TextQuery
+	}{
+		{input: ":", expected: downcache.TextQuery{}},
+		{input: "a :b", expected: downcache.TextQuery{{{Value: "a"}, {Value: "b"}}}},
+		{input: "foo : bar", expected: downcache.TextQuery{{{Value: "foo"}, {Value: "bar"}}}},
+		{input: "foo -:bar", expected: downcache.TextQuery{{{Value: "foo"}, {Value: "bar", Negate: true}}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			assert.Equal(t, tc.expected, downcache.ParseTextQuery(tc.input))
+		})
+	}
+
+	opts, err := downcache.ParseQuery("foo : bar")
+	require.NoError(t, err)
+	assert.Equal(t, downcache.TextQuery{{{Value: "foo"}, {Value: "bar"}}}, opts.FilterText)
+}
+
+func TestTextQuery_Match(t *testing.T) {
+	text := "Gophers love Go. They do not love Rust as much."
+
+	cases := []struct {
+		query    string
+		expected bool
+	}{
+		{query: "gophers", expected: true},
+		{query: "gophers rust", expected: true},
+		{query: "gophers -rust", expected: false},
+		{query: `"love go"`, expected: true},
+		{query: `"love gophers"`, expected: false},
+		{query: "python OR rust", expected: true},
+		{query: "python OR java", expected: false},
+		{query: `"unterminated phrase`, expected: false},
+		{query: "-rust", expected: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			assert.Equal(t, tc.expected, downcache.ParseTextQuery(tc.query).Match(text))
+		})
+	}
+}
+
+func TestParseQuery_TaxonomyGroups(t *testing.T) {
+	opts, err := downcache.ParseQuery(`tag:go,golang NOT tag:archived-series`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []downcache.TaxonomyFilterGroup{
+		{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "archived-series"}}, Negate: true},
+		{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "go"}, {Key: "tags", Value: "golang"}}},
+	}, opts.TaxonomyGroups())
+}
@@ -0,0 +1,228 @@
+package downcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRevisionsDir is the hidden directory under the content root that revisions are conventionally kept in.
+const DefaultRevisionsDir = ".revisions"
+
+// revisionIDFormat formats revision IDs, which sort in the order the revisions were made.
+const revisionIDFormat = "20060102T150405.000000000Z"
+
+// RevisionOp is the write that replaced the version of a post saved in a revision.
+type RevisionOp string
+
+const (
+	RevisionOpUpdate  RevisionOp = "update"  // The post was updated
+	RevisionOpDelete  RevisionOp = "delete"  // The post was deleted
+	RevisionOpRestore RevisionOp = "restore" // The post was restored from another revision
+)
+
+// Revision is a previous version of a post, saved before it was updated or deleted.
+type Revision struct {
+	ID       string     `json:"id"` // Assigned by the RevisionStore. IDs sort in the order the revisions were made.
+	PostType string     `json:"postType"`
+	Slug     string     `json:"slug"`
+	Op       RevisionOp `json:"op"`
+	Created  time.Time  `json:"created"` // When the version was replaced
+	Post     *Post      `json:"post"`    // The version of the post, as read from the filesystem
+}
+
+// RevisionStore saves previous versions of posts.
+type RevisionStore interface {
+	// Save saves the revision, setting its ID.
+	Save(ctx context.Context, rev *Revision) error
+	// List returns the revisions of a post, newest first.
+	List(ctx context.Context, postType, slug string) ([]*Revision, error)
+	// Get returns a revision of a post, or ErrRevisionNotFound.
+	Get(ctx context.Context, postType, slug, id string) (*Revision, error)
+}
+
+// WithRevisions saves the previous version of a post to the revision store every time it is updated or deleted.
+func WithRevisions(store RevisionStore) Option {
+	return func(cm *DownCache) {
+		cm.revisions = store
+	}
+}
+
+// Revisions returns the previous versions of a post, newest first.
+func (cm *DownCache) Revisions(ctx context.Context, postType, slug string) ([]*Revision, error) {
+	if cm.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return cm.revisions.List(ctx, postType, slug)
+}
+
+// GetRevision returns a previous version of a post.
+func (cm *DownCache) GetRevision(ctx context.Context, postType, slug, id string) (*Revision, error) {
+	if cm.revisions == nil {
+		return nil, ErrRevisionsDisabled
+	}
+	return cm.revisions.Get(ctx, postType, slug, id)
+}
+
+// RestoreRevision makes a previous version of a post the current version, recreating the post if it was deleted.
+// The version it replaces is saved as a new revision, so a restore can be undone.
+func (cm *DownCache) RestoreRevision(ctx context.Context, postType, slug, id string) (*Post, error) {
+	rev, err := cm.GetRevision(ctx, postType, slug, id)
+	if err != nil {
+		return nil, err
+	}
+
+	post := *rev.Post
+	post.PostType = postType
+	post.Slug = slug
+
+	if _, err := cm.fs.Read(ctx, postType, slug); err != nil {
+		return cm.Create(ctx, &post)
+	}
+
+	if err := cm.update(ctx, postType, slug, "", &post, RevisionOpRestore); err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// saveRevision saves the version of a post that a write has replaced, if revisions are enabled.
+func (cm *DownCache) saveRevision(ctx context.Context, previous *Post, op RevisionOp) error {
+	if cm.revisions == nil || previous == nil {
+		return nil
+	}
+
+	rev := &Revision{
+		PostType: previous.PostType,
+		Slug:     previous.Slug,
+		Op:       op,
+		Created:  time.Now().UTC(),
+		Post:     previous,
+	}
+
+	if err := cm.revisions.Save(ctx, rev); err != nil {
+		return fmt.Errorf("error saving revision: %w", err)
+	}
+
+	return nil
+}
+
+// LocalRevisionStore implements RevisionStore with a JSON file per revision, in a directory per post.
+type LocalRevisionStore struct {
+	rootDir string
+	mu      sync.Mutex
+}
+
+// NewLocalRevisionStore returns a LocalRevisionStore that keeps revisions in rootDir, such as DefaultRevisionsDir
+// under the content root. Revisions are JSON files, so they aren't mistaken for posts.
+func NewLocalRevisionStore(rootDir string) *LocalRevisionStore {
+	return &LocalRevisionStore{rootDir: rootDir}
+}
+
+func (s *LocalRevisionStore) Save(_ context.Context, rev *Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir, err := s.buildDir(rev.PostType, rev.Slug)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Revisions made in the same instant get the next free ID
+	created := rev.Created.UTC()
+	for {
+		rev.ID = created.Format(revisionIDFormat)
+		if _, err := os.Stat(filepath.Join(dir, rev.ID+".json")); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		created = created.Add(time.Nanosecond)
+	}
+
+	data, err := json.MarshalIndent(rev, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding revision: %w", err)
+	}
+
+	return writeFileAtomic(filepath.Join(dir, rev.ID+".json"), data, 0o644)
+}
+
+func (s *LocalRevisionStore) List(ctx context.Context, postType, slug string) ([]*Revision, error) {
+	dir, err := s.buildDir(postType, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	revisions := make([]*Revision, 0, len(ids))
+	for _, id := range ids {
+		rev, err := s.Get(ctx, postType, slug, id)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, nil
+}
+
+func (s *LocalRevisionStore) Get(_ context.Context, postType, slug, id string) (*Revision, error) {
+	if _, err := time.Parse(revisionIDFormat, id); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRevisionNotFound, id)
+	}
+
+	dir, err := s.buildDir(postType, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRevisionNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rev Revision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return nil, fmt.Errorf("error decoding revision %s: %w", id, err)
+	}
+
+	return &rev, nil
+}
+
+// buildDir returns the directory of a post's revisions. Post types and slugs with empty, "." or ".." elements are
+// rejected, as asset paths are, so a revision can't be read or written outside rootDir.
+func (s *LocalRevisionStore) buildDir(postType, slug string) (string, error) {
+	if !fs.ValidPath(postType) || !fs.ValidPath(slug) || postType == "." || slug == "." {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPostPath, PostPathID(postType, slug))
+	}
+	return filepath.Join(s.rootDir, filepath.FromSlash(postType), filepath.FromSlash(slug)), nil
+}
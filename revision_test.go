@@ -0,0 +1,167 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestDownCache_Revisions(t *testing.T) {
+	dir := t.TempDir()
+	fs := downcache.NewLocalMarkdownFS(dir, &downcache.DefaultMarkdownProcessor{}, downcache.FrontmatterYAML)
+	revisions := downcache.NewLocalRevisionStore(filepath.Join(dir, downcache.DefaultRevisionsDir))
+	cm := downcache.NewDownCache(fs, downcache.NewMemoryCacheStore(), downcache.WithRevisions(revisions))
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "First", Content: "Line one\nLine two\n"})
+	require.NoError(t, err)
+
+	revs, err := cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Empty(t, revs)
+
+	// Updating saves the previous version
+	err = cm.Update(ctx, "articles", "post", &downcache.Post{PostType: "articles", Slug: "post", Name: "Second", Status: "draft", Content: "Line one\nLine 2\n"})
+	require.NoError(t, err)
+
+	revs, err = cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	first := revs[0]
+	assert.Equal(t, downcache.RevisionOpUpdate, first.Op)
+	assert.Equal(t, "First", first.Post.Name)
+
+	rev, err := cm.GetRevision(ctx, "articles", "post", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Post.Content, rev.Post.Content)
+
+	// Diff the first version with the current one
+	diff, err := cm.Diff(ctx, "articles", "post", first.ID, downcache.RevisionCurrent)
+	require.NoError(t, err)
+	assert.True(t, diff.HasChanges())
+	assert.Equal(t, []downcache.FieldChange{
+		{Field: "name", From: "First", To: "Second"},
+		{Field: "status", From: "published", To: "draft"},
+	}, diff.Fields)
+	assert.Equal(t, []downcache.DiffLine{
+		{Op: downcache.DiffEqual, Text: "Line one"},
+		{Op: downcache.DiffDelete, Text: "Line two"},
+		{Op: downcache.DiffInsert, Text: "Line 2"},
+	}, diff.Lines)
+
+	// Restoring brings back the first version, and saves the one it replaced
+	restored, err := cm.RestoreRevision(ctx, "articles", "post", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", restored.Name)
+
+	current, err := fs.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "First", current.Name)
+
+	diff, err = cm.Diff(ctx, "articles", "post", first.ID, downcache.RevisionCurrent)
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges(), diff.String())
+
+	revs, err = cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	require.Len(t, revs, 2)
+	assert.Equal(t, downcache.RevisionOpRestore, revs[0].Op)
+	assert.Equal(t, "Second", revs[0].Post.Name)
+
+	// Deleted posts can be restored too
+	require.NoError(t, cm.Delete(ctx, "articles", "post"))
+	revs, err = cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	assert.Equal(t, downcache.RevisionOpDelete, revs[0].Op)
+
+	_, err = cm.RestoreRevision(ctx, "articles", "post", revs[0].ID)
+	require.NoError(t, err)
+	current, err = fs.Read(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Equal(t, "First", current.Name)
+
+	// Revisions aren't mistaken for posts
+	issues, err := cm.Verify(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	_, err = cm.GetRevision(ctx, "articles", "post", "../post")
+	assert.ErrorIs(t, err, downcache.ErrRevisionNotFound)
+}
+
+func TestDownCache_RevisionsRollback(t *testing.T) {
+	dir := t.TempDir()
+	store := &failingStore{MemoryCacheStore: downcache.NewMemoryCacheStore()}
+	revisions := downcache.NewLocalRevisionStore(filepath.Join(dir, downcache.DefaultRevisionsDir))
+	cm := downcache.NewDownCache(NewInMemoryFileSystem(), store, downcache.WithRevisions(revisions))
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "post", Name: "First"})
+	require.NoError(t, err)
+
+	// Failed updates and deletes are rolled back, so they don't save revisions
+	store.failUpdate = true
+	require.Error(t, cm.Update(ctx, "articles", "post", &downcache.Post{PostType: "articles", Slug: "post", Name: "Second"}))
+	store.failDelete = true
+	require.Error(t, cm.Delete(ctx, "articles", "post"))
+
+	revs, err := cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	assert.Empty(t, revs)
+
+	store.failDelete = false
+	require.NoError(t, cm.Delete(ctx, "articles", "post"))
+	revs, err = cm.Revisions(ctx, "articles", "post")
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, downcache.RevisionOpDelete, revs[0].Op)
+}
+
+func TestDownCache_RevisionsDisabled(t *testing.T) {
+	cm := downcache.NewDownCache(NewInMemoryFileSystem(), downcache.NewMemoryCacheStore())
+
+	_, err := cm.Revisions(context.Background(), "articles", "post")
+	assert.ErrorIs(t, err, downcache.ErrRevisionsDisabled)
+}
+
+func TestLocalRevisionStore_InvalidPath(t *testing.T) {
+	dir := t.TempDir()
+	revisions := downcache.NewLocalRevisionStore(filepath.Join(dir, downcache.DefaultRevisionsDir))
+
+	ctx := context.Background()
+	for _, path := range [][2]string{
+		{"..", "escape"},
+		{"articles", "../../escape"},
+		{"articles", "/escape"},
+		{"articles", "."},
+		{"", "post"},
+	} {
+		err := revisions.Save(ctx, &downcache.Revision{PostType: path[0], Slug: path[1], Created: time.Now()})
+		assert.ErrorIs(t, err, downcache.ErrInvalidPostPath, path)
+
+		_, err = revisions.List(ctx, path[0], path[1])
+		assert.ErrorIs(t, err, downcache.ErrInvalidPostPath, path)
+
+		_, err = revisions.Get(ctx, path[0], path[1], "20240101T000000.000000000Z")
+		assert.ErrorIs(t, err, downcache.ErrInvalidPostPath, path)
+	}
+
+	// Nothing was written outside the revisions directory
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Slugs of posts in sections are nested
+	require.NoError(t, revisions.Save(ctx, &downcache.Revision{PostType: "docs", Slug: "guide/intro", Created: time.Now()}))
+	list, err := revisions.List(ctx, "docs", "guide/intro")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
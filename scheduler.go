@@ -0,0 +1,135 @@
+package downcache
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// DefaultSchedulerInterval is how often a Scheduler checks for posts going live or expiring by default.
+const DefaultSchedulerInterval = time.Minute
+
+// ScheduleEventType is the kind of change reported by a ScheduleEvent.
+type ScheduleEventType string
+
+const (
+	ScheduleEventLive    ScheduleEventType = "live"    // A scheduled post's published date has passed
+	ScheduleEventExpired ScheduleEventType = "expired" // A post's expiry date has passed
+)
+
+// ScheduleEvent reports a post going live or expiring.
+type ScheduleEvent struct {
+	Type ScheduleEventType
+	Post *Post
+	At   time.Time // The published or expiry date that passed
+}
+
+// Scheduler reports published posts as their scheduled published dates and expiry dates pass, so caches and
+// feeds can be refreshed.
+type Scheduler struct {
+	cache    *DownCache
+	interval time.Duration
+}
+
+// Scheduler returns a Scheduler that checks for posts going live or expiring at the given interval.
+// If interval is zero or negative, DefaultSchedulerInterval is used.
+func (cm *DownCache) Scheduler(interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+
+	return &Scheduler{
+		cache:    cm,
+		interval: interval,
+	}
+}
+
+// Run checks for events every interval until the context is cancelled, starting from the time Run is called.
+// Both channels are closed when the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) (<-chan ScheduleEvent, <-chan error) {
+	events := make(chan ScheduleEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		from := time.Now().UTC()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			to := time.Now().UTC()
+			due, err := s.Check(ctx, from, to)
+			if err != nil {
+				// Report the error and try the same window again on the next tick
+				select {
+				case errs <- err:
+				default:
+				}
+				continue
+			}
+
+			for _, event := range due {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			from = to
+		}
+	}()
+
+	return events, errs
+}
+
+// Check returns the events for published posts that went live or expired from the from time (inclusive) to the
+// to time (exclusive), ordered by when they happened.
+func (s *Scheduler) Check(ctx context.Context, from, to time.Time) ([]ScheduleEvent, error) {
+	var events []ScheduleEvent
+
+	// Posts that went live, unless they had already expired by then
+	live, err := s.search(ctx, FilterOptions{FilterPublishedAfter: from, FilterPublishedBefore: to})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range live {
+		at := parsePostDate(post.Published)
+		if !post.IsExpired(at) {
+			events = append(events, ScheduleEvent{Type: ScheduleEventLive, Post: post, At: at})
+		}
+	}
+
+	// Posts that expired, unless they were never live
+	expired, err := s.search(ctx, FilterOptions{FilterExpiresAfter: from, FilterExpiresBefore: to})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range expired {
+		at := post.ExpiresTime()
+		if !post.IsScheduled(at) {
+			events = append(events, ScheduleEvent{Type: ScheduleEventExpired, Post: post, At: at})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+
+	return events, nil
+}
+
+// search returns every published post, whatever its visibility, matching the filter options.
+func (s *Scheduler) search(ctx context.Context, filter FilterOptions) ([]*Post, error) {
+	filter.FilterStatus = "published"
+	return s.cache.searchAll(ctx, filter)
+}
@@ -0,0 +1,140 @@
+package downcache
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosimple/slug"
+)
+
+// Slugifier makes the slug of a file or directory name, or of a post's name.
+type Slugifier interface {
+	Slugify(s string) string
+}
+
+// SlugifierFunc is a function that is a Slugifier.
+type SlugifierFunc func(s string) string
+
+// Slugify returns f(s).
+func (f SlugifierFunc) Slugify(s string) string {
+	return f(s)
+}
+
+// DefaultSlugifier makes slugs with the slug package: transliterated to lower case ASCII, with words separated by
+// hyphens. It is used unless WithSlugifier sets another.
+var DefaultSlugifier Slugifier = SlugifierFunc(slug.Make)
+
+// slugifierFS is a MarkdownFS with its own slugifiers, such as LocalMarkdownFS with WithSlugifier.
+type slugifierFS interface {
+	Slugifier(postType string) Slugifier
+}
+
+// fsSlugifier returns the filesystem's Slugifier of the post type, or DefaultSlugifier if it doesn't have one.
+func fsSlugifier(fs MarkdownFS, postType string) Slugifier {
+	if s, ok := fs.(slugifierFS); ok {
+		return s.Slugifier(postType)
+	}
+	return DefaultSlugifier
+}
+
+// SlugOptions configures the Slugifier NewSlugifier returns.
+type SlugOptions struct {
+	Language    string // The language to transliterate with, as an ISO 639-1 code, such as "de" for ä to ae and & to und. Default is "en".
+	KeepUnicode bool   // Whether the letters and digits of every script are kept as they are, rather than transliterated to ASCII, such as for Japanese or Chinese sites
+	KeepCase    bool   // Whether upper case letters are kept, rather than made lower case
+	Separator   string // The separator between words. Default is "-".
+	MaxLength   int    // The maximum number of characters in a slug, cut after a whole word if possible. Default is no maximum.
+}
+
+// NewSlugifier returns a Slugifier with the options. With the zero SlugOptions, it makes the same slugs as
+// DefaultSlugifier.
+func NewSlugifier(opts SlugOptions) Slugifier {
+	if opts.Language == "" {
+		opts.Language = "en"
+	}
+	if opts.Separator == "" {
+		opts.Separator = "-"
+	}
+	return optionsSlugifier(opts)
+}
+
+// optionsSlugifier is the Slugifier NewSlugifier returns.
+type optionsSlugifier SlugOptions
+
+// Slugify splits the text into words and joins them with the separator.
+func (o optionsSlugifier) Slugify(s string) string {
+	if !o.KeepUnicode && !o.KeepCase {
+		return o.join(strings.Split(slug.MakeLang(s, o.Language), "-"))
+	}
+	return o.join(o.words(s))
+}
+
+// words returns the words of the text. Letters and digits are kept, and other characters are transliterated or
+// separate words.
+func (o optionsSlugifier) words(s string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'),
+			o.KeepUnicode && (unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)):
+			if !o.KeepCase {
+				r = unicode.ToLower(r)
+			}
+			word.WriteRune(r)
+		default:
+			// Transliterate the character, such as ü to ue in German or & to and in English
+			for i, part := range strings.Split(slug.MakeLang(string(r), o.Language), "-") {
+				if i > 0 || part == "" {
+					flush()
+				}
+				if o.KeepCase && unicode.IsUpper(r) && part != "" {
+					part = strings.ToUpper(part[:1]) + part[1:]
+				}
+				word.WriteString(part)
+			}
+		}
+	}
+	flush()
+
+	return words
+}
+
+// join joins the words with the separator, leaving out the words after MaxLength. If the first word is longer than
+// MaxLength, it is cut.
+func (o optionsSlugifier) join(words []string) string {
+	var b strings.Builder
+	length := 0
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		wordLength := utf8.RuneCountInString(word)
+		sepLength := 0
+		if length > 0 {
+			sepLength = utf8.RuneCountInString(o.Separator)
+		}
+
+		if o.MaxLength > 0 && length+sepLength+wordLength > o.MaxLength {
+			if length == 0 {
+				b.WriteString(string([]rune(word)[:o.MaxLength]))
+			}
+			break
+		}
+
+		if length > 0 {
+			b.WriteString(o.Separator)
+		}
+		b.WriteString(word)
+		length += sepLength + wordLength
+	}
+	return b.String()
+}
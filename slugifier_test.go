@@ -0,0 +1,106 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestNewSlugifier(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     downcache.SlugOptions
+		text     string
+		expected string
+	}{
+		{"default", downcache.SlugOptions{}, "Hello, World & Friends!", "hello-world-and-friends"},
+		{"default transliterates", downcache.SlugOptions{}, "Über Größe", "uber-grosse"},
+		{"german", downcache.SlugOptions{Language: "de"}, "Über Größe & Maße", "ueber-groesse-und-masse"},
+		{"keep case", downcache.SlugOptions{KeepCase: true}, "Hello World über", "Hello-World-uber"},
+		{"keep case with language", downcache.SlugOptions{Language: "de", KeepCase: true}, "Über Größe", "Ueber-Groesse"},
+		{"keep unicode", downcache.SlugOptions{KeepUnicode: true}, "日本語のタイトル、テスト！", "日本語のタイトル-テスト"},
+		{"keep unicode lower cases", downcache.SlugOptions{KeepUnicode: true}, "Größe Été", "größe-été"},
+		{"separator", downcache.SlugOptions{Separator: "_"}, "Hello World Again", "hello_world_again"},
+		{"max length", downcache.SlugOptions{MaxLength: 12}, "Hello World Again", "hello-world"},
+		{"max length cuts a long word", downcache.SlugOptions{MaxLength: 5}, "Supercalifragilistic", "super"},
+		{"max length counts characters", downcache.SlugOptions{KeepUnicode: true, MaxLength: 3}, "日本語のタイトル", "日本語"},
+		{"empty", downcache.SlugOptions{KeepUnicode: true}, " !? ", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, downcache.NewSlugifier(tc.opts).Slugify(tc.text))
+		})
+	}
+
+	// The zero options make the same slugs as the default
+	assert.Equal(t, downcache.DefaultSlugifier.Slugify("Ça va? 100% Ünïcode_test"),
+		downcache.NewSlugifier(downcache.SlugOptions{}).Slugify("Ça va? 100% Ünïcode_test"))
+}
+
+func TestSlugifyPathWith(t *testing.T) {
+	slugifier := downcache.NewSlugifier(downcache.SlugOptions{Separator: "_"})
+	slugPath := downcache.SlugifyPathWith("/content", "/content/articles/Guides Section/2024-01-01-My Post.md", downcache.PostTypeKeyArticle, slugifier)
+	assert.Equal(t, "guides_section/2024-01-01-my_post", slugPath.Slug)
+	assert.Equal(t, "2024-01-01", slugPath.FileTimePath)
+}
+
+func TestLocalMarkdownFS_WithSlugifier(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"articles/Über uns.md":   "---\nname: Über uns\n---\n",
+		"ja/日本語のタイトル.md":         "---\nname: 日本語のタイトル\n---\n",
+		"pages/Über Größe.md":    "---\nname: Über Größe\n---\n",
+		"pages/Hello World.md":   "---\nname: Hello World\n---\n",
+		"articles/Hello Welt.md": "---\nname: Hello Welt\n---\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	fs := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML,
+		downcache.WithSlugifier(downcache.NewSlugifier(downcache.SlugOptions{Language: "de"})),
+		downcache.WithSlugifier(downcache.NewSlugifier(downcache.SlugOptions{KeepUnicode: true}), "ja"),
+		downcache.WithSlugifier(downcache.DefaultSlugifier, "pages"))
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store, downcache.WithPermalink("articles", "/:title"))
+
+	ctx := context.Background()
+	require.NoError(t, cm.SyncAll(ctx))
+
+	for _, id := range [][2]string{
+		{"articles", "ueber-uns"},
+		{"articles", "hello-welt"},
+		{"ja", "日本語のタイトル"},
+		{"pages", "uber-grosse"},
+		{"pages", "hello-world"},
+	} {
+		_, err := store.Get(ctx, id[0], id[1])
+		assert.NoError(t, err, id)
+	}
+
+	// So do permalinks
+	post, err := store.Get(ctx, "articles", "ueber-uns")
+	require.NoError(t, err)
+	assert.Equal(t, "/ueber-uns", post.Permalink)
+
+	// Slugs made from names use the post type's slugifier too
+	post, err = cm.Create(ctx, &downcache.Post{PostType: "ja", Name: "日本語のタイトル"})
+	require.NoError(t, err)
+	assert.Equal(t, "日本語のタイトル-2", post.Slug)
+
+	post, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Name: "Grüße"})
+	require.NoError(t, err)
+	assert.Equal(t, "gruesse", post.Slug)
+
+	// The created post can be read back by its slug
+	_, err = fs.Read(ctx, "ja", "日本語のタイトル-2")
+	require.NoError(t, err)
+}
@@ -5,8 +5,6 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
-
-	"github.com/gosimple/slug"
 )
 
 type SlugPath struct {
@@ -25,13 +23,19 @@ func hasFileTimeInSlug(slug string) bool {
 // - The slug is then a combination of the `postType` and the relative path.
 // - It removes leading and trailing slashes, and ensures no leading slash remains.
 // - It finds the extension based on the last period in the path and trims it from the path.
-// - If the file part of a path starts with an RFC3339 date (2006-01-02), it extracts it and removes it from the path. Note, it does not include the time part.
 // - It trims the "/index" suffix if it exists.
+// - If the file part of a path starts with an RFC3339 date (2006-01-02), it extracts it as the file time. The slug keeps the date, and the time part isn't included.
 // - It replaces all path separators with browser-compatible forward slashes.
 // - Finally, it slugifies each path part using the slug package.
 //
 // The function returns a SlugPath struct with the slugified path, the file time path, the file time, and the post type.
 func SlugifyPath(rootPath, fullPath string, postType PostType) SlugPath {
+	return SlugifyPathWith(rootPath, fullPath, postType, DefaultSlugifier)
+}
+
+// SlugifyPathWith is SlugifyPath, but slugifies each path part with the slugifier. The date at the start of a file
+// name is kept as it is.
+func SlugifyPathWith(rootPath, fullPath string, postType PostType, slugifier Slugifier) SlugPath {
 	if fullPath == "" {
 		return SlugPath{}
 	}
@@ -56,6 +60,11 @@ func SlugifyPath(rootPath, fullPath string, postType PostType) SlugPath {
 	}
 	slugPath = trimmedPath
 
+	// If the path ends with "/index", remove it, we'll use the directory name as the slug, and its date if it has one
+	if strings.HasSuffix(slugPath, "/index") {
+		slugPath = strings.TrimSuffix(slugPath, "/index")
+	}
+
 	// find the last path separator
 	lastPathSeparator := strings.LastIndex(slugPath, string(os.PathSeparator))
 
@@ -72,22 +81,20 @@ func SlugifyPath(rootPath, fullPath string, postType PostType) SlugPath {
 		if parsedTime, err := time.Parse("2006-01-02", possibleDatePath); err == nil {
 			fileTime = &parsedTime
 			fileTimePath = possibleDatePath
-			// slugPath = strings.TrimSuffix(slugPath[11:], "/")
 		}
 	}
 
-	// If the path ends with "/index", remove it, we'll use the directory name as the slug
-	if strings.HasSuffix(slugPath, "/index") {
-		slugPath = strings.TrimSuffix(slugPath, "/index")
-	}
-
 	// Make sure all path separators are replaced with browser-compatible forward slashes
 	slugPath = strings.ReplaceAll(slugPath, string(os.PathSeparator), "/")
 
-	// Slugify each path part
+	// Slugify each path part, keeping the date of the file part
 	parts := strings.Split(slugPath, "/")
 	for i, part := range parts {
-		parts[i] = slug.Make(part)
+		if i == len(parts)-1 && fileTimePath != "" {
+			parts[i] = strings.TrimSuffix(fileTimePath+"-"+slugifier.Slugify(part[len(fileTimePath)+1:]), "-")
+			continue
+		}
+		parts[i] = slugifier.Slugify(part)
 	}
 
 	// Return the slugified path, the file time path, and the file time
@@ -98,3 +105,26 @@ func SlugifyPath(rootPath, fullPath string, postType PostType) SlugPath {
 		PostType:     postType,
 	}
 }
+
+// fileDatePrefix returns the "2006-01-02-" date prefix of a file or directory name, or "" if it doesn't have one.
+func fileDatePrefix(name string) string {
+	if !hasFileTimeInSlug(name) {
+		return ""
+	}
+	if _, err := time.Parse("2006-01-02", name[:10]); err != nil {
+		return ""
+	}
+	return name[:11]
+}
+
+// stripFileDate removes the date prefix from the last part of a slug, if it has one.
+func stripFileDate(slug string) string {
+	dir, file := "", slug
+	if i := strings.LastIndex(slug, "/"); i >= 0 {
+		dir, file = slug[:i+1], slug[i+1:]
+	}
+	if prefix := fileDatePrefix(file); prefix != "" {
+		return dir + file[len(prefix):]
+	}
+	return slug
+}
@@ -54,6 +54,14 @@ func TestConvertPathToSlug(t *testing.T) {
 			expectedFileTimePath: "",
 			expectedFileTime:     nil,
 		},
+		{
+			name:                 "Page bundle with date in directory name should parse the date",
+			fullPath:             "/path/to/files/articles/foobar/2024-01-01-my-post/index.md",
+			ruleType:             articleTypeRule,
+			expectedSlug:         "foobar/2024-01-01-my-post",
+			expectedFileTimePath: "2024-01-01",
+			expectedFileTime:     &fileTime,
+		},
 		{
 			name:                 "Path without date",
 			fullPath:             "/path/to/files/articles/my-post.md",
@@ -5,15 +5,24 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/hypergopher/downcache"
 )
 
 var ErrPostNotFound = errors.New("post not found")
 
+// sqliteDateTimeFormat is the format SQLite's date and time functions expect.
+const sqliteDateTimeFormat = "2006-01-02 15:04:05"
+
+// nowColumn is the current time in the format downcache.FormatDate returns, for posts without created or updated
+// dates.
+const nowColumn = `strftime('%Y-%m-%dT%H:%M:%SZ', 'now')`
+
+var _ downcache.CacheStore = (*SQLiteStore)(nil)
+
 // SQLiteStore is a SQLite implementation of the downcache.CacheStore interface.
 type SQLiteStore struct {
 	db        *sql.DB
@@ -48,12 +57,15 @@ func (s *SQLiteStore) Init() error {
 			file_time_path TEXT,
 			name TEXT,
 			published TEXT,
+			expires TEXT,
 			status TEXT,
 			subtitle TEXT,
 			summary TEXT,
 			visibility TEXT,
 			created TEXT DEFAULT CURRENT_TIMESTAMP,
-			updated TEXT DEFAULT CURRENT_TIMESTAMP
+			updated TEXT DEFAULT CURRENT_TIMESTAMP,
+			permalink TEXT,
+			weight INTEGER DEFAULT 0
 		);
 
 		-- Index on post_id
@@ -71,17 +83,17 @@ func (s *SQLiteStore) Init() error {
 		-- Index on published date
 		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_published_idx ON ` + s.tableName + `(published);
 
-		-- Table for properties 
-		CREATE TABLE IF NOT EXISTS ` + s.tableName + `_properties (
-			post_id TEXT,
-			key TEXT,
-			value TEXT,
-			PRIMARY KEY(post_id, key),
-			FOREIGN KEY(post_id) REFERENCES ` + s.tableName + `(id) ON DELETE CASCADE
-		);
+		-- Index for seeking through posts in the default sort order
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_default_sort_idx ON ` + s.tableName + `(pinned DESC, COALESCE(datetime(published), '') DESC, slug, post_id);
+
+		-- Table for properties. List properties have a header row (idx -1, value_type 'list')
+		-- followed by a row for each element. Numbers, bools and dates are also stored in num_value
+		-- so they can be compared numerically.
+		` + s.propertiesTable(s.tableName+"_properties") + `;
 
 		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_properties_post_id_idx ON ` + s.tableName + `_properties(post_id);
 		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_properties_key_idx ON ` + s.tableName + `_properties(key);
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_properties_key_num_value_idx ON ` + s.tableName + `_properties(key, num_value);
 
 		-- Table for taxonomies
 		CREATE TABLE IF NOT EXISTS ` + s.tableName + `_taxonomies (
@@ -95,6 +107,30 @@ func (s *SQLiteStore) Init() error {
 		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_taxonomies_post_id_idx ON ` + s.tableName + `_taxonomies(post_id);
 		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_taxonomies_taxonomy_idx ON ` + s.tableName + `_taxonomies(taxonomy);
 
+		-- Table for the assets of page bundles
+		CREATE TABLE IF NOT EXISTS ` + s.tableName + `_assets (
+			post_id TEXT,
+			path TEXT,
+			mime_type TEXT,
+			size INTEGER,
+			hash TEXT,
+			PRIMARY KEY(post_id, path),
+			FOREIGN KEY(post_id) REFERENCES ` + s.tableName + `(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_assets_post_id_idx ON ` + s.tableName + `_assets(post_id);
+
+		-- Table for the aliases of posts, which are their previous URL paths
+		CREATE TABLE IF NOT EXISTS ` + s.tableName + `_aliases (
+			post_id TEXT,
+			alias TEXT,
+			idx INTEGER,
+			PRIMARY KEY(post_id, alias),
+			FOREIGN KEY(post_id) REFERENCES ` + s.tableName + `(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_aliases_alias_idx ON ` + s.tableName + `_aliases(alias);
+
 		-- Create virtual table for full-text search
 		CREATE VIRTUAL TABLE IF NOT EXISTS ` + s.tableName + `_search USING fts5(
 			name,
@@ -118,18 +154,159 @@ func (s *SQLiteStore) Init() error {
 			VALUES('delete', old.id, old.name, old.subtitle, old.content_body, old.summary);
 		END;
 
-		CREATE TRIGGER IF NOT EXISTS ` + s.tableName + `_search_au AFTER UPDATE ON ` + s.tableName + `
+		-- Earlier versions of this trigger also set the updated date, which now comes from the post
+		DROP TRIGGER IF EXISTS ` + s.tableName + `_search_au;
+		CREATE TRIGGER ` + s.tableName + `_search_au AFTER UPDATE ON ` + s.tableName + `
 		BEGIN
 			INSERT INTO ` + s.tableName + `_search(` + s.tableName + `_search, rowid, name, subtitle, content_body, summary)
 			VALUES('delete', old.id, old.name, old.subtitle, old.content_body, old.summary);
 
 			INSERT INTO ` + s.tableName + `_search(rowid, name, subtitle, content_body, summary)
 			VALUES(new.id, new.name, new.subtitle, new.content_body, new.summary);
-
-			UPDATE ` + s.tableName + ` SET updated = CURRENT_TIMESTAMP WHERE id = new.id;
 		END;
 	`
-	_, err := s.db.Exec(query)
+	if err := s.migrateProperties(); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	return s.migrate()
+}
+
+// propertiesTable returns the statement that creates the properties table with the name.
+func (s *SQLiteStore) propertiesTable(name string) string {
+	return `CREATE TABLE IF NOT EXISTS ` + name + ` (
+			post_id TEXT,
+			key TEXT,
+			idx INTEGER DEFAULT 0,
+			value TEXT,
+			value_type TEXT DEFAULT 'string',
+			num_value REAL,
+			PRIMARY KEY(post_id, key, idx),
+			FOREIGN KEY(post_id) REFERENCES ` + s.tableName + `(id) ON DELETE CASCADE
+		)`
+}
+
+// migrateProperties rebuilds a properties table created before typed properties, which has a row per key without
+// the idx, value_type and num_value columns. Its values were all stored as text, so their types are inferred, as
+// they are for filter values, until the next sync stores the types from the frontmatter.
+func (s *SQLiteStore) migrateProperties() error {
+	table := s.tableName + "_properties"
+	columns, err := s.columns(table)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 || columns["num_value"] {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	if _, err := tx.Exec(s.propertiesTable(table + "_new")); err != nil {
+		return fmt.Errorf("error migrating properties: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT post_id, key, value FROM ` + table)
+	if err != nil {
+		return fmt.Errorf("error migrating properties: %w", err)
+	}
+	type property struct {
+		postID, key string
+		value       sql.NullString
+	}
+	var properties []property
+	for rows.Next() {
+		var p property
+		if err := rows.Scan(&p.postID, &p.key, &p.value); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("error migrating properties: %w", err)
+		}
+		properties = append(properties, p)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error migrating properties: %w", err)
+	}
+
+	query := `INSERT INTO ` + table + `_new (post_id, key, idx, value, value_type, num_value) VALUES (?, ?, 0, ?, ?, ?)`
+	for _, p := range properties {
+		value, err := downcache.ParsePropertyValue(p.value.String, downcache.InferPropertyType(p.value.String))
+		if err != nil {
+			value = p.value.String
+		}
+		if _, err := tx.Exec(query, append([]interface{}{p.postID, p.key}, propertyColumns(value)...)...); err != nil {
+			return fmt.Errorf("error migrating properties: %w", err)
+		}
+	}
+
+	for _, query := range []string{
+		`DROP TABLE ` + table,
+		`ALTER TABLE ` + table + `_new RENAME TO ` + table,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("error migrating properties: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// columns returns the names of the table's columns, or none if the table doesn't exist.
+func (s *SQLiteStore) columns(table string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// addedColumns are the columns added to the posts table since it was first released, with their definitions.
+var addedColumns = []struct{ name, definition string }{
+	{"expires", "TEXT"},
+	{"permalink", "TEXT"},
+	{"weight", "INTEGER DEFAULT 0"},
+}
+
+// migrate adds the columns in addedColumns to a posts table created before them, and creates their indexes.
+func (s *SQLiteStore) migrate() error {
+	columns, err := s.columns(s.tableName)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range addedColumns {
+		if columns[column.name] {
+			continue
+		}
+		if _, err := s.db.Exec(`ALTER TABLE ` + s.tableName + ` ADD COLUMN ` + column.name + ` ` + column.definition); err != nil {
+			return fmt.Errorf("error adding column %s: %w", column.name, err)
+		}
+	}
+
+	_, err = s.db.Exec(`
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_expires_idx ON ` + s.tableName + `(expires);
+		CREATE INDEX IF NOT EXISTS ` + s.tableName + `_permalink_idx ON ` + s.tableName + `(permalink);
+	`)
 	return err
 }
 
@@ -163,18 +340,24 @@ func (s *SQLiteStore) Create(_ context.Context, post *downcache.Post) (*downcach
 			post_id, name, slug, post_type, 
 			author, content_body, etag, estimated_read_time, 
 			pinned, photo, file_time_path, published, 
-			status, subtitle, summary, visibility) 
+			status, subtitle, summary, visibility,
+			expires, created, updated, permalink,
+			weight) 
 		VALUES (
 			$1, $2, $3, $4,
 			$5, $6, $7, $8,
 			$9, $10, $11, $12,
-			$13, $14, $15, $16)
+			$13, $14, $15, $16,
+			$17, COALESCE(NULLIF($18, ''), ` + nowColumn + `), COALESCE(NULLIF($19, ''), ` + nowColumn + `), $20,
+			$21)
 	`
 	result, err := tx.Exec(query,
 		postID, post.Name, post.Slug, post.PostType,
 		post.Author, post.Content, post.ETag, post.EstimatedReadTime,
 		post.Pinned, post.Photo, post.FileTimePath, post.Published,
-		post.Status, post.Subtitle, post.Summary, post.Visibility)
+		post.Status, post.Subtitle, post.Summary, post.Visibility,
+		post.Expires, post.Created, post.Updated, post.Permalink,
+		post.Weight)
 	if err != nil {
 		return nil, err
 	}
@@ -197,6 +380,16 @@ func (s *SQLiteStore) Create(_ context.Context, post *downcache.Post) (*downcach
 		return nil, err
 	}
 
+	// Insert assets
+	if err := s.insertAssets(tx, post); err != nil {
+		return nil, err
+	}
+
+	// Insert aliases
+	if err := s.insertAliases(tx, post); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -226,22 +419,35 @@ func (s *SQLiteStore) Update(_ context.Context, oldType, oldSlug string, post *d
 			author = $4, content_body = $5, etag = $6, estimated_read_time = $7,
 			pinned = $8, photo = $9, file_time_path = $10, published = $11,
 			status = $12, subtitle = $13, summary = $14, visibility = $15,
-			post_id = $16
-		WHERE post_id = $17 
+			expires = $16, post_id = $17,
+			created = COALESCE(NULLIF($18, ''), created), updated = COALESCE(NULLIF($19, ''), ` + nowColumn + `),
+			permalink = $20, weight = $21
+		WHERE post_id = $22 
 	`
 	if _, err = tx.Exec(query,
 		post.Name, post.Slug, post.PostType,
 		post.Author, post.Content, post.ETag, post.EstimatedReadTime,
 		post.Pinned, post.Photo, post.FileTimePath, post.Published,
 		post.Status, post.Subtitle, post.Summary, post.Visibility,
-		oldPostID,
-		newPostID); err != nil {
+		post.Expires, newPostID,
+		post.Created, post.Updated, post.Permalink, post.Weight,
+		oldPostID); err != nil {
+		return err
+	}
+
+	// The post passed in may not have been read from the store, so look up its ID
+	query = `SELECT id FROM ` + s.tableName + ` WHERE post_id = ?`
+	if err := tx.QueryRow(query, newPostID).Scan(&post.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPostNotFound
+		}
 		return err
 	}
+	post.PostID = newPostID
 
 	// Delete existing properties
 	query = `DELETE FROM ` + s.tableName + `_properties WHERE post_id = ?`
-	if _, err := tx.Exec(query, post.PostID); err != nil {
+	if _, err := tx.Exec(query, post.ID); err != nil {
 		return err
 	}
 
@@ -261,6 +467,28 @@ func (s *SQLiteStore) Update(_ context.Context, oldType, oldSlug string, post *d
 		return err
 	}
 
+	// Delete existing assets
+	query = `DELETE FROM ` + s.tableName + `_assets WHERE post_id = ?`
+	if _, err := tx.Exec(query, post.ID); err != nil {
+		return err
+	}
+
+	// Insert assets
+	if err := s.insertAssets(tx, post); err != nil {
+		return err
+	}
+
+	// Delete existing aliases
+	query = `DELETE FROM ` + s.tableName + `_aliases WHERE post_id = ?`
+	if _, err := tx.Exec(query, post.ID); err != nil {
+		return err
+	}
+
+	// Insert aliases
+	if err := s.insertAliases(tx, post); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -291,7 +519,8 @@ func (s *SQLiteStore) Get(_ context.Context, postType, slug string) (*downcache.
 		    p.id, p.post_id, p.name, p.slug, p.post_type,
 		    p.author, p.content_body, p.etag, p.estimated_read_time,
 		    p.pinned, p.photo, p.file_time_path, p.published, p.status,
-		    p.subtitle, p.summary, p.visibility, p.created, p.updated
+		    p.subtitle, p.summary, p.visibility, p.created, p.updated,
+		    p.expires, p.permalink, p.weight
 		FROM ` + s.tableName + ` p
 		WHERE p.post_id = ?
 	`
@@ -303,7 +532,7 @@ func (s *SQLiteStore) Get(_ context.Context, postType, slug string) (*downcache.
 	}
 
 	// Get properties for the post
-	query = `SELECT KEY, VALUE FROM ` + s.tableName + `_properties WHERE post_id = ?`
+	query = `SELECT post_id, key, idx, value, value_type, num_value FROM ` + s.tableName + `_properties WHERE post_id = ? ORDER BY idx`
 	rows, err := s.db.Query(query, post.ID)
 	if err != nil {
 		return nil, err
@@ -313,13 +542,12 @@ func (s *SQLiteStore) Get(_ context.Context, postType, slug string) (*downcache.
 		_ = rows.Close()
 	}(rows)
 
-	post.Properties = make(map[string]string)
 	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
+		_, key, value, err := scanProperty(rows)
+		if err != nil {
 			return nil, err
 		}
-		post.Properties[key] = value
+		addProperty(post.Properties, key, value)
 	}
 
 	// Get taxonomies for the post
@@ -342,6 +570,44 @@ func (s *SQLiteStore) Get(_ context.Context, postType, slug string) (*downcache.
 		post.Taxonomies[taxonomy] = append(post.Taxonomies[taxonomy], term)
 	}
 
+	// Get assets for the post
+	query = `SELECT post_id, path, mime_type, size, hash FROM ` + s.tableName + `_assets WHERE post_id = ? ORDER BY path`
+	rows, err = s.db.Query(query, post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		_, asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		post.Assets = append(post.Assets, asset)
+	}
+
+	// Get aliases for the post
+	query = `SELECT alias FROM ` + s.tableName + `_aliases WHERE post_id = ? ORDER BY idx`
+	rows, err = s.db.Query(query, post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+		post.Aliases = append(post.Aliases, alias)
+	}
+
 	return post, nil
 }
 
@@ -391,90 +657,167 @@ func (s *SQLiteStore) GetTaxonomyTerms(_ context.Context, taxonomy string) ([]st
 	return terms, nil
 }
 
-func (s *SQLiteStore) Search(_ context.Context, opts downcache.FilterOptions) ([]*downcache.Post, error) {
-	query := `
-		SELECT DISTINCT
-		    p.id, p.post_id, p.name, p.slug, p.post_type,
-		    p.author, p.content_body, p.etag, p.estimated_read_time,
-		    p.pinned, p.photo, p.file_time_path, p.published, p.status,
-		    p.subtitle, p.summary, p.visibility, p.created, p.updated
-		FROM ` + s.tableName + ` p
-		JOIN ` + s.tableName + `_search ON p.id = ` + s.tableName + `_search.rowid
-		LEFT JOIN ` + s.tableName + `_properties prop ON p.id = prop.post_id
-		LEFT JOIN ` + s.tableName + `_taxonomies tax ON p.id = tax.post_id
-	`
+// Search searches for posts matching the filter options. It returns a page of posts and the total number of matches.
+// Pages are selected by seeking past the After or Before cursor if set, otherwise by PageNum.
+func (s *SQLiteStore) Search(ctx context.Context, opts downcache.FilterOptions) ([]*downcache.Post, int, error) {
+	text := opts.TextQuery()
+	highlight := opts.Highlight && !text.IsEmpty()
+
+	from := ` FROM ` + s.tableName + ` p`
+	if !text.IsEmpty() {
+		from += ` JOIN ` + s.tableName + `_search ON p.id = ` + s.tableName + `_search.rowid`
+	}
 
 	var conditions []string
 	var args []interface{}
 
-	orderBy := "p.created DESC"
-
-	if opts.FilterPostType != "" {
+	if !opts.FilterPostType.IsAny() {
 		conditions = append(conditions, "p.post_type = ?")
 		args = append(args, opts.FilterPostType)
 	}
 
-	if opts.FilterStatus != "" {
+	if opts.FilterStatus != "" && opts.FilterStatus != downcache.FilterTypeAny.String() {
 		conditions = append(conditions, "p.status = ?")
 		args = append(args, opts.FilterStatus)
 	}
 
-	if opts.FilterVisibility != "" {
+	if opts.FilterVisibility != "" && opts.FilterVisibility != downcache.FilterTypeAny.String() {
 		conditions = append(conditions, "p.visibility = ?")
 		args = append(args, opts.FilterVisibility)
 	}
 
+	if condition, conditionArgs := accessCondition(opts.CurrentViewer(ctx).Access(true)); condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	if opts.FilterPermalink != "" {
+		conditions = append(conditions, "p.permalink = ?")
+		args = append(args, opts.FilterPermalink)
+	}
+
+	if opts.FilterAlias != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM `+s.tableName+`_aliases a WHERE a.post_id = p.id AND a.alias = ?)`)
+		args = append(args, opts.FilterAlias)
+	}
+
 	if opts.FilterAuthor != "" {
 		conditions = append(conditions, "p.author = ?")
 		args = append(args, opts.FilterAuthor)
 	}
 
-	if opts.FilterSearch != "" {
+	if !text.IsEmpty() {
 		conditions = append(conditions, s.tableName+"_search MATCH ?")
-		args = append(args, opts.FilterSearch)
-		orderBy = s.tableName + "_search.rank"
+		args = append(args, ftsQuery(text))
 	}
 
-	if opts.FilterTaxonomies != nil {
-		for _, tax := range opts.FilterTaxonomies {
-			conditions = append(conditions, "tax.taxonomy = ? AND tax.term = ?")
-			args = append(args, tax.Key, tax.Value)
-		}
+	if !opts.FilterPublishedAfter.IsZero() {
+		conditions = append(conditions, "datetime(p.published) >= datetime(?)")
+		args = append(args, opts.FilterPublishedAfter.UTC().Format(sqliteDateTimeFormat))
 	}
 
-	if opts.FilterProperties != nil {
-		for _, prop := range opts.FilterProperties {
-			conditions = append(conditions, "prop.key = ? AND prop.value = ?")
-			args = append(args, prop.Key, prop.Value)
-		}
+	if !opts.FilterPublishedBefore.IsZero() {
+		conditions = append(conditions, "datetime(p.published) < datetime(?)")
+		args = append(args, opts.FilterPublishedBefore.UTC().Format(sqliteDateTimeFormat))
+	}
+
+	if !opts.FilterExpiresAfter.IsZero() {
+		conditions = append(conditions, "datetime(p.expires) >= datetime(?)")
+		args = append(args, opts.FilterExpiresAfter.UTC().Format(sqliteDateTimeFormat))
+	}
+
+	if !opts.FilterExpiresBefore.IsZero() {
+		conditions = append(conditions, "datetime(p.expires) < datetime(?)")
+		args = append(args, opts.FilterExpiresBefore.UTC().Format(sqliteDateTimeFormat))
+	}
+
+	// Hide posts that are scheduled for later or have expired. Posts without a valid date are never hidden.
+	if asOf, ok := opts.LiveAsOf(); ok {
+		conditions = append(conditions, publishedSortColumn+" <= ?", "(datetime(p.expires) IS NULL OR datetime(p.expires) > ?)")
+		args = append(args, asOf.Format(sqliteDateTimeFormat), asOf.Format(sqliteDateTimeFormat))
+	}
+
+	for _, group := range opts.TaxonomyGroups() {
+		condition, conditionArgs := s.taxonomyGroupCondition(group)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
 	}
 
+	for _, prop := range opts.FilterProperties {
+		condition, conditionArgs := s.propertyCondition(prop)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	where := ""
 	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += " GROUP BY p.id"
-	query += " ORDER BY " + orderBy
+	// Count all the matches, regardless of the page
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	cursor, hasCursor, before, err := opts.Cursor()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	sortBy := opts.Sort()
+	if hasCursor {
+		condition, conditionArgs, err := s.seekCondition(cursor, before, !text.IsEmpty())
+		if err != nil {
+			return nil, 0, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := `
+		SELECT
+		    p.id, p.post_id, p.name, p.slug, p.post_type,
+		    p.author, p.content_body, p.etag, p.estimated_read_time,
+		    p.pinned, p.photo, p.file_time_path, p.published, p.status,
+		    p.subtitle, p.summary, p.visibility, p.created, p.updated,
+		    p.expires, p.permalink, p.weight
+	` + from + where
+
+	// Before cursors select the page in reverse order, so the posts closest to the cursor are selected
+	query += " ORDER BY " + s.orderBy(sortBy, before, !text.IsEmpty())
+	query += " LIMIT ?"
+	args = append(args, pageSize)
+
+	if !hasCursor {
+		pageNum := max(opts.PageNum, 1)
+		query += " OFFSET ?"
+		args = append(args, (pageNum-1)*pageSize)
+	}
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer func(rows *sql.Rows) {
 		_ = rows.Close()
 	}(rows)
 
-	// var posts []*downcache.Post
 	postsMap := make(map[int64]*downcache.Post)
 	postIDs := make([]any, 0)
 	for rows.Next() {
 		post, err := s.scanPost(rows)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		postsMap[post.ID] = post
-		// posts = append(posts, post)
 		postIDs = append(postIDs, post.ID)
 	}
 
@@ -484,7 +827,7 @@ func (s *SQLiteStore) Search(_ context.Context, opts downcache.FilterOptions) ([
 	termsQuery := fmt.Sprintf(`SELECT post_id, taxonomy, term FROM `+s.tableName+`_taxonomies WHERE post_id IN (%s)`, placeholders)
 	termRows, err := s.db.Query(termsQuery, postIDs...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer func(termRows *sql.Rows) {
@@ -495,16 +838,16 @@ func (s *SQLiteStore) Search(_ context.Context, opts downcache.FilterOptions) ([
 		var postID int64
 		var taxonomy, term string
 		if err := termRows.Scan(&postID, &taxonomy, &term); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		postsMap[postID].Taxonomies[taxonomy] = append(postsMap[postID].Taxonomies[taxonomy], term)
 	}
 
 	// Get properties for the posts
-	propsQuery := fmt.Sprintf(`SELECT post_id, KEY, VALUE FROM `+s.tableName+`_properties WHERE post_id IN (%s)`, placeholders)
+	propsQuery := fmt.Sprintf(`SELECT post_id, key, idx, value, value_type, num_value FROM `+s.tableName+`_properties WHERE post_id IN (%s) ORDER BY idx`, placeholders)
 	propsRows, err := s.db.Query(propsQuery, postIDs...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer func(propsRows *sql.Rows) {
@@ -512,16 +855,306 @@ func (s *SQLiteStore) Search(_ context.Context, opts downcache.FilterOptions) ([
 	}(propsRows)
 
 	for propsRows.Next() {
+		postID, key, value, err := scanProperty(propsRows)
+		if err != nil {
+			return nil, 0, err
+		}
+		addProperty(postsMap[postID].Properties, key, value)
+	}
+
+	// Get assets for the posts
+	assetsQuery := fmt.Sprintf(`SELECT post_id, path, mime_type, size, hash FROM `+s.tableName+`_assets WHERE post_id IN (%s) ORDER BY path`, placeholders)
+	assetRows, err := s.db.Query(assetsQuery, postIDs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer func(assetRows *sql.Rows) {
+		_ = assetRows.Close()
+	}(assetRows)
+
+	for assetRows.Next() {
+		postID, asset, err := scanAsset(assetRows)
+		if err != nil {
+			return nil, 0, err
+		}
+		postsMap[postID].Assets = append(postsMap[postID].Assets, asset)
+	}
+
+	// Get aliases for the posts
+	aliasesQuery := fmt.Sprintf(`SELECT post_id, alias FROM `+s.tableName+`_aliases WHERE post_id IN (%s) ORDER BY idx`, placeholders)
+	aliasRows, err := s.db.Query(aliasesQuery, postIDs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer func(aliasRows *sql.Rows) {
+		_ = aliasRows.Close()
+	}(aliasRows)
+
+	for aliasRows.Next() {
 		var postID int64
-		var key, value string
-		if err := propsRows.Scan(&postID, &key, &value); err != nil {
-			return nil, err
+		var alias string
+		if err := aliasRows.Scan(&postID, &alias); err != nil {
+			return nil, 0, err
+		}
+		postsMap[postID].Aliases = append(postsMap[postID].Aliases, alias)
+	}
+
+	// Get highlighted snippets and scores for the posts
+	if highlight && len(postIDs) > 0 {
+		if err := s.highlightPosts(postsMap, postIDs, placeholders, ftsQuery(text), opts.HighlightOptions); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Return the posts in the order they were selected, so rank ordering is preserved
+	posts := make([]*downcache.Post, 0, len(postIDs))
+	for _, id := range postIDs {
+		posts = append(posts, postsMap[id.(int64)])
+	}
+
+	if before {
+		slices.Reverse(posts)
+	}
+
+	return posts, total, nil
+}
+
+// accessCondition returns a condition that only selects the posts allowed by the access, or an empty string if
+// every post is allowed.
+func accessCondition(access downcache.Access) (string, []interface{}) {
+	if access.Unrestricted {
+		return "", nil
+	}
+
+	var args []interface{}
+	for _, status := range access.Statuses {
+		args = append(args, status)
+	}
+	for _, visibility := range access.Visibilities {
+		args = append(args, visibility)
+	}
+
+	condition := fmt.Sprintf("(COALESCE(p.status, '') IN (%s) AND COALESCE(p.visibility, '') IN (%s))",
+		placeholders(len(access.Statuses)), placeholders(len(access.Visibilities)))
+
+	if access.Author != "" {
+		condition = "(" + condition + " OR p.author = ?)"
+		args = append(args, access.Author)
+	}
+
+	return condition, args
+}
+
+// placeholders returns n comma-separated query placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// sortColumns maps the downcache sort fields to the columns they sort by
+var sortColumns = map[string]string{
+	"pinned":    "p.pinned",
+	"published": publishedSortColumn,
+	"name":      "p.slug",
+}
+
+// publishedSortColumn is the expression used to sort by published date. Posts without a valid published
+// date sort first in ascending order, matching the other stores.
+const publishedSortColumn = "COALESCE(datetime(p.published), '')"
+
+// sortColumn returns the column expression for a sort field, or false if the field isn't supported.
+// Scores are only available for full-text searches.
+func (s *SQLiteStore) sortColumn(field string, hasText bool) (string, bool) {
+	if field == "score" {
+		if !hasText {
+			return "0", true
+		}
+		// bm25 ranks are negative, with better matches being more negative
+		return "-" + s.tableName + "_search.rank", true
+	}
+
+	column, ok := sortColumns[field]
+	return column, ok
+}
+
+// orderBy returns the ORDER BY expression for the sort fields, with the post ID as a tie-breaker.
+// If reverse is true, every direction is reversed.
+func (s *SQLiteStore) orderBy(sortBy []string, reverse, hasText bool) string {
+	order := make([]string, 0, len(sortBy)+1)
+	for _, field := range sortBy {
+		name, descending := downcache.ParseSortField(field)
+		column, ok := s.sortColumn(name, hasText)
+		if !ok {
+			continue
+		}
+
+		if descending != reverse {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+
+	if reverse {
+		return strings.Join(append(order, "p.post_id DESC"), ", ")
+	}
+	return strings.Join(append(order, "p.post_id"), ", ")
+}
+
+// seekCondition returns a condition that selects the posts after the cursor in its sort order, or before it if
+// before is true. Each sort field is compared in turn, falling back to the post ID, e.g.
+// (a > ? OR (a = ? AND (b < ? OR (b = ? AND p.post_id > ?)))).
+func (s *SQLiteStore) seekCondition(cursor downcache.Cursor, before, hasText bool) (string, []interface{}, error) {
+	op := ">"
+	if before {
+		op = "<"
+	}
+
+	condition := "p.post_id " + op + " ?"
+	args := []interface{}{cursor.PostID}
+
+	for i := len(cursor.SortBy) - 1; i >= 0; i-- {
+		name, descending := downcache.ParseSortField(cursor.SortBy[i])
+		column, ok := s.sortColumn(name, hasText)
+		if !ok {
+			continue
+		}
+
+		value, err := sortArg(name, cursor.Values[i])
+		if err != nil {
+			return "", nil, err
+		}
+
+		var fieldOp string
+		if descending == (op == ">") {
+			fieldOp = "<"
+		} else {
+			fieldOp = ">"
+		}
+
+		condition = fmt.Sprintf("(%s %s ? OR (%s = ? AND %s))", column, fieldOp, column, condition)
+		args = append([]interface{}{value, value}, args...)
+	}
+
+	return condition, args, nil
+}
+
+// sortArg converts a cursor sort value into a query argument for the field's sort column.
+func sortArg(field, value string) (interface{}, error) {
+	parsed, err := downcache.ParseSortValue(field, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", downcache.ErrInvalidCursor, err)
+	}
+
+	switch v := parsed.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		return v.UTC().Format(sqliteDateTimeFormat), nil
+	default:
+		return v, nil
+	}
+}
+
+// highlightPosts sets the FTS5 highlighted fields and bm25 relevance score on each of the matched posts.
+// They are selected separately so only the posts on the selected page are highlighted.
+func (s *SQLiteStore) highlightPosts(postsMap map[int64]*downcache.Post, postIDs []any, placeholders, match string, opts downcache.HighlightOptions) error {
+	hlOpts := opts.WithDefaults()
+	search := s.tableName + `_search`
+
+	// FTS5 limits snippets to 64 tokens
+	words := min(hlOpts.Words, 64)
+
+	query := fmt.Sprintf(`
+		SELECT
+		    rowid,
+		    highlight(`+search+`, 0, ?, ?),
+		    highlight(`+search+`, 1, ?, ?),
+		    snippet(`+search+`, 2, ?, ?, ?, ?),
+		    highlight(`+search+`, 3, ?, ?),
+		    bm25(`+search+`)
+		FROM `+search+`
+		WHERE `+search+` MATCH ? AND rowid IN (%s)
+	`, placeholders)
+
+	args := []interface{}{
+		hlOpts.Pre, hlOpts.Post,
+		hlOpts.Pre, hlOpts.Post,
+		hlOpts.Pre, hlOpts.Post, hlOpts.Ellipsis, words,
+		hlOpts.Pre, hlOpts.Post,
+		match,
+	}
+
+	rows, err := s.db.Query(query, append(args, postIDs...)...)
+	if err != nil {
+		return err
+	}
+
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	for rows.Next() {
+		var postID int64
+		var name, subtitle, content, summary sql.NullString
+		var rank float64
+		if err := rows.Scan(&postID, &name, &subtitle, &content, &summary, &rank); err != nil {
+			return err
+		}
+
+		post, ok := postsMap[postID]
+		if !ok {
+			continue
 		}
-		postsMap[postID].Properties[key] = value
+
+		post.Highlights = make(map[string]string)
+		for field, value := range map[string]sql.NullString{
+			"name":     name,
+			"subtitle": subtitle,
+			"content":  content,
+			"summary":  summary,
+		} {
+			// Columns without a match are returned without any highlight markers
+			if value.Valid && strings.Contains(value.String, hlOpts.Pre) {
+				post.Highlights[field] = value.String
+			}
+		}
+
+		// bm25 scores are negative, with better matches being more negative
+		post.Score = -rank
 	}
 
-	posts := slices.Collect(maps.Values(postsMap))
-	return posts, nil
+	return rows.Err()
+}
+
+// ftsQuery translates a full-text query into an FTS5 MATCH expression. Every term is quoted, so user input
+// can never be interpreted as FTS5 syntax.
+func ftsQuery(text downcache.TextQuery) string {
+	clauses := make([]string, 0, len(text))
+	for _, clause := range text {
+		var positive, negative []string
+		for _, term := range clause {
+			quoted := `"` + strings.ReplaceAll(term.Value, `"`, `""`) + `"`
+			if term.Negate {
+				negative = append(negative, quoted)
+			} else {
+				positive = append(positive, quoted)
+			}
+		}
+
+		expr := strings.Join(positive, " AND ")
+		for _, term := range negative {
+			expr += " NOT " + term
+		}
+		clauses = append(clauses, "("+expr+")")
+	}
+	return strings.Join(clauses, " OR ")
 }
 
 func (s *SQLiteStore) scanPost(scanner interface {
@@ -529,12 +1162,15 @@ func (s *SQLiteStore) scanPost(scanner interface {
 },
 ) (*downcache.Post, error) {
 	var p downcache.Post
-	var properties, taxonomies string
+	var taxonomies string
+	var permalink sql.NullString
+	var weight sql.NullInt64
 	if err := scanner.Scan(
 		&p.ID, &p.PostID, &p.Name, &p.Slug, &p.PostType,
 		&p.Author, &p.Content, &p.ETag, &p.EstimatedReadTime,
 		&p.Pinned, &p.Photo, &p.FileTimePath, &p.Published, &p.Status,
 		&p.Subtitle, &p.Summary, &p.Visibility, &p.Created, &p.Updated,
+		&p.Expires, &permalink, &weight,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrPostNotFound
@@ -542,14 +1178,9 @@ func (s *SQLiteStore) scanPost(scanner interface {
 		return nil, err
 	}
 
-	p.Properties = make(map[string]string)
-	for _, prop := range strings.Fields(properties) {
-		if prop == "" {
-			continue
-		}
-		parts := strings.SplitN(prop, ":", 2)
-		p.Properties[parts[0]] = parts[1]
-	}
+	p.Permalink = permalink.String
+	p.Weight = int(weight.Int64)
+	p.Properties = make(map[string]any)
 
 	p.Taxonomies = make(map[string][]string)
 	for _, tax := range strings.Fields(taxonomies) {
@@ -564,16 +1195,160 @@ func (s *SQLiteStore) scanPost(scanner interface {
 }
 
 func (s *SQLiteStore) insertProperties(tx *sql.Tx, post *downcache.Post) error {
-	for key, value := range post.Properties {
-		query := `REPLACE INTO ` + s.tableName + `_properties (post_id, key, value) VALUES (?, ?, ?)`
-		_, err := tx.Exec(query, post.ID, key, value)
-		if err != nil {
+	query := `REPLACE INTO ` + s.tableName + `_properties (post_id, key, idx, value, value_type, num_value) VALUES (?, ?, ?, ?, ?, ?)`
+	for key, value := range downcache.NormalizeProperties(post.Properties) {
+		list, isList := value.([]any)
+		if !isList {
+			if _, err := tx.Exec(query, append([]interface{}{post.ID, key, 0}, propertyColumns(value)...)...); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Add a header row, so empty lists are preserved
+		if _, err := tx.Exec(query, post.ID, key, -1, nil, downcache.PropertyTypeList, nil); err != nil {
 			return err
 		}
+
+		for i, item := range list {
+			if _, err := tx.Exec(query, append([]interface{}{post.ID, key, i}, propertyColumns(item)...)...); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// propertyColumns returns the value, value_type and num_value columns for a normalized scalar property value.
+func propertyColumns(value any) []interface{} {
+	propType := downcache.PropertyTypeOf(value)
+	var numValue interface{}
+	if num, ok := numericPropertyValue(value); ok {
+		numValue = num
+	}
+	return []interface{}{downcache.FormatPropertyValue(value), string(propType), numValue}
+}
+
+// numericPropertyValue returns the numeric form of a number, bool or date property value.
+// Dates are converted to seconds since the Unix epoch.
+func numericPropertyValue(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		return float64(v.Unix()) + float64(v.Nanosecond())/1e9, true
+	default:
+		return 0, false
+	}
+}
+
+// scanProperty scans a property row. List header rows are returned as an empty list.
+func scanProperty(rows *sql.Rows) (int64, string, any, error) {
+	var postID int64
+	var idx int
+	var key string
+	var value sql.NullString
+	var valueType string
+	var numValue sql.NullFloat64
+	if err := rows.Scan(&postID, &key, &idx, &value, &valueType, &numValue); err != nil {
+		return 0, "", nil, err
+	}
+
+	if downcache.PropertyType(valueType) == downcache.PropertyTypeList {
+		return postID, key, []any{}, nil
+	}
+
+	parsed, err := downcache.ParsePropertyValue(value.String, downcache.PropertyType(valueType))
+	if err != nil {
+		return 0, "", nil, fmt.Errorf("invalid %s property %s: %w", valueType, key, err)
+	}
+
+	return postID, key, parsed, nil
+}
+
+// addProperty adds a scanned property to the properties map. Rows must be added in idx order, so list
+// header rows are added before their elements.
+func addProperty(props map[string]any, key string, value any) {
+	if list, ok := props[key].([]any); ok {
+		if _, isHeader := value.([]any); !isHeader {
+			props[key] = append(list, value)
+			return
+		}
+	}
+	props[key] = value
+}
+
+// taxonomyGroupCondition returns the SQL condition and arguments for a taxonomy filter group. The post must have
+// any of the group's terms, or none of them if the group is negated.
+func (s *SQLiteStore) taxonomyGroupCondition(group downcache.TaxonomyFilterGroup) (string, []interface{}) {
+	terms := make([]string, 0, len(group.Terms))
+	args := make([]interface{}, 0, len(group.Terms)*2)
+	for _, term := range group.Terms {
+		terms = append(terms, "(t.taxonomy = ? AND t.term = ?)")
+		args = append(args, term.Key, term.Value)
+	}
+
+	condition := `EXISTS (SELECT 1 FROM ` + s.tableName + `_taxonomies t WHERE t.post_id = p.id AND (` + strings.Join(terms, " OR ") + `))`
+	if group.Negate {
+		condition = "NOT " + condition
+	}
+
+	return condition, args
+}
+
+// propertyCondition returns the SQL condition and arguments for a property filter. The filter value is parsed
+// into each property type, and compared against the properties of that type, matching MatchProperty.
+func (s *SQLiteStore) propertyCondition(prop downcache.KeyValueFilter) (string, []interface{}) {
+	exists := `EXISTS (SELECT 1 FROM ` + s.tableName + `_properties pr WHERE pr.post_id = p.id AND pr.key = ?`
+	args := []interface{}{prop.Key}
+
+	var sqlOp string
+	switch prop.Operator() {
+	case downcache.FilterOpExists:
+		return exists + `)`, args
+	case downcache.FilterOpNe:
+		eq := prop
+		eq.Op = downcache.FilterOpEq
+		condition, eqArgs := s.propertyCondition(eq)
+		return "NOT " + condition, eqArgs
+	case downcache.FilterOpPrefix:
+		return exists + ` AND pr.value_type != 'list' AND substr(pr.value, 1, length(?)) = ?)`, append(args, prop.Value, prop.Value)
+	case downcache.FilterOpGt:
+		sqlOp = ">"
+	case downcache.FilterOpGte:
+		sqlOp = ">="
+	case downcache.FilterOpLt:
+		sqlOp = "<"
+	case downcache.FilterOpLte:
+		sqlOp = "<="
+	default:
+		sqlOp = "="
+	}
+
+	var valueConditions []string
+	for _, value := range prop.FilterValues() {
+		valueConditions = append(valueConditions, "(pr.value_type = 'string' AND pr.value "+sqlOp+" ?)")
+		args = append(args, value)
+
+		for _, propType := range []downcache.PropertyType{downcache.PropertyTypeNumber, downcache.PropertyTypeBool, downcache.PropertyTypeDate} {
+			parsed, err := downcache.ParsePropertyValue(value, propType)
+			if err != nil {
+				continue
+			}
+			num, _ := numericPropertyValue(parsed)
+			valueConditions = append(valueConditions, "(pr.value_type = ? AND pr.num_value "+sqlOp+" ?)")
+			args = append(args, string(propType), num)
+		}
+	}
+
+	return exists + ` AND (` + strings.Join(valueConditions, " OR ") + `))`, args
+}
+
 func (s *SQLiteStore) insertTaxonomies(tx *sql.Tx, post *downcache.Post) error {
 	for taxonomy, terms := range post.Taxonomies {
 		for _, term := range terms {
@@ -586,3 +1361,33 @@ func (s *SQLiteStore) insertTaxonomies(tx *sql.Tx, post *downcache.Post) error {
 	}
 	return nil
 }
+
+func (s *SQLiteStore) insertAssets(tx *sql.Tx, post *downcache.Post) error {
+	query := `REPLACE INTO ` + s.tableName + `_assets (post_id, path, mime_type, size, hash) VALUES (?, ?, ?, ?, ?)`
+	for _, asset := range post.Assets {
+		if _, err := tx.Exec(query, post.ID, asset.Path, asset.MIMEType, asset.Size, asset.Hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) insertAliases(tx *sql.Tx, post *downcache.Post) error {
+	query := `REPLACE INTO ` + s.tableName + `_aliases (post_id, alias, idx) VALUES (?, ?, ?)`
+	for i, alias := range post.Aliases {
+		if _, err := tx.Exec(query, post.ID, alias, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanAsset scans an asset row.
+func scanAsset(rows *sql.Rows) (int64, downcache.Asset, error) {
+	var postID int64
+	var asset downcache.Asset
+	if err := rows.Scan(&postID, &asset.Path, &asset.MIMEType, &asset.Size, &asset.Hash); err != nil {
+		return 0, downcache.Asset{}, err
+	}
+	return postID, asset, nil
+}
@@ -3,8 +3,10 @@ package sqlitestore_test
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,7 +76,7 @@ func createTestPost(t *testing.T, store *sqlitestore.SQLiteStore, testPost *down
 			Published:  sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true},
 			Status:     "draft",
 			Visibility: "public",
-			Properties: map[string]string{
+			Properties: map[string]any{
 				"test1": "test 1",
 				"test2": "test 2",
 			},
@@ -220,6 +222,277 @@ func TestSQLiteStore_Delete(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSQLiteStore_Assets(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	ctx := context.Background()
+	assets := []downcache.Asset{
+		{Path: "images/shot.png", MIMEType: "image/png", Size: 1024, Hash: "abc"},
+		{Path: "report.pdf", MIMEType: "application/pdf", Size: 2048, Hash: "def"},
+	}
+	post := createTestPost(t, store, &downcache.Post{
+		Name:     "Bundle",
+		Slug:     "bundle",
+		PostType: "article",
+		Content:  "A page bundle",
+		Status:   "published",
+		Assets:   assets,
+	})
+
+	post2, err := store.Get(ctx, post.PostType, post.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, assets, post2.Assets)
+
+	posts, _, err := store.Search(ctx, downcache.FilterOptions{FilterPostType: downcache.PostTypeKeyAny})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(posts))
+	}
+	assert.Equal(t, assets, posts[0].Assets)
+
+	// Updating replaces the assets
+	post.Assets = assets[1:]
+	if err := store.Update(ctx, post.PostType, post.Slug, post); err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+
+	post2, err = store.Get(ctx, post.PostType, post.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, assets[1:], post2.Assets)
+}
+
+func TestSQLiteStore_Dates(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	ctx := context.Background()
+	post := createTestPost(t, store, &downcache.Post{
+		Name:     "Dated",
+		Slug:     "dated",
+		PostType: "article",
+		Status:   "published",
+		Created:  "2024-03-01T10:00:00Z",
+		Updated:  "2024-03-02T10:00:00Z",
+	})
+
+	post2, err := store.Get(ctx, post.PostType, post.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, "2024-03-01T10:00:00Z", post2.Created)
+	assert.Equal(t, "2024-03-02T10:00:00Z", post2.Updated)
+
+	// The updated date is the post's, and the created date is kept if the post doesn't have one
+	post2.Created = ""
+	post2.Updated = "2024-03-05T10:00:00Z"
+	if err := store.Update(ctx, post2.PostType, post2.Slug, post2); err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	post3, err := store.Get(ctx, post.PostType, post.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, "2024-03-01T10:00:00Z", post3.Created)
+	assert.Equal(t, "2024-03-05T10:00:00Z", post3.Updated)
+
+	// Posts without dates get the current time
+	undated := createTestPost(t, store, &downcache.Post{Name: "Undated", Slug: "undated", PostType: "article", Status: "published"})
+	undated, err = store.Get(ctx, undated.PostType, undated.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	updated, err := downcache.ParseDate(undated.Updated, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse updated date: %v", err)
+	}
+	assert.WithinDuration(t, time.Now(), updated, time.Minute)
+}
+
+func TestSQLiteStore_Permalink(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	ctx := context.Background()
+	createTestPost(t, store, &downcache.Post{Name: "First", Slug: "first", PostType: "article", Status: "published", Permalink: "/2024/03/first/"})
+	createTestPost(t, store, &downcache.Post{Name: "Second", Slug: "second", PostType: "article", Status: "published", Permalink: "/2024/03/second/"})
+
+	posts, _, err := store.Search(ctx, downcache.FilterOptions{FilterPostType: downcache.PostTypeKeyAny, FilterPermalink: "/2024/03/second/"})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(posts))
+	}
+	assert.Equal(t, "second", posts[0].Slug)
+	assert.Equal(t, "/2024/03/second/", posts[0].Permalink)
+}
+
+func TestSQLiteStore_Aliases(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	ctx := context.Background()
+	createTestPost(t, store, &downcache.Post{Name: "First", Slug: "first", PostType: "article", Status: "published", Aliases: []string{"/old/first", "/article/1"}})
+	post := createTestPost(t, store, &downcache.Post{Name: "Second", Slug: "second", PostType: "article", Status: "published"})
+
+	got, err := store.Get(ctx, "article", "first")
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, []string{"/old/first", "/article/1"}, got.Aliases)
+
+	post.Aliases = []string{"/article/second"}
+	post.Slug = "renamed"
+	if err := store.Update(ctx, "article", "second", post); err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+
+	posts, _, err := store.Search(ctx, downcache.FilterOptions{FilterPostType: downcache.PostTypeKeyAny, FilterAlias: "/article/second"})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("Expected 1 post, got %d", len(posts))
+	}
+	assert.Equal(t, "renamed", posts[0].Slug)
+	assert.Equal(t, []string{"/article/second"}, posts[0].Aliases)
+
+	posts, _, err = store.Search(ctx, downcache.FilterOptions{FilterPostType: downcache.PostTypeKeyAny, FilterAlias: "/article/missing"})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+	assert.Empty(t, posts)
+}
+
+func TestSQLiteStore_Migrate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite db: %v", err)
+	}
+	defer db.Close()
+
+	// A posts table from before the permalink and weight columns were added
+	if _, err := db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id TEXT, slug TEXT, post_type TEXT,
+		author TEXT, content_body TEXT, etag TEXT, estimated_read_time TEXT, pinned INTEGER, photo TEXT, file_time_path TEXT,
+		name TEXT, published TEXT, expires TEXT, status TEXT, subtitle TEXT, summary TEXT, visibility TEXT,
+		created TEXT DEFAULT CURRENT_TIMESTAMP, updated TEXT DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	store := sqlitestore.NewSQLiteStore(db, dbPath, "posts")
+	if err := store.Init(); err != nil {
+		t.Fatalf("Failed to init store: %v", err)
+	}
+	// Init is idempotent
+	if err := store.Init(); err != nil {
+		t.Fatalf("Failed to init store again: %v", err)
+	}
+
+	createTestPost(t, store, &downcache.Post{Name: "Post", Slug: "post", PostType: "article", Permalink: "/article/post", Weight: 3})
+	post, err := store.Get(context.Background(), "article", "post")
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, "/article/post", post.Permalink)
+	assert.Equal(t, 3, post.Weight)
+}
+
+// baselineSchema is the schema of the first release, before any migrations.
+const baselineSchema = `
+	CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id TEXT, slug TEXT, post_type TEXT, author TEXT,
+		content_body TEXT, etag TEXT, estimated_read_time TEXT, pinned INTEGER, photo TEXT, file_time_path TEXT,
+		name TEXT, published TEXT, status TEXT, subtitle TEXT, summary TEXT, visibility TEXT,
+		created TEXT DEFAULT CURRENT_TIMESTAMP, updated TEXT DEFAULT CURRENT_TIMESTAMP);
+	CREATE UNIQUE INDEX posts_post_id_idx ON posts(post_id);
+	CREATE UNIQUE INDEX posts_post_type_slug_idx ON posts(post_type, slug);
+	CREATE TABLE posts_properties (post_id TEXT, key TEXT, value TEXT, PRIMARY KEY(post_id, key),
+		FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE);
+	CREATE INDEX posts_properties_post_id_idx ON posts_properties(post_id);
+	CREATE INDEX posts_properties_key_idx ON posts_properties(key);
+	CREATE TABLE posts_taxonomies (post_id TEXT, taxonomy TEXT, term TEXT, PRIMARY KEY(post_id, taxonomy, term),
+		FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE);
+	CREATE VIRTUAL TABLE posts_search USING fts5(name, subtitle, content_body, summary, content='posts', content_rowid='id');
+`
+
+func TestSQLiteStore_MigrateBaseline(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "baseline.db")
+	db, err := NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create SQLite db: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("Failed to create baseline schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO posts (id, post_id, slug, post_type, author, content_body, etag, estimated_read_time, pinned, photo,
+			file_time_path, name, published, status, subtitle, summary, visibility)
+		VALUES (1, 'article/old', 'old', 'article', '', '', '', '', 0, '', '', 'Old', '', 'draft', '', '', 'public');
+		INSERT INTO posts_properties (post_id, key, value) VALUES (1, 'color', 'blue'), (1, 'rating', 4), (1, 'featured', 'true')`); err != nil {
+		t.Fatalf("Failed to insert baseline rows: %v", err)
+	}
+
+	store := sqlitestore.NewSQLiteStore(db, dbPath, "posts")
+	if err := store.Init(); err != nil {
+		t.Fatalf("Failed to init store: %v", err)
+	}
+	// Init is idempotent
+	if err := store.Init(); err != nil {
+		t.Fatalf("Failed to init store again: %v", err)
+	}
+
+	// The properties are kept, with the types of their values inferred
+	rows, err := db.Query(`SELECT key, idx, value, value_type, num_value FROM posts_properties WHERE post_id = 1 ORDER BY key`)
+	if err != nil {
+		t.Fatalf("Failed to query properties: %v", err)
+	}
+	defer rows.Close()
+	var properties []string
+	for rows.Next() {
+		var key, value, valueType string
+		var idx int
+		var numValue sql.NullFloat64
+		if err := rows.Scan(&key, &idx, &value, &valueType, &numValue); err != nil {
+			t.Fatalf("Failed to scan property: %v", err)
+		}
+		properties = append(properties, fmt.Sprintf("%s %d %s %s %v", key, idx, value, valueType, numValue.Float64))
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, []string{"color 0 blue string 0", "featured 0 true bool 1", "rating 0 4 number 4"}, properties)
+
+	// Posts with the columns added since can be written, read and searched
+	ctx := context.Background()
+	expires := sql.NullString{String: time.Now().Add(time.Hour).UTC().Format(time.RFC3339), Valid: true}
+	createTestPost(t, store, &downcache.Post{Name: "New", Slug: "new", PostType: "article", Status: "published",
+		Published: sql.NullString{String: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), Valid: true},
+		Expires:   expires, Visibility: "public"})
+	post, err := store.Get(ctx, "article", "new")
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, expires, post.Expires)
+
+	post, err = store.Get(ctx, "article", "old")
+	if err != nil {
+		t.Fatalf("Failed to get migrated post: %v", err)
+	}
+	assert.Equal(t, "blue", post.Properties["color"])
+
+	posts, _, err := store.Search(ctx, downcache.FilterOptions{FilterPostType: "article", FilterStatus: "published"})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+	assert.Len(t, posts, 1)
+}
+
 func TestSQLiteStore_Search(t *testing.T) {
 	store := setupTestEnvironment(t)
 	defer teardownTestEnvironment(t, store)
@@ -233,7 +506,7 @@ func TestSQLiteStore_Search(t *testing.T) {
 		Published:  sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true},
 		Status:     "published",
 		Visibility: "public",
-		Properties: map[string]string{
+		Properties: map[string]any{
 			"test1": "test 1",
 			"test2": "test 2",
 		},
@@ -250,7 +523,7 @@ func TestSQLiteStore_Search(t *testing.T) {
 		Published:  sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true},
 		Status:     "published",
 		Visibility: "private",
-		Properties: map[string]string{
+		Properties: map[string]any{
 			"test3": "test3",
 		},
 		Taxonomies: map[string][]string{
@@ -319,6 +592,33 @@ func TestSQLiteStore_Search(t *testing.T) {
 			},
 			expectedPosts: []*downcache.Post{post3},
 		},
+		{
+			name: "Filter by any of tags, excluding a category",
+			filter: downcache.FilterOptions{
+				FilterTaxonomyGroups: []downcache.TaxonomyFilterGroup{
+					{Terms: []downcache.KeyValueFilter{{Key: "tags", Value: "tag1"}, {Key: "tags", Value: "tag3"}}},
+					{Terms: []downcache.KeyValueFilter{{Key: "categories", Value: "cat3"}}, Negate: true},
+				},
+			},
+			expectedPosts: []*downcache.Post{post1, post2},
+		},
+		{
+			name: "Filter by all of tags",
+			filter: downcache.FilterOptions{
+				FilterTaxonomies: []downcache.KeyValueFilter{
+					{Key: "tags", Value: "tag1"},
+					{Key: "tags", Value: "tag2"},
+				},
+			},
+			expectedPosts: []*downcache.Post{post1, post2},
+		},
+		{
+			name: "Anonymous viewer",
+			filter: downcache.FilterOptions{
+				Viewer: downcache.AnonymousViewer,
+			},
+			expectedPosts: []*downcache.Post{post2},
+		},
 		{
 			name: "Filter by property",
 			filter: downcache.FilterOptions{
@@ -337,8 +637,13 @@ func TestSQLiteStore_Search(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			// Search as an admin, who can see drafts and private posts, unless the case has a viewer
+			if tc.filter.Viewer.Role == "" {
+				tc.filter.Viewer = downcache.Viewer{Role: downcache.ViewerAdmin}
+			}
+
 			// Search for posts
-			posts, err := store.Search(context.Background(), tc.filter)
+			posts, _, err := store.Search(context.Background(), tc.filter)
 			if err != nil {
 				t.Fatalf("Failed to search posts: %v", err)
 			}
@@ -366,3 +671,329 @@ func TestSQLiteStore_Search(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteStore_SearchHighlight(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	createTestPost(t, store, nil)
+	createTestPost(t, store, &downcache.Post{
+		Name:       "Gophers",
+		Slug:       "gophers",
+		PostType:   "article",
+		Content:    "Gophers are small burrowing rodents. Some gophers are also mascots.",
+		Status:     "published",
+		Visibility: "public",
+	})
+
+	posts, _, err := store.Search(context.Background(), downcache.FilterOptions{
+		PageNum:      1,
+		PageSize:     10,
+		FilterSearch: "gophers",
+		Highlight:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, "<mark>Gophers</mark>", posts[0].Highlights["name"])
+		assert.Contains(t, posts[0].Highlights["content"], "<mark>Gophers</mark> are small")
+		assert.NotContains(t, posts[0].Highlights, "summary")
+		assert.Greater(t, posts[0].Score, 0.0)
+	}
+
+	// Without highlighting, no highlights or score are returned
+	posts, _, err = store.Search(context.Background(), downcache.FilterOptions{
+		FilterSearch: "gophers",
+	})
+	if err != nil {
+		t.Fatalf("Failed to search posts: %v", err)
+	}
+
+	if assert.Len(t, posts, 1) {
+		assert.Empty(t, posts[0].Highlights)
+		assert.Zero(t, posts[0].Score)
+	}
+}
+
+func TestSQLiteStore_SearchProperties(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	added := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Pancakes",
+		Slug:     "pancakes",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  20,
+			"difficulty": "easy",
+			"vegan":      false,
+			"added":      added,
+			"meals":      []any{"breakfast", "brunch"},
+			"notes":      []any{},
+		},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Risotto",
+		Slug:     "risotto",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  45,
+			"difficulty": "medium",
+			"vegan":      true,
+			"added":      time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			"meals":      []any{"dinner"},
+		},
+	})
+	createTestPost(t, store, &downcache.Post{
+		Name:     "Souffle",
+		Slug:     "souffle",
+		PostType: "recipes",
+		Properties: map[string]any{
+			"cook_time":  25,
+			"difficulty": "hard",
+		},
+	})
+
+	// Typed values round trip
+	post, err := store.Get(context.Background(), "recipes", "pancakes")
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, map[string]any{
+		"cook_time":  20.0,
+		"difficulty": "easy",
+		"vegan":      false,
+		"added":      added,
+		"meals":      []any{"breakfast", "brunch"},
+		"notes":      []any{},
+	}, post.Properties)
+
+	cases := []struct {
+		name     string
+		filters  []downcache.KeyValueFilter
+		expected []string
+	}{
+		{"Number lt", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"}}, []string{"pancakes", "souffle"}},
+		{"Number gte", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpGte, Value: "25"}}, []string{"risotto", "souffle"}},
+		{"In", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}}}, []string{"pancakes", "risotto"}},
+		{"Bool eq", []downcache.KeyValueFilter{{Key: "vegan", Value: "true"}}, []string{"risotto"}},
+		{"Ne includes missing", []downcache.KeyValueFilter{{Key: "vegan", Op: downcache.FilterOpNe, Value: "true"}}, []string{"pancakes", "souffle"}},
+		{"Exists", []downcache.KeyValueFilter{{Key: "meals", Op: downcache.FilterOpExists}}, []string{"pancakes", "risotto"}},
+		{"Date gt", []downcache.KeyValueFilter{{Key: "added", Op: downcache.FilterOpGt, Value: "2024-02-01"}}, []string{"risotto"}},
+		{"List eq", []downcache.KeyValueFilter{{Key: "meals", Value: "brunch"}}, []string{"pancakes"}},
+		{"Prefix", []downcache.KeyValueFilter{{Key: "difficulty", Op: downcache.FilterOpPrefix, Value: "me"}}, []string{"risotto"}},
+		{"Combined", []downcache.KeyValueFilter{
+			{Key: "cook_time", Op: downcache.FilterOpLt, Value: "30"},
+			{Key: "difficulty", Op: downcache.FilterOpIn, Values: []string{"easy", "medium"}},
+		}, []string{"pancakes"}},
+		{"Type mismatch", []downcache.KeyValueFilter{{Key: "cook_time", Op: downcache.FilterOpGt, Value: "abc"}}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, _, err := store.Search(context.Background(), downcache.FilterOptions{
+				FilterProperties: tc.filters,
+			})
+			if err != nil {
+				t.Fatalf("Failed to search posts: %v", err)
+			}
+
+			var slugs []string
+			for _, post := range posts {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.ElementsMatch(t, tc.expected, slugs)
+		})
+	}
+}
+
+func TestSQLiteStore_SearchCursor(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	for i := 1; i <= 5; i++ {
+		createTestPost(t, store, &downcache.Post{
+			Name:      fmt.Sprintf("Note %d", i),
+			Slug:      fmt.Sprintf("note-%d", i),
+			PostType:  "notes",
+			Content:   "A short note",
+			Published: sql.NullString{String: fmt.Sprintf("2024-01-0%d", i), Valid: true},
+		})
+	}
+
+	search := func(filter downcache.FilterOptions) ([]string, downcache.Paginator) {
+		t.Helper()
+		filter.FilterPostType = "notes"
+		filter.PageSize = 2
+		posts, total, err := store.Search(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("Failed to search posts: %v", err)
+		}
+
+		var slugs []string
+		for _, post := range posts {
+			slugs = append(slugs, post.Slug)
+		}
+		return slugs, downcache.NewCursorPaginator(posts, total, filter)
+	}
+
+	slugs, page := search(downcache.FilterOptions{})
+	assert.Equal(t, []string{"note-5", "note-4"}, slugs)
+	assert.True(t, page.HasNext)
+	assert.False(t, page.HasPrev)
+
+	// A new post doesn't shift the following pages
+	createTestPost(t, store, &downcache.Post{
+		Name:      "Note 6",
+		Slug:      "note-6",
+		PostType:  "notes",
+		Published: sql.NullString{String: "2024-01-06", Valid: true},
+	})
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+	assert.True(t, page.HasPrev)
+
+	slugs, page = search(downcache.FilterOptions{After: page.NextCursor})
+	assert.Equal(t, []string{"note-1"}, slugs)
+	assert.False(t, page.HasNext)
+
+	slugs, page = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-3", "note-2"}, slugs)
+
+	slugs, _ = search(downcache.FilterOptions{Before: page.PrevCursor})
+	assert.Equal(t, []string{"note-5", "note-4"}, slugs)
+
+	// Cursors are tied to the sort order
+	_, _, err := store.Search(context.Background(), downcache.FilterOptions{After: page.PrevCursor, SortBy: []string{"name"}})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+
+	_, _, err = store.Search(context.Background(), downcache.FilterOptions{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, downcache.ErrInvalidCursor)
+}
+
+func TestSQLiteStore_SearchCursorScore(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	for i := 1; i <= 4; i++ {
+		createTestPost(t, store, &downcache.Post{
+			Name:     fmt.Sprintf("Gopher %d", i),
+			Slug:     fmt.Sprintf("gopher-%d", i),
+			PostType: "notes",
+			Content:  strings.Repeat("gopher ", i) + strings.Repeat("burrow ", 10),
+		})
+	}
+
+	filter := downcache.FilterOptions{FilterSearch: "gopher", Highlight: true, PageSize: 3}
+
+	var slugs []string
+	for {
+		posts, total, err := store.Search(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("Failed to search posts: %v", err)
+		}
+		for _, post := range posts {
+			slugs = append(slugs, post.Slug)
+		}
+
+		page := downcache.NewCursorPaginator(posts, total, filter)
+		if !page.HasNext {
+			break
+		}
+		filter.After = page.NextCursor
+	}
+
+	assert.Equal(t, []string{"gopher-4", "gopher-3", "gopher-2", "gopher-1"}, slugs)
+}
+
+func TestSQLiteStore_SearchSchedule(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	now := time.Now().UTC()
+	date := func(d time.Duration) sql.NullString {
+		return sql.NullString{String: now.Add(d).Format(time.RFC3339), Valid: true}
+	}
+
+	createTestPost(t, store, &downcache.Post{Name: "Undated", Slug: "undated", PostType: "article"})
+	createTestPost(t, store, &downcache.Post{Name: "Live", Slug: "live", PostType: "article", Published: date(-time.Hour), Expires: date(time.Hour)})
+	createTestPost(t, store, &downcache.Post{Name: "Scheduled", Slug: "scheduled", PostType: "article", Published: date(24 * time.Hour)})
+	createTestPost(t, store, &downcache.Post{Name: "Expired", Slug: "expired", PostType: "article", Published: date(-48 * time.Hour), Expires: date(-24 * time.Hour)})
+
+	cases := []struct {
+		name     string
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"Now", downcache.FilterOptions{}, []string{"undated", "live"}},
+		{"As of tomorrow", downcache.FilterOptions{AsOf: now.Add(36 * time.Hour)}, []string{"undated", "scheduled"}},
+		{"As of two days ago", downcache.FilterOptions{AsOf: now.Add(-36 * time.Hour)}, []string{"undated", "expired"}},
+		{"Include scheduled", downcache.FilterOptions{IncludeScheduled: true}, []string{"undated", "live", "scheduled", "expired"}},
+		{"Expiring", downcache.FilterOptions{IncludeScheduled: true, FilterExpiresAfter: now}, []string{"live"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, _, err := store.Search(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("Failed to search posts: %v", err)
+			}
+
+			var slugs []string
+			for _, post := range posts {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.ElementsMatch(t, tc.expected, slugs)
+		})
+	}
+
+	// The expiry date round trips
+	post, err := store.Get(context.Background(), "article", "live")
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	assert.Equal(t, date(time.Hour), post.Expires)
+}
+
+func TestSQLiteStore_SearchViewer(t *testing.T) {
+	store := setupTestEnvironment(t)
+	defer teardownTestEnvironment(t, store)
+
+	createTestPost(t, store, &downcache.Post{Name: "Public", Slug: "public", PostType: "article", Author: "jane", Status: "published", Visibility: "public"})
+	createTestPost(t, store, &downcache.Post{Name: "Unlisted", Slug: "unlisted", PostType: "article", Author: "jane", Status: "published", Visibility: "unlisted"})
+	createTestPost(t, store, &downcache.Post{Name: "Jane's draft", Slug: "jane-draft", PostType: "article", Author: "jane", Status: "draft", Visibility: "public"})
+	createTestPost(t, store, &downcache.Post{Name: "Bob's private", Slug: "bob-private", PostType: "article", Author: "bob", Status: "published", Visibility: "private"})
+	createTestPost(t, store, &downcache.Post{Name: "Defaults", Slug: "defaults", PostType: "article"})
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		filter   downcache.FilterOptions
+		expected []string
+	}{
+		{"Anonymous by default", context.Background(), downcache.FilterOptions{}, []string{"public", "defaults"}},
+		{"Anonymous draft filter", context.Background(), downcache.FilterOptions{FilterStatus: "draft"}, nil},
+		{"Author", context.Background(), downcache.FilterOptions{Viewer: downcache.Viewer{Role: downcache.ViewerAuthor, Author: "jane"}}, []string{"public", "unlisted", "jane-draft", "defaults"}},
+		{"Admin from context", downcache.WithViewer(context.Background(), downcache.Viewer{Role: downcache.ViewerAdmin}), downcache.FilterOptions{}, []string{"public", "unlisted", "jane-draft", "bob-private", "defaults"}},
+		{"Admin drafts", context.Background(), downcache.FilterOptions{Viewer: downcache.Viewer{Role: downcache.ViewerAdmin}, FilterStatus: "draft"}, []string{"jane-draft"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			posts, _, err := store.Search(tc.ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("Failed to search posts: %v", err)
+			}
+
+			var slugs []string
+			for _, post := range posts {
+				slugs = append(slugs, post.Slug)
+			}
+			assert.ElementsMatch(t, tc.expected, slugs)
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package downcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrashDir is the hidden directory under the content root that LocalMarkdownFS moves deleted posts to.
+const TrashDir = ".trash"
+
+// TrashedPost is a deleted post in the trash.
+type TrashedPost struct {
+	PostType string    `json:"postType"`
+	Slug     string    `json:"slug"`
+	Deleted  time.Time `json:"deleted"` // When the post was deleted
+	Post     *Post     `json:"-"`       // The post as it was when it was deleted
+}
+
+// TrashFS is a MarkdownFS that can move deleted posts to a trash area, where they are hidden from Walk and Read,
+// instead of removing them. DownCache.Delete uses it when the MarkdownFS supports it.
+type TrashFS interface {
+	MarkdownFS
+	// Trash moves a post to the trash, recording when it was deleted. A post with the same type and slug that is
+	// already in the trash is replaced.
+	Trash(ctx context.Context, postType, slug string, deleted time.Time) error
+	// Untrash moves a post from the trash back to its type and slug. It returns ErrNotInTrash if the post isn't in
+	// the trash, or ErrPostExists if another post has taken its place.
+	Untrash(ctx context.Context, postType, slug string) error
+	// ListTrash returns the posts in the trash, most recently deleted first.
+	ListTrash(ctx context.Context) ([]*TrashedPost, error)
+	// Purge permanently removes a post from the trash.
+	Purge(ctx context.Context, postType, slug string) error
+}
+
+// Restore moves a deleted post out of the trash and adds it back to the store.
+func (cm *DownCache) Restore(ctx context.Context, postType, slug string) (*Post, error) {
+	trash, ok := cm.fs.(TrashFS)
+	if !ok {
+		return nil, ErrTrashDisabled
+	}
+
+	if err := cm.checkWritable(postType, slug); err != nil {
+		return nil, err
+	}
+
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	id, err := cm.journal.begin(JournalOpCreate, PostPathID(postType, slug))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := trash.Untrash(ctx, postType, slug); err != nil {
+		return nil, cm.commit(id, fmt.Errorf("error restoring from trash: %w", err))
+	}
+
+	post, err := cm.fs.Read(ctx, postType, slug)
+	if err == nil {
+		cm.index(post)
+		post, err = cm.store.Create(ctx, post)
+	}
+	if err != nil {
+		// Rollback: put the post back in the trash
+		if trErr := trash.Trash(ctx, postType, slug, time.Now().UTC()); trErr != nil {
+			return nil, fmt.Errorf("failed to add to store and rollback failed: %v, %w", trErr, err)
+		}
+		return nil, cm.commit(id, fmt.Errorf("error adding to store: %w", err))
+	}
+
+	return post, cm.commit(id, nil)
+}
+
+// ListTrash returns the deleted posts in the trash, most recently deleted first.
+func (cm *DownCache) ListTrash(ctx context.Context) ([]*TrashedPost, error) {
+	trash, ok := cm.fs.(TrashFS)
+	if !ok {
+		return nil, ErrTrashDisabled
+	}
+	return trash.ListTrash(ctx)
+}
+
+// PurgeTrash permanently removes the posts that were deleted more than olderThan ago, or every post in the trash if
+// olderThan is zero, and returns them.
+func (cm *DownCache) PurgeTrash(ctx context.Context, olderThan time.Duration) ([]*TrashedPost, error) {
+	trash, ok := cm.fs.(TrashFS)
+	if !ok {
+		return nil, ErrTrashDisabled
+	}
+
+	if cm.ReadOnly() {
+		return nil, fmt.Errorf("%w: can't purge the trash", ErrReadOnly)
+	}
+
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	trashed, err := trash.ListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var purged []*TrashedPost
+	for _, tp := range trashed {
+		if tp.Deleted.After(cutoff) {
+			continue
+		}
+
+		if err := trash.Purge(ctx, tp.PostType, tp.Slug); err != nil {
+			return purged, fmt.Errorf("error purging %s: %w", PostPathID(tp.PostType, tp.Slug), err)
+		}
+		purged = append(purged, tp)
+	}
+
+	return purged, nil
+}
+
+var _ TrashFS = (*LocalMarkdownFS)(nil)
+
+func (fs *LocalMarkdownFS) Trash(_ context.Context, postType, slug string, deleted time.Time) error {
+	trashBase := fs.buildTrashBase(postType, slug)
+
+	unlock, err := fs.lock(fs.buildPath(postType, slug), trashBase)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path, bundle := fs.postPath(postType, slug)
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	if err := fs.checkChildren(postType, slug, path, bundle); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(TrashedPost{PostType: postType, Slug: slug, Deleted: deleted.UTC()})
+	if err != nil {
+		return fmt.Errorf("error encoding trash metadata: %w", err)
+	}
+
+	if err := writeFileAtomic(trashBase+".json", data, 0o644); err != nil {
+		return err
+	}
+
+	// Replace any version of the post already in the trash. A page bundle is trashed with its assets.
+	if err := fs.removeTrashed(postType, slug); err != nil {
+		return err
+	}
+	// The date of a file whose slug doesn't include it is kept, so it is restored with it
+	datedBase := fs.datedBase(postType, trashBase, postDatePrefix(path, bundle))
+	trashPath := datedBase + filepath.Ext(path)
+	if bundle {
+		path = filepath.Dir(path)
+		trashPath = datedBase
+	}
+
+	if err := os.Rename(path, trashPath); err != nil {
+		_ = os.Remove(trashBase + ".json")
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(trashPath))
+}
+
+func (fs *LocalMarkdownFS) Untrash(_ context.Context, postType, slug string) error {
+	trashBase := fs.buildTrashBase(postType, slug)
+
+	unlock, err := fs.lock(fs.buildPath(postType, slug), trashBase)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	trashPath, bundle := fs.trashedPath(postType, slug)
+	if trashPath == "" {
+		return fmt.Errorf("%w: %s", ErrNotInTrash, PostPathID(postType, slug))
+	}
+	if current, _ := fs.postPath(postType, slug); fileExists(current) {
+		return fmt.Errorf("%w: %s", ErrPostExists, PostPathID(postType, slug))
+	}
+
+	datedBase := fs.datedBase(postType, fs.buildBundleDir(postType, slug), postDatePrefix(trashPath, bundle))
+	path := datedBase + filepath.Ext(trashPath)
+	if bundle {
+		trashPath = filepath.Dir(trashPath)
+		path = datedBase
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(trashPath, path); err != nil {
+		return err
+	}
+
+	if err := os.Remove(trashBase + ".json"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(trashPath)); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+func (fs *LocalMarkdownFS) ListTrash(_ context.Context) ([]*TrashedPost, error) {
+	var trashed []*TrashedPost
+
+	trashDir := filepath.Join(fs.rootDir, TrashDir)
+	err := filepath.WalkDir(trashDir, func(path string, d os.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// The assets of a trashed page bundle aren't trash metadata
+			if _, bundle := fs.findPost(path); path != trashDir && bundle && trashMetaExists(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var tp TrashedPost
+		if err := json.Unmarshal(data, &tp); err != nil {
+			return fmt.Errorf("error decoding trash metadata %s: %w", path, err)
+		}
+
+		contentPath, bundle := fs.findSlug(tp.PostType, strings.TrimSuffix(path, ".json"))
+		if contentPath == "" {
+			// Metadata left behind by an interrupted Trash or Untrash
+			return nil
+		}
+
+		content, err := os.ReadFile(contentPath)
+		if err != nil {
+			return err
+		}
+
+		tp.Post, err = fs.proc.Process(content)
+		if err != nil {
+			return fmt.Errorf("error processing markdown file %s: %w", contentPath, err)
+		}
+		tp.Post.PostType = tp.PostType
+		tp.Post.Slug = tp.Slug
+		setFileDate(tp.Post, SlugifyPath(fs.rootDir, contentPath, ""))
+
+		if bundle {
+			if tp.Post.Assets, err = bundleAssets(contentPath, fs.cfg); err != nil {
+				return err
+			}
+		}
+
+		trashed = append(trashed, &tp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(trashed, func(i, j int) bool {
+		return trashed[i].Deleted.After(trashed[j].Deleted)
+	})
+
+	return trashed, nil
+}
+
+func (fs *LocalMarkdownFS) Purge(_ context.Context, postType, slug string) error {
+	trashBase := fs.buildTrashBase(postType, slug)
+
+	unlock, err := fs.lock(trashBase)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if trashPath, _ := fs.trashedPath(postType, slug); trashPath == "" {
+		return fmt.Errorf("%w: %s", ErrNotInTrash, PostPathID(postType, slug))
+	}
+
+	if err := fs.removeTrashed(postType, slug); err != nil {
+		return err
+	}
+
+	if err := os.Remove(trashBase + ".json"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return syncDir(filepath.Dir(trashBase))
+}
+
+// removeTrashed removes a post's file or page bundle from the trash, if it is there, but not its metadata.
+func (fs *LocalMarkdownFS) removeTrashed(postType, slug string) error {
+	for {
+		trashPath, bundle := fs.trashedPath(postType, slug)
+		switch {
+		case trashPath == "":
+			return nil
+		case bundle:
+			if err := os.RemoveAll(filepath.Dir(trashPath)); err != nil {
+				return err
+			}
+		default:
+			if err := os.Remove(trashPath); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// trashedPath returns the path of a post's file in the trash, which is the index file of a page bundle if the post
+// is one, and whether it is. It returns "" if the post isn't in the trash.
+func (fs *LocalMarkdownFS) trashedPath(postType, slug string) (string, bool) {
+	return fs.findSlug(postType, fs.buildTrashBase(postType, slug))
+}
+
+// trashMetaExists returns true if there is trash metadata for the post with the path in the trash without an
+// extension, which may have a date prefix its slug doesn't.
+func trashMetaExists(base string) bool {
+	dir, name := filepath.Split(base)
+	return fileExists(base+".json") || fileExists(filepath.Join(dir, strings.TrimPrefix(name, fileDatePrefix(name)))+".json")
+}
+
+// buildTrashBase returns the path of a post in the trash without an extension. Its metadata is in the .json file.
+func (fs *LocalMarkdownFS) buildTrashBase(postType, slug string) string {
+	return filepath.Join(fs.rootDir, TrashDir, postType, slug)
+}
@@ -0,0 +1,107 @@
+package downcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestDownCache_Trash(t *testing.T) {
+	fs := downcache.NewLocalMarkdownFS(t.TempDir(), realProcessor, downcache.FrontmatterYAML)
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	for _, slug := range []string{"kept", "deleted"} {
+		_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: slug, Name: slug, Content: "Hello"})
+		require.NoError(t, err)
+	}
+
+	// Deleted posts are moved to the trash, and hidden from the store, Read and Walk
+	before := time.Now()
+	require.NoError(t, cm.Delete(ctx, "articles", "deleted"))
+
+	_, err := store.Get(ctx, "articles", "deleted")
+	assert.Error(t, err)
+	_, err = fs.Read(ctx, "articles", "deleted")
+	assert.Error(t, err)
+
+	posts, errs := fs.Walk(ctx)
+	var walked []string
+	for post := range posts {
+		walked = append(walked, post.Slug)
+	}
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"kept"}, walked)
+
+	trashed, err := cm.ListTrash(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, "articles", trashed[0].PostType)
+	assert.Equal(t, "deleted", trashed[0].Slug)
+	assert.Equal(t, "deleted", trashed[0].Post.Name)
+	assert.WithinRange(t, trashed[0].Deleted, before.Add(-time.Second), time.Now())
+
+	// Restoring puts it back
+	restored, err := cm.Restore(ctx, "articles", "deleted")
+	require.NoError(t, err)
+	assert.Equal(t, "deleted", restored.Name)
+	_, err = store.Get(ctx, "articles", "deleted")
+	require.NoError(t, err)
+
+	_, err = cm.Restore(ctx, "articles", "deleted")
+	assert.ErrorIs(t, err, downcache.ErrNotInTrash)
+
+	// A post can't be restored over one that has taken its place
+	require.NoError(t, cm.Delete(ctx, "articles", "deleted"))
+	_, err = cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: "deleted", Name: "Replacement"})
+	require.NoError(t, err)
+	_, err = cm.Restore(ctx, "articles", "deleted")
+	assert.ErrorIs(t, err, downcache.ErrPostExists)
+
+	// Purging only removes posts deleted long enough ago
+	require.NoError(t, cm.Delete(ctx, "articles", "kept"))
+	purged, err := cm.PurgeTrash(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	purged, err = cm.PurgeTrash(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, purged, 2)
+
+	trashed, err = cm.ListTrash(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+}
+
+func TestDownCache_TrashDisabled(t *testing.T) {
+	cm := downcache.NewDownCache(NewInMemoryFileSystem(), downcache.NewMemoryCacheStore())
+
+	_, err := cm.Restore(context.Background(), "articles", "post")
+	assert.ErrorIs(t, err, downcache.ErrTrashDisabled)
+}
+
+func TestDownCache_CreateUniqueSlugTrash(t *testing.T) {
+	fs := downcache.NewLocalMarkdownFS(t.TempDir(), realProcessor, downcache.FrontmatterYAML)
+	cm := downcache.NewDownCache(fs, downcache.NewMemoryCacheStore())
+
+	ctx := context.Background()
+	_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Name: "Hello World"})
+	require.NoError(t, err)
+	require.NoError(t, cm.Delete(ctx, "articles", "hello-world"))
+
+	// The trashed post keeps its slug, so it can be restored
+	post, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Name: "Hello World"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello-world-2", post.Slug)
+
+	_, err = cm.Restore(ctx, "articles", "hello-world")
+	require.NoError(t, err)
+}
@@ -0,0 +1,154 @@
+package downcache
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TreeNode is a post in a Tree, or a directory of posts without an index file.
+type TreeNode struct {
+	Slug     string      // The slug of the post, or the path of the directory, such as guides/setup. The root's is "".
+	Post     *Post       // The post, or nil for a directory without an index file the viewer can see
+	Parent   *TreeNode   // The section the node is in, or nil for the root
+	Children []*TreeNode // The posts and directories in the section, ordered by weight and then title
+}
+
+// Title returns the name of the node's post, or the last part of its slug if it doesn't have one.
+func (n *TreeNode) Title() string {
+	if n.Post != nil && n.Post.Name != "" {
+		return n.Post.Name
+	}
+	if n.Slug == "" {
+		return ""
+	}
+	return path.Base(n.Slug)
+}
+
+// Weight returns the weight of the node's post, or 0 if it doesn't have one.
+func (n *TreeNode) Weight() int {
+	if n.Post == nil {
+		return 0
+	}
+	return n.Post.Weight
+}
+
+// IsSection returns true if the node has children.
+func (n *TreeNode) IsSection() bool {
+	return len(n.Children) > 0
+}
+
+// Ancestors returns the sections the node is in, top-level section first and not including the root, such as for
+// breadcrumbs.
+func (n *TreeNode) Ancestors() []*TreeNode {
+	var ancestors []*TreeNode
+	for parent := n.Parent; parent != nil && parent.Parent != nil; parent = parent.Parent {
+		ancestors = append([]*TreeNode{parent}, ancestors...)
+	}
+	return ancestors
+}
+
+// Siblings returns the other nodes in the node's section, in order.
+func (n *TreeNode) Siblings() []*TreeNode {
+	if n.Parent == nil {
+		return nil
+	}
+	var siblings []*TreeNode
+	for _, child := range n.Parent.Children {
+		if child != n {
+			siblings = append(siblings, child)
+		}
+	}
+	return siblings
+}
+
+// Find returns the node with the slug below the node, or nil if there isn't one.
+func (n *TreeNode) Find(slug string) *TreeNode {
+	node := n
+	for _, part := range strings.Split(strings.Trim(slug, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		node = node.child(part)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Walk calls fn for the node and every node below it, parents before their children, in order. Returning false
+// skips the children of the node.
+func (n *TreeNode) Walk(fn func(node *TreeNode) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// child returns the child with the last part of its slug, or nil if there isn't one.
+func (n *TreeNode) child(name string) *TreeNode {
+	for _, child := range n.Children {
+		if path.Base(child.Slug) == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// sort orders the children of the node and the nodes below it: posts with a weight first, lightest first, then by
+// title and slug.
+func (n *TreeNode) sort() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if wa, wb := a.Weight(), b.Weight(); wa != wb {
+			return wb == 0 || (wa != 0 && wa < wb)
+		}
+		if ta, tb := strings.ToLower(a.Title()), strings.ToLower(b.Title()); ta != tb {
+			return ta < tb
+		}
+		return a.Slug < b.Slug
+	})
+	for _, child := range n.Children {
+		child.sort()
+	}
+}
+
+// Tree returns the posts of the post type the viewer in the context can list, as a tree of sections built from
+// their slugs, such as for navigation that mirrors the directory layout. The post of a section is its page bundle's
+// index file, such as guides/index.md for guides/setup.md, and a directory without one is a node without a post. A
+// post with the slug "index", from an index file directly in the post type's directory, is the root's post.
+func (cm *DownCache) Tree(ctx context.Context, postType string) (*TreeNode, error) {
+	posts, err := cm.searchPages(ctx, FilterOptions{FilterPostType: PostType(postType)})
+	if err != nil {
+		return nil, err
+	}
+
+	root := &TreeNode{}
+	nodes := map[string]*TreeNode{"": root}
+	for _, post := range posts {
+		if post.Slug == BundleIndexName {
+			root.Post = post
+			continue
+		}
+
+		node := root
+		for _, part := range strings.Split(post.Slug, "/") {
+			slug := path.Join(node.Slug, part)
+			child, ok := nodes[slug]
+			if !ok {
+				child = &TreeNode{Slug: slug, Parent: node}
+				node.Children = append(node.Children, child)
+				nodes[slug] = child
+			}
+			node = child
+		}
+		node.Post = post
+	}
+
+	root.sort()
+	return root, nil
+}
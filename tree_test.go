@@ -0,0 +1,140 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestDownCache_Tree(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs/index.md":                    "---\nname: Documentation\n---\n",
+		"docs/intro.md":                    "---\nname: Introduction\nweight: 1\n---\n",
+		"docs/guides/index.md":             "---\nname: Guides\nweight: 2\n---\n",
+		"docs/guides/diagram.png":          string(pngHeader),
+		"docs/guides/Setup.md":             "---\nname: Setup\nweight: 1\n---\n",
+		"docs/guides/advanced.md":          "---\nname: Advanced\n---\n",
+		"docs/guides/basics/index.md":      "---\nname: Basics\nweight: 2\n---\n",
+		"docs/guides/basics/screen.png":    string(pngHeader),
+		"docs/reference/api.md":            "---\nname: API\n---\n",
+		"docs/reference/cli.md":            "---\nname: CLI\n---\n",
+		"docs/reference/drafts/wip.md":     "---\nname: WIP\nstatus: draft\n---\n",
+		"docs/appendix.md":                 "---\nname: Appendix\n---\n",
+		"articles/not-in-the-docs-tree.md": "---\nname: Article\n---\n",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(name)), []byte(content))
+	}
+
+	fs := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	cm := downcache.NewDownCache(fs, downcache.NewMemoryCacheStore())
+
+	ctx := context.Background()
+	require.NoError(t, cm.SyncAll(ctx))
+
+	tree, err := cm.Tree(ctx, "docs")
+	require.NoError(t, err)
+	require.NotNil(t, tree.Post)
+	assert.Equal(t, "Documentation", tree.Title())
+
+	var titles []string
+	tree.Walk(func(node *downcache.TreeNode) bool {
+		if node.Parent != nil {
+			titles = append(titles, node.Title())
+		}
+		return true
+	})
+	// Weighted posts come first, then the rest by title. The draft's directory has no post the viewer can see.
+	assert.Equal(t, []string{
+		"Introduction",
+		"Guides", "Setup", "Basics", "Advanced",
+		"Appendix",
+		"reference", "API", "CLI",
+	}, titles)
+
+	// A section's post is its index file, and its assets are the files in its directory that aren't posts
+	guides := tree.Find("guides")
+	require.NotNil(t, guides)
+	require.NotNil(t, guides.Post)
+	assert.True(t, guides.IsSection())
+	require.Len(t, guides.Post.Assets, 1)
+	assert.Equal(t, "diagram.png", guides.Post.Assets[0].Path)
+
+	basics := tree.Find("/guides/basics/")
+	require.NotNil(t, basics)
+	assert.Equal(t, "guides/basics", basics.Slug)
+	assert.False(t, basics.IsSection())
+	require.Len(t, basics.Post.Assets, 1)
+	assert.Equal(t, "screen.png", basics.Post.Assets[0].Path)
+
+	// Breadcrumbs
+	setup := tree.Find("guides/setup")
+	require.NotNil(t, setup)
+	assert.Same(t, guides, setup.Parent)
+	assert.Equal(t, []*downcache.TreeNode{guides}, setup.Ancestors())
+	assert.Empty(t, guides.Ancestors())
+	assert.Len(t, setup.Siblings(), 2)
+
+	reference := tree.Find("reference")
+	require.NotNil(t, reference)
+	assert.Nil(t, reference.Post)
+	assert.Nil(t, tree.Find("reference/drafts"))
+	assert.Nil(t, tree.Find("missing"))
+
+	// Admins see the draft
+	tree, err = cm.Tree(downcache.WithViewer(ctx, downcache.Viewer{Role: downcache.ViewerAdmin}), "docs")
+	require.NoError(t, err)
+	wip := tree.Find("reference/drafts/wip")
+	require.NotNil(t, wip)
+	assert.Equal(t, []string{"reference", "drafts"}, []string{wip.Ancestors()[0].Slug, wip.Ancestors()[1].Title()})
+
+	// Sections can be read and written like other page bundles
+	post, err := fs.Read(ctx, "docs", "guides")
+	require.NoError(t, err)
+	assert.Equal(t, "Guides", post.Name)
+	post.Weight = 5
+	require.NoError(t, cm.Update(ctx, "docs", "guides", post))
+	content, err := os.ReadFile(filepath.Join(dir, "docs", "guides", "index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "weight: 5")
+}
+
+func TestDownCache_SectionWithChildren(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "index.md"), []byte("---\nname: Guides\n---\n"))
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "diagram.png"), pngHeader)
+	writeTestFile(t, filepath.Join(dir, "docs", "guides", "setup.md"), []byte("---\nname: Setup\n---\n"))
+
+	fs := downcache.NewLocalMarkdownFS(dir, realProcessor, downcache.FrontmatterYAML)
+	cm := downcache.NewDownCache(fs, downcache.NewMemoryCacheStore())
+
+	ctx := context.Background()
+	require.NoError(t, cm.SyncAll(ctx))
+
+	// A section isn't deleted, moved or trashed with its child posts
+	assert.ErrorIs(t, fs.Delete(ctx, "docs", "guides"), downcache.ErrHasChildren)
+	assert.ErrorIs(t, fs.Move(ctx, "docs", "guides", "docs", "manuals"), downcache.ErrHasChildren)
+	assert.ErrorIs(t, fs.Trash(ctx, "docs", "guides", time.Now()), downcache.ErrHasChildren)
+	assert.ErrorIs(t, cm.Delete(ctx, "docs", "guides"), downcache.ErrHasChildren)
+
+	for _, name := range []string{"index.md", "diagram.png", "setup.md"} {
+		assert.FileExists(t, filepath.Join(dir, "docs", "guides", name))
+	}
+	_, err := cm.Get(ctx, "docs", "guides")
+	require.NoError(t, err)
+	_, err = cm.Get(ctx, "docs", "guides/setup")
+	require.NoError(t, err)
+
+	// Once its children are gone, it is a page bundle that is deleted with its assets
+	require.NoError(t, fs.Delete(ctx, "docs", "guides/setup"))
+	require.NoError(t, fs.Delete(ctx, "docs", "guides"))
+	assert.NoDirExists(t, filepath.Join(dir, "docs", "guides"))
+}
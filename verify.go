@@ -0,0 +1,107 @@
+package downcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// InconsistencyKind is the way the store disagrees with the filesystem about a post.
+type InconsistencyKind string
+
+const (
+	InconsistencyMissing  InconsistencyKind = "missing"  // The post's file exists, but the post isn't in the store
+	InconsistencyOrphaned InconsistencyKind = "orphaned" // The post is in the store, but its file doesn't exist
+	InconsistencyStale    InconsistencyKind = "stale"    // The post in the store has a different ETag to its file
+)
+
+// Inconsistency is a post the filesystem and store disagree about.
+type Inconsistency struct {
+	Kind     InconsistencyKind
+	PostType string
+	Slug     string
+}
+
+// Verify compares every post in the filesystem with the store, and returns the posts they disagree about, ordered by
+// post type and slug. Use Repair to fix them.
+func (cm *DownCache) Verify(ctx context.Context) ([]Inconsistency, error) {
+	var issues []Inconsistency
+	files := make(map[string]bool)
+
+	posts, errs := cm.fs.Walk(ctx)
+	for post := range posts {
+		files[PostPathID(post.PostType, post.Slug)] = true
+
+		stored, err := cm.store.Get(ctx, post.PostType, post.Slug)
+		switch {
+		case err != nil:
+			issues = append(issues, Inconsistency{Kind: InconsistencyMissing, PostType: post.PostType, Slug: post.Slug})
+		case stored.ETag != post.ETag:
+			issues = append(issues, Inconsistency{Kind: InconsistencyStale, PostType: post.PostType, Slug: post.Slug})
+		}
+	}
+
+	// Check for any errors from Walk
+	for err := range errs {
+		return nil, fmt.Errorf("error walking filesystem: %w", err)
+	}
+
+	stored, err := cm.searchAll(ctx, FilterOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing store: %w", err)
+	}
+
+	for _, post := range stored {
+		if !files[PostPathID(post.PostType, post.Slug)] {
+			issues = append(issues, Inconsistency{Kind: InconsistencyOrphaned, PostType: post.PostType, Slug: post.Slug})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return PostPathID(issues[i].PostType, issues[i].Slug) < PostPathID(issues[j].PostType, issues[j].Slug)
+	})
+
+	return issues, nil
+}
+
+// Repair brings the store into line with the filesystem for each inconsistency returned by Verify.
+func (cm *DownCache) Repair(ctx context.Context, issues []Inconsistency) error {
+	cm.writeMu.Lock()
+	defer cm.writeMu.Unlock()
+
+	for _, issue := range issues {
+		if err := cm.repair(ctx, issue.PostType, issue.Slug); err != nil {
+			return fmt.Errorf("error repairing %s: %w", PostPathID(issue.PostType, issue.Slug), err)
+		}
+	}
+
+	return nil
+}
+
+// repair brings the store into line with the post's file: the post is added to or updated in the store if the
+// file exists, and removed from the store if it doesn't.
+func (cm *DownCache) repair(ctx context.Context, postType, slug string) error {
+	_, storeErr := cm.store.Get(ctx, postType, slug)
+	inStore := storeErr == nil
+
+	post, err := cm.fs.Read(ctx, postType, slug)
+	if errors.Is(err, os.ErrNotExist) {
+		if inStore {
+			return cm.store.Delete(ctx, postType, slug)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading from filesystem: %w", err)
+	}
+
+	cm.index(post)
+	if inStore {
+		return cm.store.Update(ctx, postType, slug, post)
+	}
+
+	_, err = cm.store.Create(ctx, post)
+	return err
+}
@@ -0,0 +1,56 @@
+package downcache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hypergopher/downcache"
+)
+
+func TestDownCache_Verify(t *testing.T) {
+	dir := t.TempDir()
+	fs := downcache.NewLocalMarkdownFS(dir, &downcache.DefaultMarkdownProcessor{}, downcache.FrontmatterYAML)
+	store := downcache.NewMemoryCacheStore()
+	cm := downcache.NewDownCache(fs, store)
+
+	ctx := context.Background()
+	for _, slug := range []string{"edited", "unchanged"} {
+		_, err := cm.Create(ctx, &downcache.Post{PostType: "articles", Slug: slug, Name: slug, Content: "Hello"})
+		require.NoError(t, err)
+	}
+
+	issues, err := cm.Verify(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	// Change the filesystem and store behind DownCache's back
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "articles", "added.md"), []byte("---\nname: Added\n---\n\nNew"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "articles", "edited.md"), []byte("---\nname: Edited\n---\n\nChanged"), 0o644))
+	_, err = store.Create(ctx, &downcache.Post{PostType: "articles", Slug: "orphan", Name: "Orphan"})
+	require.NoError(t, err)
+
+	issues, err = cm.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []downcache.Inconsistency{
+		{Kind: downcache.InconsistencyMissing, PostType: "articles", Slug: "added"},
+		{Kind: downcache.InconsistencyStale, PostType: "articles", Slug: "edited"},
+		{Kind: downcache.InconsistencyOrphaned, PostType: "articles", Slug: "orphan"},
+	}, issues)
+
+	require.NoError(t, cm.Repair(ctx, issues))
+
+	issues, err = cm.Verify(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	edited, err := store.Get(ctx, "articles", "edited")
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", edited.Name)
+	_, err = store.Get(ctx, "articles", "orphan")
+	assert.Error(t, err)
+}
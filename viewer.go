@@ -0,0 +1,105 @@
+package downcache
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// ViewerRole is the role of the person viewing posts, which decides the posts they can see.
+type ViewerRole string
+
+const (
+	ViewerAnonymous ViewerRole = "anonymous" // Can see published, public posts. Unlisted posts can be fetched directly.
+	ViewerAuthor    ViewerRole = "author"    // Can also see all of their own posts, whatever their status or visibility
+	ViewerAdmin     ViewerRole = "admin"     // Can see every post
+)
+
+// Viewer is the person viewing posts. The zero value is an anonymous viewer.
+type Viewer struct {
+	Role   ViewerRole
+	Author string // The author whose posts an author viewer can always see. Compared with Post.Author.
+}
+
+// AnonymousViewer is a viewer who can only see published, public posts.
+var AnonymousViewer = Viewer{Role: ViewerAnonymous}
+
+// viewerContextKey is the context key for the Viewer.
+type viewerContextKey struct{}
+
+// WithViewer returns a copy of the context with the viewer. Stores and DownCache.Get use it when
+// FilterOptions.Viewer isn't set.
+func WithViewer(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, viewerContextKey{}, viewer)
+}
+
+// ViewerFromContext returns the viewer in the context, or AnonymousViewer if there isn't one.
+func ViewerFromContext(ctx context.Context) Viewer {
+	if ctx != nil {
+		if viewer, ok := ctx.Value(viewerContextKey{}).(Viewer); ok && viewer.Role != "" {
+			return viewer
+		}
+	}
+	return AnonymousViewer
+}
+
+// CurrentViewer returns the viewer to filter posts for: Viewer if set, otherwise the viewer in the context.
+func (fo FilterOptions) CurrentViewer(ctx context.Context) Viewer {
+	if fo.Viewer.Role != "" {
+		return fo.Viewer
+	}
+	return ViewerFromContext(ctx)
+}
+
+// Access describes the posts a viewer can see. A post is visible if Unrestricted is set, if both its status is
+// in Statuses and its visibility is in Visibilities, or if its author is Author. An empty status is treated as
+// published and an empty visibility as public.
+type Access struct {
+	Unrestricted bool
+	Statuses     []string
+	Visibilities []string
+	Author       string
+}
+
+// Access returns the posts the viewer can see. Unlisted posts are only included if listing is false, i.e. when a
+// post is fetched directly rather than searched for.
+func (v Viewer) Access(listing bool) Access {
+	if v.Role == ViewerAdmin {
+		return Access{Unrestricted: true}
+	}
+
+	access := Access{
+		Statuses:     []string{"published", ""},
+		Visibilities: []string{"public", ""},
+	}
+
+	if !listing {
+		access.Visibilities = append(access.Visibilities, "unlisted")
+	}
+
+	if v.Role == ViewerAuthor && v.Author != "" {
+		access.Author = v.Author
+	}
+
+	return access
+}
+
+// Allows returns true if the post is visible.
+func (a Access) Allows(post *Post) bool {
+	if a.Unrestricted || (a.Author != "" && post.Author == a.Author) {
+		return true
+	}
+
+	return slices.Contains(a.Statuses, post.Status) && slices.Contains(a.Visibilities, post.Visibility)
+}
+
+// CanView returns true if the viewer can fetch the post directly at the given time. Scheduled and expired posts
+// can only be fetched by admins and their authors.
+func (v Viewer) CanView(post *Post, at time.Time) bool {
+	access := v.Access(false)
+	if !access.Allows(post) {
+		return false
+	}
+
+	return post.IsLive(at) || access.Unrestricted || (access.Author != "" && post.Author == access.Author)
+}
@@ -0,0 +1,164 @@
+package downcache
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultExtensions are the file extensions of posts, unless WithExtensions is used.
+var DefaultExtensions = []string{".md"}
+
+// tempExtensions are the extensions of editor swap, temporary and backup files, which Walk skips.
+var tempExtensions = []string{".swp", ".swo", ".tmp", ".bak", ".orig"}
+
+// WalkOption configures the files LocalMarkdownFS and IOFSMarkdownFS treat as posts.
+type WalkOption func(*walkConfig)
+
+// walkConfig decides the files that are posts.
+type walkConfig struct {
+	extensions    []string             // The extensions of posts, in lower case. The first is used for new posts.
+	rootPostType  string               // The post type of files directly in the root directory, if they are posts
+	skipRoot      bool                 // Whether files directly in the root directory are skipped
+	datelessTypes []string             // The post types whose slugs don't include the dates in their file names
+	slugifier     Slugifier            // Makes the slugs of posts, unless their post type has its own
+	typeSlugifier map[string]Slugifier // The slugifiers of post types, by post type
+}
+
+func newWalkConfig(opts []WalkOption) walkConfig {
+	cfg := walkConfig{extensions: DefaultExtensions, slugifier: DefaultSlugifier}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithExtensions sets the file extensions of posts, such as ".md", ".markdown", ".mdx" and ".mdown". They are
+// matched case-insensitively. New posts are written with the first.
+func WithExtensions(extensions ...string) WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.extensions = nil
+		for _, ext := range extensions {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			cfg.extensions = append(cfg.extensions, ext)
+		}
+		if len(cfg.extensions) == 0 {
+			cfg.extensions = DefaultExtensions
+		}
+	}
+}
+
+// WithRootPostType makes posts of the files directly in the root directory, with the post type. By default, Walk
+// fails if there are any, as the post type of a file is its top-level directory.
+func WithRootPostType(postType string) WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.rootPostType = postType
+	}
+}
+
+// SkipRootFiles makes Walk skip the files directly in the root directory, such as a README, rather than fail.
+func SkipRootFiles() WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.skipRoot = true
+	}
+}
+
+// StripSlugDates removes the "2006-01-02-" dates from the start of the file names of posts of the post types from
+// their slugs, so articles/2024-08-21-post-slug.md is the post articles/post-slug. The date is still the post's
+// FileTimePath, and its published date if its frontmatter doesn't have one.
+func StripSlugDates(postTypes ...string) WalkOption {
+	return func(cfg *walkConfig) {
+		cfg.datelessTypes = append(cfg.datelessTypes, postTypes...)
+	}
+}
+
+// WithSlugifier sets the Slugifier that makes the slugs of posts from their file and directory names, and the slugs
+// DownCache makes from their names. With post types, it is only used for posts of those types, such as a Japanese
+// section of a site. See NewSlugifier for the built-in options.
+func WithSlugifier(slugifier Slugifier, postTypes ...string) WalkOption {
+	return func(cfg *walkConfig) {
+		if len(postTypes) == 0 {
+			cfg.slugifier = slugifier
+			return
+		}
+		if cfg.typeSlugifier == nil {
+			cfg.typeSlugifier = make(map[string]Slugifier)
+		}
+		for _, postType := range postTypes {
+			cfg.typeSlugifier[postType] = slugifier
+		}
+	}
+}
+
+// slugifierFor returns the Slugifier of the post type.
+func (cfg walkConfig) slugifierFor(postType string) Slugifier {
+	if slugifier, ok := cfg.typeSlugifier[postType]; ok {
+		return slugifier
+	}
+	return cfg.slugifier
+}
+
+// slugifyPath returns the slugified path of a post of the post type. The post type is "" for files directly in the
+// root directory.
+func (cfg walkConfig) slugifyPath(rootPath, fullPath, postType string) SlugPath {
+	if postType == "" {
+		return SlugifyPathWith(rootPath, fullPath, "", cfg.slugifierFor(cfg.rootPostType))
+	}
+	return SlugifyPathWith(rootPath, fullPath, PostType(postType), cfg.slugifierFor(postType))
+}
+
+// isPost returns true if the file name has one of the extensions of posts.
+func (cfg walkConfig) isPost(name string) bool {
+	return slices.Contains(cfg.extensions, strings.ToLower(path.Ext(name)))
+}
+
+// rootType returns the post type of a file directly in the root directory, or false if it should be skipped.
+func (cfg walkConfig) rootType(relPath string) (string, bool, error) {
+	switch {
+	case cfg.rootPostType != "":
+		return cfg.rootPostType, true, nil
+	case cfg.skipRoot:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("invalid file path structure: %s", relPath)
+	}
+}
+
+// hiddenOrTemp returns true if a file or directory is hidden, such as the trash, .git and the temporary files of
+// atomic writes, or is an editor swap, temporary or backup file.
+func hiddenOrTemp(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, "~") ||
+		(len(name) > 1 && strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")) ||
+		slices.Contains(tempExtensions, strings.ToLower(path.Ext(name)))
+}
+
+// stripsDate returns true if the slugs of the post type don't include the dates in their file names.
+func (cfg walkConfig) stripsDate(postType string) bool {
+	return slices.Contains(cfg.datelessTypes, postType)
+}
+
+// slug returns the slug of a post of the post type from its slugified path.
+func (cfg walkConfig) slug(postType string, slugPath SlugPath) string {
+	if cfg.stripsDate(postType) {
+		return stripFileDate(slugPath.Slug)
+	}
+	return slugPath.Slug
+}
+
+// setFileDate sets the post's FileTimePath to the date in its file name, if it has one, and its published date to
+// it if the frontmatter doesn't set one.
+func setFileDate(post *Post, slugPath SlugPath) {
+	if slugPath.FileTime == nil {
+		return
+	}
+	post.FileTimePath = slugPath.FileTimePath
+	if !post.Published.Valid {
+		post.Published = sql.NullString{String: slugPath.FileTimePath, Valid: true}
+	}
+}